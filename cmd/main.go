@@ -4,8 +4,11 @@ import (
 	"context"
 	"csTrade/config"
 	"csTrade/db"
+	"csTrade/internal/domain/bot"
 	"csTrade/internal/handlers/httpgin"
 	"csTrade/internal/repository"
+	"csTrade/internal/secret"
+	"csTrade/internal/service"
 	"csTrade/internal/service/bots"
 	"net/http"
 	"os"
@@ -22,6 +25,7 @@ import (
 //		@description	CSGO trade
 //	  @BasePath	/
 func main() {
+	startedAt := time.Now()
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer stop()
 	cfg := config.LoadEnv()
@@ -35,11 +39,81 @@ func main() {
 	repo := repository.NewRepository(dbconn)
 
 	///////////////////
-	botmanager := bots.NewBotManager(repo.Bot)
-	botmanager.InitBots(ctx)
+	var cipher *secret.Cipher
+	if cfg.BotSecretKey != "" {
+		cipher, err = secret.NewCipher(cfg.BotSecretKey)
+		if err != nil {
+			log.Panic().Err(err).Msg("Err bot secret key")
+			return
+		}
+	} else {
+		log.Warn().Msg("BOT_SECRET_KEY not set, bot sessions will not be persisted")
+	}
+
+	timeSync := bot.NewTimeSync(cfg.SteamAPIURL, &http.Client{Timeout: cfg.SteamAPITimeout}, cfg.TimeSyncInterval)
+	go timeSync.Run(ctx)
+
+	botmanager := bots.NewBotManager(repo.Bot, cipher,
+		bot.WithTimeSync(timeSync),
+		bot.WithRateLimit(cfg.SteamBotRateLimit, cfg.SteamBotRateBurst),
+		bot.WithGlobalRateLimiter(bot.NewRateLimiter(cfg.SteamGlobalRateLimit, cfg.SteamGlobalRateBurst)),
+		bot.WithRetryPolicy(bot.RetryPolicy{
+			MaxAttempts: cfg.SteamRetryAttempts,
+			BaseDelay:   bot.DefaultRetryPolicy.BaseDelay,
+			MaxDelay:    bot.DefaultRetryPolicy.MaxDelay,
+		}),
+		bot.WithEndpoints(bot.Endpoints{
+			API:       cfg.SteamAPIURL,
+			Community: cfg.SteamCommunityURL,
+			Store:     cfg.SteamStoreURL,
+			Login:     cfg.SteamLoginURL,
+		}),
+		bot.WithTimeouts(bot.Timeouts{
+			Login:        cfg.SteamLoginTimeout,
+			Trade:        cfg.SteamTradeTimeout,
+			Confirmation: cfg.SteamConfirmationTimeout,
+			API:          cfg.SteamAPITimeout,
+			Inventory:    cfg.SteamInventoryTimeout,
+		}),
+	)
+	botmanager.SetSpareProxies(cfg.SpareProxies)
+	botmanager.SetAPIKeyDomain(cfg.SteamAPIKeyDomain)
+	botmanager.SetCapacity(cfg.BotInventoryCapacity)
+
+	selector, err := bots.NewSelector(cfg.BotSelector)
+	if err != nil {
+		log.Panic().Err(err).Msg("Err bot selector")
+		return
+	}
+	botmanager.SetSelector(selector)
+
+	escrowPolicy, err := service.ParseEscrowPolicy(cfg.EscrowPolicy)
+	if err != nil {
+		log.Panic().Err(err).Msg("Err escrow policy")
+		return
+	}
+
+	dispatcher := bots.NewDispatcher(botmanager, cfg.DispatcherWorkers, cfg.DispatcherQueueSize)
+	dispatcher.Start(ctx)
+
+	go func() {
+		botmanager.InitBots(ctx, cfg.BotLoginConcurrency)
+		service.NewOfferService(repo, botmanager, dispatcher, escrowPolicy).StartReconciliation(ctx, startedAt, cfg.ReconcileInterval)
+		botmanager.StartSessionRenewal(cfg.SessionCheckInterval)
+		botmanager.StartAPIKeyCheck(ctx, cfg.APIKeyCheckInterval)
+		botmanager.StartWatchdog(cfg.SessionWatchdogInterval, bots.WatchdogBackoff{
+			Base: cfg.ReloginBackoffBase,
+			Max:  cfg.ReloginBackoffMax,
+		})
+		botmanager.StartPollers(cfg.TradePollInterval, cfg.TradePollHistory)
+		botmanager.StartInventoryRefresh(ctx, cfg.InventoryRefreshInterval)
+	}()
+
+	tracker := service.NewTradeTracker(repo, botmanager)
+	go tracker.Run(ctx, botmanager.Events)
 	//////////////////////
 
-	r := httpgin.Init(repo, botmanager)
+	r := httpgin.Init(repo, botmanager, dispatcher, escrowPolicy, cfg.AdminToken)
 	srv := &http.Server{
 		Addr:         ":8080",
 		Handler:      r,
@@ -66,4 +140,6 @@ func main() {
 	} else {
 		log.Info().Msg("Server stopped gracefully")
 	}
+
+	dispatcher.Wait()
 }
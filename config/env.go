@@ -2,6 +2,9 @@ package config
 
 import (
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 	"github.com/rs/zerolog/log"
@@ -15,9 +18,55 @@ type EnvVars struct {
 	IdentitySecret string
 	DeviceID       string
 	DbUrl          string
+	BotSecretKey   string
 	Debug          bool
 	Env            string
 	LogLevel       string
+
+	TradePollInterval time.Duration
+	TradePollHistory  time.Duration
+
+	SessionCheckInterval     time.Duration
+	InventoryRefreshInterval time.Duration
+	TimeSyncInterval         time.Duration
+
+	SteamAPIURL       string
+	SteamCommunityURL string
+	SteamStoreURL     string
+	SteamLoginURL     string
+
+	SteamLoginTimeout        time.Duration
+	SteamTradeTimeout        time.Duration
+	SteamConfirmationTimeout time.Duration
+	SteamAPITimeout          time.Duration
+	SteamInventoryTimeout    time.Duration
+
+	SteamBotRateLimit    float64
+	SteamBotRateBurst    int
+	SteamGlobalRateLimit float64
+	SteamGlobalRateBurst int
+	SteamRetryAttempts   int
+
+	SpareProxies []string
+
+	EscrowPolicy string
+
+	SteamAPIKeyDomain   string
+	APIKeyCheckInterval time.Duration
+
+	SessionWatchdogInterval time.Duration
+	ReloginBackoffBase      time.Duration
+	ReloginBackoffMax       time.Duration
+
+	BotInventoryCapacity int
+	BotLoginConcurrency  int
+	BotSelector          string
+
+	DispatcherWorkers   int
+	DispatcherQueueSize int
+	ReconcileInterval   time.Duration
+
+	AdminToken string
 }
 
 func LoadEnv() *EnvVars {
@@ -32,8 +81,54 @@ func LoadEnv() *EnvVars {
 		IdentitySecret: getEnv("IDENTITY_SECRET", ""),
 		DeviceID:       getEnv("DEVICE_ID", ""),
 		DbUrl:          getEnv("DB_URL", ""),
+		BotSecretKey:   getEnv("BOT_SECRET_KEY", ""),
 		Env:            getEnv("ENV", "dev"),
 		LogLevel:       getEnv("LOG_LEVEL", "debug"),
+
+		TradePollInterval: getEnvDuration("TRADE_POLL_INTERVAL", 30*time.Second),
+		TradePollHistory:  getEnvDuration("TRADE_POLL_HISTORY", 24*time.Hour),
+
+		SessionCheckInterval:     getEnvDuration("SESSION_CHECK_INTERVAL", 5*time.Minute),
+		InventoryRefreshInterval: getEnvDuration("INVENTORY_REFRESH_INTERVAL", 10*time.Minute),
+		TimeSyncInterval:         getEnvDuration("TIME_SYNC_INTERVAL", time.Hour),
+
+		SteamAPIURL:       getEnv("STEAM_API_URL", "https://api.steampowered.com"),
+		SteamCommunityURL: getEnv("STEAM_COMMUNITY_URL", "https://steamcommunity.com"),
+		SteamStoreURL:     getEnv("STEAM_STORE_URL", "https://store.steampowered.com"),
+		SteamLoginURL:     getEnv("STEAM_LOGIN_URL", "https://login.steampowered.com"),
+
+		SteamLoginTimeout:        getEnvDuration("STEAM_LOGIN_TIMEOUT", time.Minute),
+		SteamTradeTimeout:        getEnvDuration("STEAM_TRADE_TIMEOUT", 30*time.Second),
+		SteamConfirmationTimeout: getEnvDuration("STEAM_CONFIRMATION_TIMEOUT", 30*time.Second),
+		SteamAPITimeout:          getEnvDuration("STEAM_API_TIMEOUT", 15*time.Second),
+		SteamInventoryTimeout:    getEnvDuration("STEAM_INVENTORY_TIMEOUT", time.Minute),
+
+		SteamBotRateLimit:    getEnvFloat("STEAM_BOT_RATE_LIMIT", 2),
+		SteamBotRateBurst:    getEnvInt("STEAM_BOT_RATE_BURST", 5),
+		SteamGlobalRateLimit: getEnvFloat("STEAM_GLOBAL_RATE_LIMIT", 10),
+		SteamGlobalRateBurst: getEnvInt("STEAM_GLOBAL_RATE_BURST", 20),
+		SteamRetryAttempts:   getEnvInt("STEAM_RETRY_ATTEMPTS", 4),
+
+		SpareProxies: getEnvList("BOT_SPARE_PROXIES"),
+
+		EscrowPolicy: getEnv("ESCROW_POLICY", "reject"),
+
+		SteamAPIKeyDomain:   getEnv("STEAM_API_KEY_DOMAIN", "localhost"),
+		APIKeyCheckInterval: getEnvDuration("API_KEY_CHECK_INTERVAL", 5*time.Minute),
+
+		SessionWatchdogInterval: getEnvDuration("SESSION_WATCHDOG_INTERVAL", 2*time.Minute),
+		ReloginBackoffBase:      getEnvDuration("RELOGIN_BACKOFF_BASE", 5*time.Second),
+		ReloginBackoffMax:       getEnvDuration("RELOGIN_BACKOFF_MAX", 5*time.Minute),
+
+		BotInventoryCapacity: getEnvInt("BOT_INVENTORY_CAPACITY", 1000),
+		BotLoginConcurrency:  getEnvInt("BOT_LOGIN_CONCURRENCY", 4),
+		BotSelector:          getEnv("BOT_SELECTOR", "least_loaded"),
+
+		DispatcherWorkers:   getEnvInt("DISPATCHER_WORKERS", 4),
+		DispatcherQueueSize: getEnvInt("DISPATCHER_QUEUE_SIZE", 256),
+		ReconcileInterval:   getEnvDuration("RECONCILE_INTERVAL", time.Minute),
+
+		AdminToken: getEnv("ADMIN_TOKEN", ""),
 	}
 
 	return cfg
@@ -47,3 +142,58 @@ func getEnv(key, defaultVal string) string {
 	log.Info().Str("use default", defaultVal).Str("for key", key).Msg("ENV")
 	return defaultVal
 }
+
+func getEnvDuration(key string, defaultVal time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		log.Info().Dur("use default", defaultVal).Str("for key", key).Msg("ENV")
+		return defaultVal
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		log.Warn().Err(err).Str("for key", key).Msg("ENV invalid duration, use default")
+		return defaultVal
+	}
+	return d
+}
+
+func getEnvInt(key string, defaultVal int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		log.Info().Int("use default", defaultVal).Str("for key", key).Msg("ENV")
+		return defaultVal
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		log.Warn().Err(err).Str("for key", key).Msg("ENV invalid int, use default")
+		return defaultVal
+	}
+	return n
+}
+
+func getEnvFloat(key string, defaultVal float64) float64 {
+	value := os.Getenv(key)
+	if value == "" {
+		log.Info().Float64("use default", defaultVal).Str("for key", key).Msg("ENV")
+		return defaultVal
+	}
+
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		log.Warn().Err(err).Str("for key", key).Msg("ENV invalid float, use default")
+		return defaultVal
+	}
+	return f
+}
+
+func getEnvList(key string) []string {
+	var list []string
+	for _, v := range strings.Split(os.Getenv(key), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
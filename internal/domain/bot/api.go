@@ -2,9 +2,11 @@ package bot
 
 import (
 	"compress/gzip"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
+	"maps"
 	"net/http"
 	"net/url"
 	"strings"
@@ -26,8 +28,31 @@ func parseTradeURL(tradeURL string) (partnerID, token string, err error) {
 	return partnerID, token, nil
 }
 
-func (sc *SteamBot) ReceiveFromUser(assetID, tradeURL, SellerID string) (string, error) {
-	log.Info().Str("assetID", assetID).Str("tradeURL", tradeURL).Msg("RECEIVE FROM START")
+// tradeOfferAssets fills in the amount Steam expects for every asset of a
+// json_tradeoffer side.
+func tradeOfferAssets(assets []Asset) []Asset {
+	side := make([]Asset, len(assets))
+	for i, a := range assets {
+		if a.Amount == 0 {
+			a.Amount = 1
+		}
+		side[i] = a
+	}
+	return side
+}
+
+func (sc *SteamBot) ReceiveFromUser(ctx context.Context, assets []Asset, tradeURL, SellerID, message string) (string, error) {
+	ctx, cancel := sc.withTimeout(ctx, sc.timeouts.Trade)
+	defer cancel()
+
+	if err := sc.checkFrozen(); err != nil {
+		return "", err
+	}
+	if len(assets) == 0 {
+		return "", fmt.Errorf("no assets to receive")
+	}
+
+	log.Info().Int("assets", len(assets)).Str("tradeURL", tradeURL).Msg("RECEIVE FROM START")
 
 	partner, token, err := parseTradeURL(tradeURL)
 	if err != nil {
@@ -39,22 +64,20 @@ func (sc *SteamBot) ReceiveFromUser(assetID, tradeURL, SellerID string) (string,
 	offer := map[string]interface{}{
 		"newversion": true,
 		"version":    2,
-		"me":         map[string]interface{}{"assets": []map[string]string{}},
-		"them": map[string]interface{}{"assets": []map[string]string{
-			{"appid": "730", "contextid": "2", "assetid": assetID},
-		}},
+		"me":         map[string]interface{}{"assets": []Asset{}},
+		"them":       map[string]interface{}{"assets": tradeOfferAssets(assets)},
 	}
 
 	form := url.Values{
 		"sessionid":                 {sc.GetSessionID()},
 		"serverid":                  {"1"},
 		"partner":                   {SellerID},
-		"tradeoffermessage":         {""},
+		"tradeoffermessage":         {message},
 		"trade_offer_create_params": {fmt.Sprintf(`{"trade_offer_access_token":"%s"}`, token)},
 		"json_tradeoffer":           {toJSON(offer)},
 	}
 
-	req, err := http.NewRequest("POST", "https://steamcommunity.com/tradeoffer/new/send", strings.NewReader(form.Encode()))
+	req, err := http.NewRequestWithContext(ctx, "POST", sc.endpoints.Community+"/tradeoffer/new/send", strings.NewReader(form.Encode()))
 	if err != nil {
 		log.Error().Err(err).Msg("err to create HTTP request for trade offer")
 		return "", err
@@ -65,120 +88,139 @@ func (sc *SteamBot) ReceiveFromUser(assetID, tradeURL, SellerID string) (string,
 	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64)")
 	req.Header.Set("X-Requested-With", "XMLHttpRequest")
 
-	resp, err := sc.Client.Do(req)
+	resp, err := sc.send(req, nonIdempotent)
 	if err != nil {
 		log.Error().Err(err).Msg("err to send trade offer request")
 		return "", err
 	}
 	defer resp.Body.Close()
 
-	var reader io.Reader = resp.Body
-	if resp.Header.Get("Content-Encoding") == "gzip" {
-		log.Info().Interface("response_headers", resp.Header).
-			Msg("Received response headers")
-		if gzReader, err := gzip.NewReader(resp.Body); err == nil {
-			defer gzReader.Close()
-			reader = gzReader
-		}
-	}
-
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("HTTP error ReceiveFromUser %d", resp.StatusCode)
-	}
-
-	var res struct {
-		TradeOfferID string `json:"tradeofferid"`
-	}
-	bodyBytes, _ := io.ReadAll(reader)
-	if err := json.Unmarshal(bodyBytes, &res); err != nil {
-		return "", fmt.Errorf("failed to parse tradeofferid: %w", err)
+	res, err := decodeSendOffer(resp)
+	if err != nil {
+		return "", err
 	}
-	log.Info().Str("id", string(bodyBytes)).
-		Msg("resp tradeofferid new trade")
+	log.Info().Str("trade_offer_id", res.TradeOfferID).Msg("resp tradeofferid new trade")
 
 	return res.TradeOfferID, nil
 }
 
-func (sc *SteamBot) SendToBuyer(assetID, tradeURL, buyerID string) error {
+func (sc *SteamBot) SendToBuyer(ctx context.Context, assets []Asset, tradeURL, buyerID, message string) (string, error) {
+	if err := sc.checkFrozen(); err != nil {
+		return "", err
+	}
+	if len(assets) == 0 {
+		return "", fmt.Errorf("no assets to send")
+	}
 	_, token, err := parseTradeURL(tradeURL)
 	if err != nil {
-		return err
+		return "", err
 	}
 	offer := map[string]interface{}{
 		"newversion": true,
 		"version":    2,
-		"me": map[string]interface{}{"assets": []map[string]string{
-			{"appid": "730", "contextid": "2", "assetid": assetID},
-		}},
-		"them": map[string]interface{}{"assets": []map[string]string{}},
+		"me":         map[string]interface{}{"assets": tradeOfferAssets(assets)},
+		"them":       map[string]interface{}{"assets": []Asset{}},
 	}
 	form := url.Values{
 		"sessionid":                 {sc.GetSessionID()},
 		"serverid":                  {"1"},
 		"partner":                   {buyerID},
-		"tradeoffermessage":         {""},
+		"tradeoffermessage":         {message},
 		"trade_offer_create_params": {fmt.Sprintf(`{"trade_offer_access_token":"%s"}`, token)},
 		"json_tradeoffer":           {toJSON(offer)},
 	}
-	req, _ := http.NewRequest("POST", "https://steamcommunity.com/tradeoffer/new/send", strings.NewReader(form.Encode()))
+	sendCtx, cancel := sc.withTimeout(ctx, sc.timeouts.Trade)
+	defer cancel()
+
+	req, _ := http.NewRequestWithContext(sendCtx, "POST", sc.endpoints.Community+"/tradeoffer/new/send", strings.NewReader(form.Encode()))
 	req.Header.Set("Referer", tradeURL)
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 
-	resp, err := sc.Client.Do(req)
+	resp, err := sc.send(req, nonIdempotent)
 	if err != nil {
-		return err
+		return "", err
 	}
 	defer resp.Body.Close()
-	var res struct {
-		TradeOfferID            string `json:"tradeofferid"`
-		StrError                string `json:"strError"`
-		NeedsMobileConfirmation bool   `json:"needs_mobile_confirmation"`
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
-		return fmt.Errorf("err parse tradeOffer to buyer: %w", err)
+	res, err := decodeSendOffer(resp)
+	if err != nil {
+		return "", err
 	}
 	log.Info().Interface("resp", res).Msg("To buyer")
 
-	if res.StrError != "" {
-		return fmt.Errorf("steam error: %s", res.StrError)
+	if res.NeedsMobileConfirmation {
+		if err := sc.AcceptConfirmationByCreatorID(ctx, res.TradeOfferID); err != nil {
+			return res.TradeOfferID, fmt.Errorf("err confirm tradeOffer %s: %w", res.TradeOfferID, err)
+		}
 	}
 
-	return nil
+	return res.TradeOfferID, nil
 }
 
-// func (sc *SteamBot) GetStatus(tradeOfferID string) error {
-// 	url := fmt.Sprintf("https://steamcommunity.com/tradeoffer/%s/?json=1", tradeOfferID)
+type sendOfferResponse struct {
+	TradeOfferID            string `json:"tradeofferid"`
+	StrError                string `json:"strError"`
+	NeedsMobileConfirmation bool   `json:"needs_mobile_confirmation"`
+	NeedsEmailConfirmation  bool   `json:"needs_email_confirmation"`
+}
 
-// 	resp, err := sc.apiCall("GET", url, nil)
-// 	if err != nil {
-// 		return fmt.Errorf("failed to get trade status %s: %w", tradeOfferID, err)
-// 	}
-// 	if resp.StatusCode != http.StatusOK {
-// 		return fmt.Errorf("failed to get trade status %s: unexpected status code %d", tradeOfferID, resp.StatusCode)
-// 	}
+// decodeSendOffer reads a tradeoffer/new/send response and turns strError
+// into one of the typed errors.
+func decodeSendOffer(resp *http.Response) (*sendOfferResponse, error) {
+	var reader io.Reader = resp.Body
+	if resp.Header.Get("Content-Encoding") == "gzip" {
+		if gzReader, err := gzip.NewReader(resp.Body); err == nil {
+			defer gzReader.Close()
+			reader = gzReader
+		}
+	}
 
-// 	defer resp.Body.Close()
+	body, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read trade offer response: %w", err)
+	}
 
-// 	body, err := io.ReadAll(resp.Body)
-// 	if err != nil {
-// 		return fmt.Errorf("failed to read body: %w", err)
-// 	}
+	var res sendOfferResponse
+	decodeErr := json.Unmarshal(body, &res)
 
-//		log.Info().Msgf("TradeOffer %s status body: %s", tradeOfferID, string(body))
-//		return nil
-//	}
+	if err := classifySteamError(resp.StatusCode, 0, res.StrError); err != nil {
+		return nil, err
+	}
+	if res.StrError != "" {
+		return nil, &SteamError{StatusCode: resp.StatusCode, EResult: parseEResult(res.StrError), Message: res.StrError, Err: ErrTradeOfferFailed}
+	}
+	if decodeErr != nil {
+		return nil, fmt.Errorf("failed to parse tradeofferid: %w", decodeErr)
+	}
+	if resp.StatusCode != http.StatusOK || res.TradeOfferID == "" {
+		return nil, &SteamError{StatusCode: resp.StatusCode, Err: ErrTradeOfferFailed}
+	}
 
-func (sc *SteamBot) GetStatus(tradeOfferID string) error {
-	return nil
+	return &res, nil
+}
+
+func (sc *SteamBot) GetStatus(ctx context.Context, tradeOfferID string) (*TradeOfferStatus, error) {
+	tradeOffer, err := sc.GetTradeOffer(ctx, tradeOfferID)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Info().Str("trade_offer_id", tradeOfferID).
+		Str("state", tradeOffer.State.String()).
+		Msg("trade offer status")
+
+	return tradeOffer.Status(), nil
 }
 
-func (sc *SteamBot) DeclineTrade(tradeOfferID string) error {
+func (sc *SteamBot) DeclineTrade(ctx context.Context, tradeOfferID string) error {
+	ctx, cancel := sc.withTimeout(ctx, sc.timeouts.Trade)
+	defer cancel()
+
 	params := map[string]string{
 		"sessionid": sc.GetSessionID(),
 	}
 
-	resp, err := sc.apiCall("POST",
-		fmt.Sprintf("https://steamcommunity.com/tradeoffer/%s/cancel", tradeOfferID), params)
+	resp, err := sc.apiCall(ctx, "POST",
+		fmt.Sprintf("%s/tradeoffer/%s/cancel", sc.endpoints.Community, tradeOfferID), params)
 	if err != nil {
 		return err
 	}
@@ -186,28 +228,50 @@ func (sc *SteamBot) DeclineTrade(tradeOfferID string) error {
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("failed to cancel trade %s, status: %d, body: %s", tradeOfferID, resp.StatusCode, body)
+		var result struct {
+			Success int `json:"success"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&result)
+		if err := classifySteamError(resp.StatusCode, result.Success, ""); err != nil {
+			return fmt.Errorf("failed to cancel trade %s: %w", tradeOfferID, err)
+		}
+		return fmt.Errorf("failed to cancel trade %s, status: %d, eresult: %d", tradeOfferID, resp.StatusCode, result.Success)
 	}
 	log.Info().Any("BODY:", resp.Body).Msg("resp:")
 
 	return nil
 }
 
-func (sc *SteamBot) apiCall(method, endpoint string, params map[string]string) (*http.Response, error) {
+func (sc *SteamBot) apiCall(ctx context.Context, method, endpoint string, params map[string]string) (*http.Response, error) {
 	urlStr := endpoint
 	if !strings.HasPrefix(endpoint, "http") {
-		urlStr = SteamAPIURL + endpoint
+		urlStr = sc.endpoints.API + endpoint
 	}
-	req, err := http.NewRequest(method, urlStr, nil)
 
+	var body io.Reader
+	if method == "POST" && params != nil {
+		data := url.Values{}
+		for k, v := range params {
+			data.Set(k, v)
+		}
+		body = strings.NewReader(data.Encode())
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, urlStr, body)
 	if err != nil {
 		return nil, err
 	}
 
 	req.Header = http.Header{
 		"User-Agent": []string{"Mozilla/5.0 (Windows NT 10.0; Win64; x64)"},
-		"Origin":     []string{SteamCommunityURL},
+		"Origin":     []string{sc.endpoints.Community},
+	}
+
+	if key := sc.APIKey().Key; key != "" && strings.HasPrefix(endpoint, "/IEconService/") {
+		// copy so the caller's map is left as it was
+		withKey := map[string]string{"key": key}
+		maps.Copy(withKey, params)
+		params = withKey
 	}
 
 	if method == "GET" && params != nil {
@@ -216,14 +280,9 @@ func (sc *SteamBot) apiCall(method, endpoint string, params map[string]string) (
 			q.Add(k, v)
 		}
 		req.URL.RawQuery = q.Encode()
-	} else if method == "POST" && params != nil {
-		data := url.Values{}
-		for k, v := range params {
-			data.Set(k, v)
-		}
-		req.Body = io.NopCloser(strings.NewReader(data.Encode()))
+	} else if body != nil {
 		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 	}
 
-	return sc.Client.Do(req)
+	return sc.send(req, idempotent)
 }
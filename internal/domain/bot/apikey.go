@@ -0,0 +1,141 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// WebAPIKey is the Steam Web API key registered on the bot account and the
+// domain it was registered for.
+type WebAPIKey struct {
+	Key    string
+	Domain string
+}
+
+var (
+	apiKeyRe       = regexp.MustCompile(`Key:\s*([0-9A-F]{32})`)
+	apiKeyDomainRe = regexp.MustCompile(`Domain Name:\s*([^<\s]+)`)
+)
+
+// APIKey is the key the bot sends with Web API calls, empty until SetAPIKey.
+func (sc *SteamBot) APIKey() WebAPIKey {
+	sc.credMu.RLock()
+	defer sc.credMu.RUnlock()
+	return sc.apiKey
+}
+
+func (sc *SteamBot) SetAPIKey(key WebAPIKey) {
+	sc.credMu.Lock()
+	defer sc.credMu.Unlock()
+	sc.apiKey = key
+}
+
+// GetAPIKey reads the key from the account's /dev/apikey page. Key is empty
+// when the account has none.
+func (sc *SteamBot) GetAPIKey(ctx context.Context) (*WebAPIKey, error) {
+	ctx, cancel := sc.withTimeout(ctx, sc.timeouts.API)
+	defer cancel()
+
+	resp, err := sc.apiCall(ctx, "GET", sc.endpoints.Community+"/dev/apikey", map[string]string{"l": "english"})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get api key: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.Request != nil && strings.Contains(resp.Request.URL.Path, "/login") {
+		return nil, fmt.Errorf("failed to get api key: %w", ErrSessionExpired)
+	}
+	if err := responseError(resp); err != nil {
+		return nil, fmt.Errorf("failed to get api key: %w", err)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read api key page: %w", err)
+	}
+
+	key := &WebAPIKey{}
+	if m := apiKeyRe.FindSubmatch(body); m != nil {
+		key.Key = string(m[1])
+	}
+	if m := apiKeyDomainRe.FindSubmatch(body); m != nil {
+		key.Domain = html.UnescapeString(string(m[1]))
+	}
+
+	return key, nil
+}
+
+// RegisterAPIKey registers a key for domain and returns what Steam shows
+// afterwards.
+func (sc *SteamBot) RegisterAPIKey(ctx context.Context, domain string) (*WebAPIKey, error) {
+	regCtx, cancel := sc.withTimeout(ctx, sc.timeouts.API)
+	defer cancel()
+
+	form := url.Values{
+		"domain":       {domain},
+		"agreeToTerms": {"agreed"},
+		"sessionid":    {sc.GetSessionID()},
+		"Submit":       {"Register"},
+	}
+	req, err := http.NewRequestWithContext(regCtx, "POST", sc.endpoints.Community+"/dev/registerkey", strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Referer", sc.endpoints.Community+"/dev/apikey")
+
+	resp, err := sc.send(req, nonIdempotent)
+	if err != nil {
+		return nil, fmt.Errorf("failed to register api key: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if err := responseError(resp); err != nil {
+		return nil, fmt.Errorf("failed to register api key: %w", err)
+	}
+
+	key, err := sc.GetAPIKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if key.Key == "" {
+		return nil, fmt.Errorf("failed to register api key: no key after registration")
+	}
+
+	log.Info().Str("bot", sc.SteamID).Str("domain", key.Domain).Msg("api key registered")
+	return key, nil
+}
+
+// Freeze stops the bot from creating, accepting or confirming trades until
+// Unfreeze is called.
+func (sc *SteamBot) Freeze(reason string) {
+	sc.frozen.Store(&reason)
+}
+
+func (sc *SteamBot) Unfreeze() {
+	sc.frozen.Store(nil)
+}
+
+// Frozen reports whether the bot is frozen and why.
+func (sc *SteamBot) Frozen() (string, bool) {
+	reason := sc.frozen.Load()
+	if reason == nil {
+		return "", false
+	}
+	return *reason, true
+}
+
+func (sc *SteamBot) checkFrozen() error {
+	if reason, frozen := sc.Frozen(); frozen {
+		return fmt.Errorf("bot %s: %w: %s", sc.SteamID, ErrBotFrozen, reason)
+	}
+	return nil
+}
@@ -1,22 +1,20 @@
 package bot
 
 import (
-	"crypto/hmac"
+	"context"
 	"crypto/rand"
 	"crypto/rsa"
-	"crypto/sha1"
 	"csTrade/internal/repository"
 	"encoding/base64"
-	"encoding/binary"
 	"encoding/json"
 	"fmt"
-	"io"
 	"math/big"
 	"net/http"
 	"net/http/cookiejar"
 	"net/url"
-	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/rs/zerolog/log"
@@ -29,6 +27,124 @@ const (
 	SteamLoginURL     = "https://login.steampowered.com"
 )
 
+type Endpoints struct {
+	API       string
+	Community string
+	Store     string
+	Login     string
+}
+
+var DefaultEndpoints = Endpoints{
+	API:       SteamAPIURL,
+	Community: SteamCommunityURL,
+	Store:     SteamStoreURL,
+	Login:     SteamLoginURL,
+}
+
+// Timeouts bound whole bot operations, including every request and retry
+// they make. A zero field means no deadline beyond the caller's context.
+type Timeouts struct {
+	Login        time.Duration
+	Trade        time.Duration
+	Confirmation time.Duration
+	API          time.Duration
+	Inventory    time.Duration
+}
+
+var DefaultTimeouts = Timeouts{
+	Login:        time.Minute,
+	Trade:        30 * time.Second,
+	Confirmation: 30 * time.Second,
+	API:          15 * time.Second,
+	Inventory:    time.Minute,
+}
+
+type Option func(*SteamBot)
+
+// WithEndpoints points the bot at other Steam hosts, empty fields keep
+// their defaults.
+func WithEndpoints(e Endpoints) Option {
+	return func(sc *SteamBot) {
+		if e.API != "" {
+			sc.endpoints.API = strings.TrimRight(e.API, "/")
+		}
+		if e.Community != "" {
+			sc.endpoints.Community = strings.TrimRight(e.Community, "/")
+		}
+		if e.Store != "" {
+			sc.endpoints.Store = strings.TrimRight(e.Store, "/")
+		}
+		if e.Login != "" {
+			sc.endpoints.Login = strings.TrimRight(e.Login, "/")
+		}
+	}
+}
+
+// WithTimeouts overrides the per-operation deadlines, empty fields keep
+// their defaults.
+func WithTimeouts(t Timeouts) Option {
+	return func(sc *SteamBot) {
+		if t.Login > 0 {
+			sc.timeouts.Login = t.Login
+		}
+		if t.Trade > 0 {
+			sc.timeouts.Trade = t.Trade
+		}
+		if t.Confirmation > 0 {
+			sc.timeouts.Confirmation = t.Confirmation
+		}
+		if t.API > 0 {
+			sc.timeouts.API = t.API
+		}
+		if t.Inventory > 0 {
+			sc.timeouts.Inventory = t.Inventory
+		}
+	}
+}
+
+// WithTimeSync shares one Steam clock offset between bots. Without it every
+// bot keeps its own.
+func WithTimeSync(ts *TimeSync) Option {
+	return func(sc *SteamBot) {
+		sc.timeSync = ts
+	}
+}
+
+// WithRateLimit gives every bot it is applied to its own token bucket.
+func WithRateLimit(perSecond float64, burst int) Option {
+	return func(sc *SteamBot) {
+		sc.limiter = NewRateLimiter(perSecond, burst)
+	}
+}
+
+// WithGlobalRateLimiter shares one token bucket between all bots of the
+// process, on top of their own limits.
+func WithGlobalRateLimiter(l *RateLimiter) Option {
+	return func(sc *SteamBot) {
+		sc.globalLimiter = l
+	}
+}
+
+func WithRetryPolicy(p RetryPolicy) Option {
+	return func(sc *SteamBot) {
+		sc.retry = p
+	}
+}
+
+func WithTransport(rt http.RoundTripper) Option {
+	return func(sc *SteamBot) {
+		sc.Client.Transport = rt
+	}
+}
+
+// WithAuthFailureHandler is called whenever Steam answers a bot request in a
+// way that looks like a lost session. It must not block.
+func WithAuthFailureHandler(fn func(*SteamBot, *http.Response)) Option {
+	return func(sc *SteamBot) {
+		sc.onAuthFailure = fn
+	}
+}
+
 type SteamBot struct {
 	Username       string
 	Password       string
@@ -36,41 +152,120 @@ type SteamBot struct {
 	SharedSecret   string
 	IdentitySecret string
 	DeviceID       string
-	AccessToken    string
 	SkinCount      int
-	RefreshToken   string
 	Client         *http.Client
+
+	// credMu guards the session tokens and the Web API key, which session
+	// renewal rewrites while pollers and trades read them.
+	credMu       sync.RWMutex
+	accessToken  string
+	refreshToken string
+	apiKey       WebAPIKey
+
+	endpoints Endpoints
+	timeouts  Timeouts
+	timeSync  *TimeSync
+
+	retry         RetryPolicy
+	limiter       *RateLimiter
+	globalLimiter *RateLimiter
+
+	proxy  atomic.Pointer[url.URL]
+	frozen atomic.Pointer[string]
+
+	onAuthFailure func(*SteamBot, *http.Response)
 }
 
-func NewSteamClient(b *repository.Bot) *SteamBot {
+func NewSteamClient(b *repository.Bot, opts ...Option) *SteamBot {
 	jar, _ := cookiejar.New(nil)
 
-	u, _ := url.Parse(SteamCommunityURL)
-	jar.SetCookies(u, []*http.Cookie{
-		{Name: "Steam_Language", Value: "english"},
-		{Name: "timezoneOffset", Value: "0,0"},
-	})
 	if b.DeviceID != "" && !strings.HasPrefix(b.DeviceID, "android:") {
 		b.DeviceID = "android:" + b.DeviceID
 	}
 
-	return &SteamBot{
+	sc := &SteamBot{
 		Username:       b.Username,
 		Password:       b.Password,
 		SteamID:        b.SteamID,
 		SharedSecret:   b.SharedSecret,
 		IdentitySecret: b.IdentitySecret,
 		DeviceID:       b.DeviceID,
+		endpoints:      DefaultEndpoints,
+		timeouts:       DefaultTimeouts,
+		retry:          DefaultRetryPolicy,
 		Client: &http.Client{
 			Timeout: 30 * time.Second,
 			Jar:     jar,
-			CheckRedirect: func(req *http.Request, via []*http.Request) error {
-				req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64)")
-				req.Header.Set("Origin", SteamCommunityURL)
-				return nil
-			},
 		},
 	}
+	sc.Client.Transport = sc.newTransport()
+	if b.ProxyURL != nil && *b.ProxyURL != "" {
+		if err := sc.SetProxy(*b.ProxyURL); err != nil {
+			log.Error().Err(err).Str("bot", b.SteamID).Msg("ignoring bot proxy")
+		}
+	}
+	if b.FrozenAt != nil {
+		reason := "frozen"
+		if b.FrozenReason != nil {
+			reason = *b.FrozenReason
+		}
+		sc.Freeze(reason)
+	}
+	sc.Client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64)")
+		req.Header.Set("Origin", sc.endpoints.Community)
+		return nil
+	}
+
+	for _, opt := range opts {
+		opt(sc)
+	}
+	if sc.timeSync == nil {
+		sc.timeSync = NewTimeSync(sc.endpoints.API, sc.Client, DefaultTimeSyncInterval)
+	}
+
+	u, _ := url.Parse(sc.endpoints.Community)
+	jar.SetCookies(u, []*http.Cookie{
+		{Name: "Steam_Language", Value: "english"},
+		{Name: "timezoneOffset", Value: "0,0"},
+	})
+
+	return sc
+}
+
+func (sc *SteamBot) Endpoints() Endpoints {
+	return sc.endpoints
+}
+
+func (sc *SteamBot) withTimeout(ctx context.Context, d time.Duration) (context.Context, context.CancelFunc) {
+	if d <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, d)
+}
+
+// cookieURLs lists the hosts that share the web session cookies.
+func (sc *SteamBot) cookieURLs() []*url.URL {
+	var urls []*url.URL
+	for _, raw := range []string{sc.endpoints.Community, sc.endpoints.Store, sc.endpoints.Login} {
+		u, err := url.Parse(raw)
+		if err != nil {
+			continue
+		}
+		urls = append(urls, u)
+	}
+	return urls
+}
+
+func sessionCookie(u *url.URL, name, value string, httpOnly bool) *http.Cookie {
+	return &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Domain:   u.Hostname(),
+		Path:     "/",
+		Secure:   u.Scheme == "https",
+		HttpOnly: httpOnly,
+	}
 }
 
 type Asset struct {
@@ -82,8 +277,13 @@ type Asset struct {
 	InstanceID string `json:"instanceid,omitempty"`
 }
 
+// CSAsset is a single CS item in the given inventory slot.
+func CSAsset(assetID string) Asset {
+	return Asset{AppID: CSAppID, ContextID: CSContextID, AssetID: assetID, Amount: 1}
+}
+
 func (sc *SteamBot) GetSteamLoginSecure() string {
-	u, _ := url.Parse(SteamCommunityURL)
+	u, _ := url.Parse(sc.endpoints.Community)
 	if sc.Client == nil || sc.Client.Jar == nil {
 		return ""
 	}
@@ -95,7 +295,7 @@ func (sc *SteamBot) GetSteamLoginSecure() string {
 	return ""
 }
 func (sc *SteamBot) GetSessionID() string {
-	u, _ := url.Parse(SteamCommunityURL)
+	u, _ := url.Parse(sc.endpoints.Community)
 	if sc.Client == nil || sc.Client.Jar == nil {
 		return ""
 	}
@@ -112,37 +312,13 @@ func toJSON(v interface{}) string {
 	return string(b)
 }
 
-func (sc *SteamBot) GenerateTOTPCode() (string, error) {
-	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(sc.SharedSecret))
-	if err != nil {
-		return "", fmt.Errorf("base64 decode failed: %v", err)
-	}
-
-	steamTime, err := sc.GetSteamTime()
+func (sc *SteamBot) GenerateTOTPCode(ctx context.Context) (string, error) {
+	steamTime, err := sc.GetSteamTime(ctx)
 	if err != nil {
 		return "", fmt.Errorf("failed to get Steam time: %v", err)
 	}
 
-	timestamp := steamTime.Unix() / 30
-
-	timeBytes := make([]byte, 8)
-	binary.BigEndian.PutUint64(timeBytes, uint64(timestamp))
-
-	h := hmac.New(sha1.New, key)
-	h.Write(timeBytes)
-	hash := h.Sum(nil)
-
-	offset := hash[19] & 0x0F
-	code := binary.BigEndian.Uint32(hash[offset:offset+4]) & 0x7FFFFFFF
-
-	chars := "23456789BCDFGHJKMNPQRTVWXY"
-	result := make([]byte, 5)
-	for i := 0; i < 5; i++ {
-		result[i] = chars[code%uint32(len(chars))]
-		code /= uint32(len(chars))
-	}
-
-	return string(result), nil
+	return GenerateAuthCode(sc.SharedSecret, steamTime)
 }
 
 type RSAParams struct {
@@ -150,9 +326,9 @@ type RSAParams struct {
 	Timestamp string
 }
 
-func (sc *SteamBot) fetchRSAParams() (*RSAParams, error) {
+func (sc *SteamBot) fetchRSAParams(ctx context.Context) (*RSAParams, error) {
 	params := map[string]string{"account_name": sc.Username}
-	resp, err := sc.apiCall("GET", "/IAuthenticationService/GetPasswordRSAPublicKey/v1/", params)
+	resp, err := sc.apiCall(ctx, "GET", "/IAuthenticationService/GetPasswordRSAPublicKey/v1/", params)
 	if err != nil {
 		return nil, err
 	}
@@ -200,8 +376,8 @@ type LoginResponse struct {
 	} `json:"response"`
 }
 
-func (sc *SteamBot) startAuth() (*LoginResponse, error) {
-	rsaParams, err := sc.fetchRSAParams()
+func (sc *SteamBot) startAuth(ctx context.Context) (*LoginResponse, error) {
+	rsaParams, err := sc.fetchRSAParams(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -218,7 +394,7 @@ func (sc *SteamBot) startAuth() (*LoginResponse, error) {
 		"persistence":          "1",
 	}
 
-	resp, err := sc.apiCall("POST", "/IAuthenticationService/BeginAuthSessionViaCredentials/v1/", data)
+	resp, err := sc.apiCall(ctx, "POST", "/IAuthenticationService/BeginAuthSessionViaCredentials/v1/", data)
 	if err != nil {
 		return nil, err
 	}
@@ -234,8 +410,8 @@ func (sc *SteamBot) startAuth() (*LoginResponse, error) {
 	return &loginResp, nil
 }
 
-func (sc *SteamBot) submitTOTP(clientID string) error {
-	code, err := sc.GenerateTOTPCode()
+func (sc *SteamBot) submitTOTP(ctx context.Context, clientID string) error {
+	code, err := sc.GenerateTOTPCode(ctx)
 	if err != nil {
 		return err
 	}
@@ -248,7 +424,7 @@ func (sc *SteamBot) submitTOTP(clientID string) error {
 		"code_type": "3",
 	}
 
-	resp, err := sc.apiCall("POST", "/IAuthenticationService/UpdateAuthSessionWithSteamGuardCode/v1/", data)
+	resp, err := sc.apiCall(ctx, "POST", "/IAuthenticationService/UpdateAuthSessionWithSteamGuardCode/v1/", data)
 	if err != nil {
 		log.Error().Err(err).Msg("err submitTOTP resp")
 		return err
@@ -264,13 +440,13 @@ func (sc *SteamBot) submitTOTP(clientID string) error {
 	return nil
 }
 
-func (sc *SteamBot) getTokens(clientID, requestID string) error {
+func (sc *SteamBot) getTokens(ctx context.Context, clientID, requestID string) error {
 	data := map[string]string{
 		"client_id":  clientID,
 		"request_id": requestID,
 	}
 
-	resp, err := sc.apiCall("POST", "/IAuthenticationService/PollAuthSessionStatus/v1/", data)
+	resp, err := sc.apiCall(ctx, "POST", "/IAuthenticationService/PollAuthSessionStatus/v1/", data)
 	if err != nil {
 		return err
 	}
@@ -292,8 +468,7 @@ func (sc *SteamBot) getTokens(clientID, requestID string) error {
 		return fmt.Errorf("empty access token")
 	}
 
-	sc.AccessToken = result.Response.AccessToken
-	sc.RefreshToken = result.Response.RefreshToken
+	sc.setTokens(result.Response.AccessToken, result.Response.RefreshToken)
 
 	err = sc.setSteamLoginSecure()
 	if err != nil {
@@ -303,107 +478,66 @@ func (sc *SteamBot) getTokens(clientID, requestID string) error {
 	return nil
 }
 
-func (sc *SteamBot) GetSteamTime() (time.Time, error) {
-	resp, err := sc.apiCall("POST", "/ITwoFactorService/QueryTime/v1/", nil)
-	if err != nil {
-		return time.Time{}, err
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return time.Time{}, err
-	}
+func (sc *SteamBot) GetSteamTime(ctx context.Context) (time.Time, error) {
+	ctx, cancel := sc.withTimeout(ctx, sc.timeouts.API)
+	defer cancel()
 
-	var response struct {
-		Response struct {
-			ServerTime string `json:"server_time"`
-		} `json:"response"`
-	}
-
-	if err := json.Unmarshal(body, &response); err != nil {
-		return time.Time{}, fmt.Errorf("failed to unmarshal server time: %w", err)
-	}
-
-	serverTime, err := strconv.ParseInt(response.Response.ServerTime, 10, 64)
-	if err != nil {
-		return time.Time{}, fmt.Errorf("invalid server time format: %w", err)
-	}
-
-	return time.Unix(serverTime, 0), nil
+	return sc.timeSync.Now(ctx)
 }
 
-func (sc *SteamBot) Login() error {
-	loginResp, err := sc.startAuth()
+func (sc *SteamBot) Login(ctx context.Context) error {
+	ctx, cancel := sc.withTimeout(ctx, sc.timeouts.Login)
+	defer cancel()
+
+	loginResp, err := sc.startAuth(ctx)
 	if err != nil {
 		return fmt.Errorf("start auth failed: %w", err)
 	}
 
-	if err := sc.submitTOTP(loginResp.Response.ClientID); err != nil {
+	if err := sc.submitTOTP(ctx, loginResp.Response.ClientID); err != nil {
 		return fmt.Errorf("TOTP submit failed: %w", err)
 	}
 
-	if err := sc.getTokens(loginResp.Response.ClientID, loginResp.Response.RequestID); err != nil {
+	if err := sc.getTokens(ctx, loginResp.Response.ClientID, loginResp.Response.RequestID); err != nil {
 		return fmt.Errorf("get tokens failed: %w", err)
 	}
 
-	req, _ := http.NewRequest("GET", "https://steamcommunity.com/", nil)
-	resp, err := sc.Client.Do(req)
+	req, _ := http.NewRequestWithContext(ctx, "GET", sc.endpoints.Community+"/", nil)
+	resp, err := sc.send(req, idempotent)
 	if err != nil {
 		return fmt.Errorf("failed to fetch steamcommunity: %w", err)
 	}
 	resp.Body.Close()
 
-	return sc.testSession()
+	return sc.testSession(ctx)
 }
 
 func (sc *SteamBot) storeCookies(resp *http.Response) error {
-	domains := []string{
-		"steamcommunity.com",
-		"store.steampowered.com",
-		"login.steampowered.com",
-	}
-
-	for _, domain := range domains {
-		domainURL := &url.URL{Scheme: "https", Host: domain}
-		sc.Client.Jar.SetCookies(domainURL, resp.Cookies())
+	for _, u := range sc.cookieURLs() {
+		sc.Client.Jar.SetCookies(u, resp.Cookies())
 	}
 	return nil
 }
 func (sc *SteamBot) setSteamLoginSecure() error {
-	if sc.AccessToken == "" || sc.SteamID == "" {
+	accessToken := sc.AccessToken()
+	if accessToken == "" || sc.SteamID == "" {
 		return fmt.Errorf("accesstoken or steamID empty")
 	}
 
-	steamLoginSecure := sc.SteamID + "%7C%7C" + sc.AccessToken
+	steamLoginSecure := sc.SteamID + "%7C%7C" + accessToken
 
-	domains := []string{
-		"steamcommunity.com",
-		"store.steampowered.com",
-		"login.steampowered.com",
-	}
-
-	for _, domain := range domains {
-		domainURL := &url.URL{Scheme: "https", Host: domain}
-		cookies := []*http.Cookie{
-			{
-				Name:     "steamLoginSecure",
-				Value:    steamLoginSecure,
-				Domain:   domain,
-				Path:     "/",
-				Secure:   true,
-				HttpOnly: true,
-			},
-		}
-		sc.Client.Jar.SetCookies(domainURL, cookies)
+	for _, u := range sc.cookieURLs() {
+		sc.Client.Jar.SetCookies(u, []*http.Cookie{
+			sessionCookie(u, "steamLoginSecure", steamLoginSecure, true),
+		})
 	}
 
 	return nil
 }
 
-func (sc *SteamBot) testSession() error {
-	req, _ := http.NewRequest("GET", "https://store.steampowered.com/account", nil)
-	resp, err := sc.Client.Do(req)
+func (sc *SteamBot) testSession(ctx context.Context) error {
+	req, _ := http.NewRequestWithContext(ctx, "GET", sc.endpoints.Store+"/account", nil)
+	resp, err := sc.send(req, idempotent)
 	if err != nil {
 		return err
 	}
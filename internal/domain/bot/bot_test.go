@@ -0,0 +1,445 @@
+package bot_test
+
+import (
+	"csTrade/internal"
+	"csTrade/internal/domain/bot"
+	"csTrade/internal/domain/bot/fakesteam"
+	"csTrade/internal/repository"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	botSteamID   = "76561198000000001"
+	userSteamID  = "76561198000000002"
+	buyerSteamID = "76561198000000003"
+)
+
+func newFakeSteam(t *testing.T) *fakesteam.Server {
+	t.Helper()
+
+	srv := fakesteam.New()
+	t.Cleanup(srv.Close)
+
+	srv.AddAccount(fakesteam.Account{
+		SteamID:        botSteamID,
+		Username:       "bot1",
+		Password:       "hunter2",
+		SharedSecret:   "cnOgv/KdpLoP6Nbh0GMkXkPXALQ=",
+		IdentitySecret: "aBcdEfGhIjKlMnOpQrStUvWxYz0=",
+	})
+	srv.AddAccount(fakesteam.Account{SteamID: userSteamID, Username: "seller", TradeToken: "sellertk"})
+	srv.AddAccount(fakesteam.Account{SteamID: buyerSteamID, Username: "buyer", TradeToken: "buyertkn"})
+
+	return srv
+}
+
+// newBot logs a bot in on srv, opts come after the fake Steam endpoints.
+func newBot(t *testing.T, srv *fakesteam.Server, opts ...bot.Option) *bot.SteamBot {
+	t.Helper()
+
+	b := bot.NewSteamClient(&repository.Bot{
+		Username:       "bot1",
+		Password:       "hunter2",
+		SteamID:        botSteamID,
+		SharedSecret:   "cnOgv/KdpLoP6Nbh0GMkXkPXALQ=",
+		IdentitySecret: "aBcdEfGhIjKlMnOpQrStUvWxYz0=",
+		DeviceID:       "android:0a1b2c3d",
+	}, append([]bot.Option{bot.WithEndpoints(srv.Endpoints())}, opts...)...)
+
+	require.NoError(t, b.Login(t.Context()))
+	return b
+}
+
+func TestDepositFlow(t *testing.T) {
+	srv := newFakeSteam(t)
+	b := newBot(t, srv)
+
+	srv.AddInventoryItem(userSteamID, internal.Asset{
+		Appid:      bot.CSAppID,
+		Contextid:  bot.CSContextID,
+		Assetid:    "1001",
+		Classid:    "310776",
+		Instanceid: "0",
+		Amount:     "1",
+	}, internal.Description{Appid: bot.CSAppID, Classid: "310776", Instanceid: "0", Tradable: 1})
+
+	tradeID, err := b.ReceiveFromUser(t.Context(), []bot.Asset{bot.CSAsset("1001")}, srv.TradeURL(userSteamID), userSteamID, "")
+	require.NoError(t, err)
+	require.NotEmpty(t, tradeID)
+
+	status, err := b.GetStatus(t.Context(), tradeID)
+	require.NoError(t, err)
+	assert.Equal(t, bot.TradeOfferStateActive, status.State)
+	assert.False(t, status.IsFinal)
+	require.Len(t, status.ItemsToReceive, 1)
+	assert.Equal(t, "1001", status.ItemsToReceive[0].AssetID)
+
+	require.True(t, srv.SetOfferState(tradeID, bot.TradeOfferStateAccepted))
+
+	status, err = b.GetStatus(t.Context(), tradeID)
+	require.NoError(t, err)
+	assert.Equal(t, bot.TradeOfferStateAccepted, status.State)
+	assert.Equal(t, "Accepted", status.StateName)
+	assert.True(t, status.IsFinal)
+
+	accepted, err := b.GetTradeOffer(t.Context(), tradeID)
+	require.NoError(t, err)
+	receipt, err := b.GetTradeReceipt(t.Context(), accepted.TradeID)
+	require.NoError(t, err)
+
+	newAssetID, ok := receipt.NewAssetID("1001")
+	require.True(t, ok)
+	assert.NotEqual(t, "1001", newAssetID)
+
+	inventory, err := b.GetInventory(t.Context(), bot.CSAppID, bot.CSContextID)
+	require.NoError(t, err)
+	require.Len(t, inventory.Assets, 1)
+	assert.Equal(t, newAssetID, inventory.Assets[0].Assetid)
+}
+
+func TestDepositManyItemsInOneOffer(t *testing.T) {
+	srv := newFakeSteam(t)
+	b := newBot(t, srv)
+
+	assetIDs := []string{"1001", "1002", "1003"}
+	assets := make([]bot.Asset, 0, len(assetIDs))
+	for _, id := range assetIDs {
+		srv.AddInventoryItem(userSteamID, internal.Asset{
+			Appid:      bot.CSAppID,
+			Contextid:  bot.CSContextID,
+			Assetid:    id,
+			Classid:    "310776",
+			Instanceid: "0",
+			Amount:     "1",
+		}, internal.Description{Appid: bot.CSAppID, Classid: "310776", Instanceid: "0", Tradable: 1})
+		assets = append(assets, bot.CSAsset(id))
+	}
+
+	tradeID, err := b.ReceiveFromUser(t.Context(), assets, srv.TradeURL(userSteamID), userSteamID, "")
+	require.NoError(t, err)
+
+	status, err := b.GetStatus(t.Context(), tradeID)
+	require.NoError(t, err)
+	require.Len(t, status.ItemsToReceive, len(assetIDs))
+
+	require.True(t, srv.SetOfferState(tradeID, bot.TradeOfferStateAccepted))
+	accepted, err := b.GetTradeOffer(t.Context(), tradeID)
+	require.NoError(t, err)
+	receipt, err := b.GetTradeReceipt(t.Context(), accepted.TradeID)
+	require.NoError(t, err)
+
+	for _, id := range assetIDs {
+		_, ok := receipt.NewAssetID(id)
+		assert.True(t, ok, id)
+	}
+
+	_, err = b.SendToBuyer(t.Context(), nil, srv.TradeURL(buyerSteamID), buyerSteamID, "")
+	assert.Error(t, err)
+}
+
+func TestDeliveryIsConfirmedAndCancelable(t *testing.T) {
+	srv := newFakeSteam(t)
+	b := newBot(t, srv)
+
+	tradeID, err := b.SendToBuyer(t.Context(), []bot.Asset{bot.CSAsset("2002")}, srv.TradeURL(buyerSteamID), buyerSteamID, "security code: K7PQ2M")
+	require.NoError(t, err)
+
+	sent, ok := srv.Offer(tradeID)
+	require.True(t, ok)
+	assert.Equal(t, bot.TradeOfferStateActive, sent.State)
+	assert.Equal(t, "security code: K7PQ2M", sent.Message)
+
+	require.NoError(t, b.DeclineTrade(t.Context(), tradeID))
+
+	sent, _ = srv.Offer(tradeID)
+	assert.Equal(t, bot.TradeOfferStateCanceled, sent.State)
+}
+
+func TestSendToBuyerWithWrongTradeToken(t *testing.T) {
+	srv := newFakeSteam(t)
+	b := newBot(t, srv)
+
+	_, err := b.SendToBuyer(t.Context(), []bot.Asset{bot.CSAsset("2002")}, srv.URL+"/tradeoffer/new/?partner=1&token=wrong", buyerSteamID, "")
+	assert.ErrorIs(t, err, bot.ErrInvalidTradeToken)
+}
+
+func TestSendOfferErrorsAreTyped(t *testing.T) {
+	srv := newFakeSteam(t)
+	b := newBot(t, srv)
+
+	tests := []struct {
+		status   int
+		strError string
+		want     error
+	}{
+		{http.StatusInternalServerError, "There was an error sending your trade offer.  Please try again later. (26)", bot.ErrItemNoLongerAvailable},
+		{http.StatusInternalServerError, "There was an error sending your trade offer.  Please try again later. (50)", bot.ErrTooManyOffers},
+		{http.StatusInternalServerError, "You cannot trade with buyer because they have a trade ban.", bot.ErrPartnerTradeBanned},
+		{http.StatusUnauthorized, "", bot.ErrSessionExpired},
+		{http.StatusInternalServerError, "Something unexpected (2)", bot.ErrTradeOfferFailed},
+	}
+
+	for _, tt := range tests {
+		srv.FailNextSend(tt.status, tt.strError)
+		_, err := b.SendToBuyer(t.Context(), []bot.Asset{bot.CSAsset("2002")}, srv.TradeURL(buyerSteamID), buyerSteamID, "")
+		assert.ErrorIs(t, err, tt.want, tt.strError)
+	}
+}
+
+func TestSessionRenewAndRestore(t *testing.T) {
+	srv := newFakeSteam(t)
+	b := newBot(t, srv)
+
+	oldToken := b.AccessToken()
+	require.NoError(t, b.RenewAccessToken(t.Context()))
+	assert.NotEqual(t, oldToken, b.AccessToken())
+	require.NoError(t, b.ValidateSession(t.Context()))
+
+	restored := bot.NewSteamClient(&repository.Bot{SteamID: botSteamID}, bot.WithEndpoints(srv.Endpoints()))
+	restored.RestoreSession(b.ExportSession())
+	require.NoError(t, restored.ValidateSession(t.Context()))
+
+	srv.RevokeTokens(botSteamID)
+	assert.ErrorIs(t, restored.ValidateSession(t.Context()), bot.ErrSessionInvalid)
+	assert.ErrorIs(t, restored.RenewAccessToken(t.Context()), bot.ErrRefreshTokenRejected)
+}
+
+func TestValidateSessionRejectedByCommunity(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		status int
+		header http.Header
+	}{
+		{"unauthorized", http.StatusUnauthorized, nil},
+		{"forbidden", http.StatusForbidden, nil},
+		{"login redirect", http.StatusFound, http.Header{"Location": {"/login/home/?goto=chat"}}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newFakeSteam(t)
+			b := newBot(t, srv)
+
+			srv.FailRequests("/chat/clientjstoken", 1, tt.status, tt.header)
+			assert.ErrorIs(t, b.ValidateSession(t.Context()), bot.ErrSessionInvalid)
+			require.NoError(t, b.ValidateSession(t.Context()))
+		})
+	}
+}
+
+func TestSessionRenewDuringPolling(t *testing.T) {
+	srv := newFakeSteam(t)
+	b := newBot(t, srv)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for range 5 {
+			assert.NoError(t, b.RenewAccessToken(t.Context()))
+		}
+	}()
+	for range 5 {
+		_, err := b.GetTradeOffers(t.Context(), true, true, time.Time{})
+		assert.NoError(t, err)
+		b.ExportSession()
+	}
+	<-done
+
+	require.NoError(t, b.ValidateSession(t.Context()))
+}
+
+func TestGetInventoryPages(t *testing.T) {
+	srv := newFakeSteam(t)
+	b := newBot(t, srv)
+
+	const total = 2100
+	for i := range total {
+		classID := strconv.Itoa(100 + i%3)
+		srv.AddInventoryItem(botSteamID, internal.Asset{
+			Appid:      bot.CSAppID,
+			Contextid:  bot.CSContextID,
+			Assetid:    strconv.Itoa(40000 + i),
+			Classid:    classID,
+			Instanceid: "0",
+			Amount:     "1",
+		}, internal.Description{Appid: bot.CSAppID, Classid: classID, Instanceid: "0", Tradable: 1})
+	}
+
+	inventory, err := b.GetInventory(t.Context(), bot.CSAppID, bot.CSContextID)
+	require.NoError(t, err)
+	assert.Len(t, inventory.Assets, total)
+	assert.Len(t, inventory.Descriptions, 3)
+	assert.Equal(t, total, inventory.TotalInventoryCount)
+}
+
+func TestOperationDeadline(t *testing.T) {
+	hang := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	t.Cleanup(hang.Close)
+
+	b := bot.NewSteamClient(&repository.Bot{Username: "bot1", SteamID: botSteamID},
+		bot.WithEndpoints(bot.Endpoints{API: hang.URL, Community: hang.URL, Store: hang.URL, Login: hang.URL}),
+		bot.WithTimeouts(bot.Timeouts{Login: 100 * time.Millisecond}),
+	)
+
+	start := time.Now()
+	err := b.Login(t.Context())
+	require.Error(t, err)
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
+
+func TestTimeSyncIsSharedAcrossBots(t *testing.T) {
+	srv := newFakeSteam(t)
+	srv.SetTimeOffset(90 * time.Second)
+
+	ts := bot.NewTimeSync(srv.URL, srv.Client(), time.Hour)
+	for range 3 {
+		b := bot.NewSteamClient(&repository.Bot{
+			Username:     "bot1",
+			Password:     "hunter2",
+			SteamID:      botSteamID,
+			SharedSecret: "cnOgv/KdpLoP6Nbh0GMkXkPXALQ=",
+		}, bot.WithEndpoints(srv.Endpoints()), bot.WithTimeSync(ts))
+		require.NoError(t, b.Login(t.Context()))
+	}
+
+	assert.Equal(t, 1, srv.TimeQueries())
+	assert.InDelta(t, 90*time.Second, ts.Offset(), float64(2*time.Second))
+}
+
+func TestGetTradeHoldDurations(t *testing.T) {
+	srv := newFakeSteam(t)
+	const heldSteamID = "76561198000000004"
+	srv.AddAccount(fakesteam.Account{SteamID: heldSteamID, Username: "nomobile", TradeToken: "heldtokn", TradeHold: 15 * 24 * time.Hour})
+	b := newBot(t, srv)
+
+	holds, err := b.GetTradeHoldDurations(t.Context(), buyerSteamID, srv.TradeURL(buyerSteamID))
+	require.NoError(t, err)
+	assert.False(t, holds.Held())
+
+	holds, err = b.GetTradeHoldDurations(t.Context(), heldSteamID, srv.TradeURL(heldSteamID))
+	require.NoError(t, err)
+	assert.True(t, holds.Held())
+	assert.Equal(t, 15*24*time.Hour, holds.Their)
+	assert.Equal(t, 15*24*time.Hour, holds.Both)
+	assert.Zero(t, holds.My)
+
+	_, err = b.GetTradeHoldDurations(t.Context(), heldSteamID, srv.URL+"/tradeoffer/new/?partner=4&token=wrong")
+	assert.ErrorIs(t, err, bot.ErrInvalidTradeToken)
+}
+
+func TestReceivedOffersAcceptAndDecline(t *testing.T) {
+	srv := newFakeSteam(t)
+	b := newBot(t, srv)
+
+	srv.AddInventoryItem(userSteamID, internal.Asset{
+		Appid:      bot.CSAppID,
+		Contextid:  bot.CSContextID,
+		Assetid:    "1001",
+		Classid:    "310776",
+		Instanceid: "0",
+		Amount:     "1",
+	}, internal.Description{Appid: bot.CSAppID, Classid: "310776", Instanceid: "0", Tradable: 1})
+
+	gift := srv.SendOffer(userSteamID, botSteamID, []string{"1001"}, nil)
+	scam := srv.SendOffer(buyerSteamID, botSteamID, nil, []string{"2002"})
+
+	offers, err := b.GetTradeOffers(t.Context(), false, true, time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	require.Len(t, offers.Received, 2)
+	for _, o := range offers.Received {
+		assert.False(t, o.IsOurOffer)
+		if o.TradeOfferID == gift {
+			assert.Equal(t, userSteamID, bot.SteamID64(o.AccountIDOther))
+			require.Len(t, o.ItemsToReceive, 1)
+			assert.Empty(t, o.ItemsToGive)
+		}
+	}
+
+	tradeID, err := b.AcceptTradeOffer(t.Context(), gift, userSteamID)
+	require.NoError(t, err)
+	assert.NotEmpty(t, tradeID)
+
+	inventory, err := b.GetInventory(t.Context(), bot.CSAppID, bot.CSContextID)
+	require.NoError(t, err)
+	assert.Len(t, inventory.Assets, 1)
+
+	require.NoError(t, b.DeclineTradeOffer(t.Context(), scam))
+	declined, _ := srv.Offer(scam)
+	assert.Equal(t, bot.TradeOfferStateDeclined, declined.State)
+
+	_, err = b.AcceptTradeOffer(t.Context(), scam, buyerSteamID)
+	assert.ErrorIs(t, err, bot.ErrTradeOfferFailed)
+}
+
+func TestAPIKeyRegisterAndSwap(t *testing.T) {
+	srv := newFakeSteam(t)
+	b := newBot(t, srv)
+
+	key, err := b.GetAPIKey(t.Context())
+	require.NoError(t, err)
+	assert.Empty(t, key.Key)
+
+	key, err = b.RegisterAPIKey(t.Context(), "cstrade.test")
+	require.NoError(t, err)
+	assert.Len(t, key.Key, 32)
+	assert.Equal(t, "cstrade.test", key.Domain)
+
+	srv.SetAPIKey(botSteamID, "0123456789ABCDEF0123456789ABCDEF", "steamcommunlty.example")
+	swapped, err := b.GetAPIKey(t.Context())
+	require.NoError(t, err)
+	assert.NotEqual(t, key.Key, swapped.Key)
+	assert.Equal(t, "steamcommunlty.example", swapped.Domain)
+}
+
+func TestFrozenBotDoesNotTrade(t *testing.T) {
+	srv := newFakeSteam(t)
+	b := newBot(t, srv)
+
+	b.Freeze("api key changed")
+	reason, frozen := b.Frozen()
+	assert.True(t, frozen)
+	assert.Equal(t, "api key changed", reason)
+
+	_, err := b.SendToBuyer(t.Context(), []bot.Asset{bot.CSAsset("2002")}, srv.TradeURL(buyerSteamID), buyerSteamID, "")
+	assert.ErrorIs(t, err, bot.ErrBotFrozen)
+	assert.Zero(t, srv.Requests("/tradeoffer/new/send"))
+
+	b.Unfreeze()
+	_, err = b.SendToBuyer(t.Context(), []bot.Asset{bot.CSAsset("2002")}, srv.TradeURL(buyerSteamID), buyerSteamID, "")
+	assert.NoError(t, err)
+}
+
+func TestAuthFailureIsReportedAndRecovered(t *testing.T) {
+	srv := newFakeSteam(t)
+
+	var failures []int
+	b := bot.NewSteamClient(&repository.Bot{
+		Username:       "bot1",
+		Password:       "hunter2",
+		SteamID:        botSteamID,
+		SharedSecret:   "cnOgv/KdpLoP6Nbh0GMkXkPXALQ=",
+		IdentitySecret: "aBcdEfGhIjKlMnOpQrStUvWxYz0=",
+	}, bot.WithEndpoints(srv.Endpoints()), bot.WithAuthFailureHandler(func(_ *bot.SteamBot, resp *http.Response) {
+		failures = append(failures, resp.StatusCode)
+	}))
+	require.NoError(t, b.Login(t.Context()))
+	require.Empty(t, failures)
+
+	srv.RevokeTokens(botSteamID)
+	_, err := b.GetTradeOffers(t.Context(), true, true, time.Now().Add(-time.Hour))
+	require.Error(t, err)
+	assert.Equal(t, []int{http.StatusUnauthorized}, failures)
+	assert.ErrorIs(t, b.ValidateSession(t.Context()), bot.ErrSessionInvalid)
+
+	require.NoError(t, b.Reauthenticate(t.Context()))
+	require.NoError(t, b.ValidateSession(t.Context()))
+	_, err = b.GetTradeOffers(t.Context(), true, true, time.Now().Add(-time.Hour))
+	assert.NoError(t, err)
+}
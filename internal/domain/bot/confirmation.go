@@ -0,0 +1,203 @@
+package bot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	ConfirmationTypeTrade  = 2
+	ConfirmationTypeMarket = 3
+	ConfirmationTypeAPIKey = 9
+)
+
+const (
+	confirmationLookupAttempts  = 3
+	confirmationLookupRetryWait = 2 * time.Second
+)
+
+type Confirmation struct {
+	Type         int      `json:"type"`
+	TypeName     string   `json:"type_name"`
+	ID           string   `json:"id"`
+	CreatorID    string   `json:"creator_id"`
+	Nonce        string   `json:"nonce"`
+	CreationTime int64    `json:"creation_time"`
+	Headline     string   `json:"headline"`
+	Summary      []string `json:"summary"`
+}
+
+func (sc *SteamBot) confirmationParams(ctx context.Context, tag string) (map[string]string, error) {
+	if sc.IdentitySecret == "" || sc.DeviceID == "" {
+		return nil, fmt.Errorf("identity secret or device id empty")
+	}
+
+	steamTime, err := sc.GetSteamTime(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get Steam time: %w", err)
+	}
+
+	key, err := GenerateConfirmationKey(sc.IdentitySecret, steamTime, tag)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]string{
+		"p":   sc.DeviceID,
+		"a":   sc.SteamID,
+		"k":   key,
+		"t":   strconv.FormatInt(steamTime.Unix(), 10),
+		"m":   "react",
+		"tag": tag,
+	}, nil
+}
+
+func (sc *SteamBot) GetConfirmations(ctx context.Context) ([]Confirmation, error) {
+	ctx, cancel := sc.withTimeout(ctx, sc.timeouts.Confirmation)
+	defer cancel()
+
+	params, err := sc.confirmationParams(ctx, "list")
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := sc.apiCall(ctx, "GET", sc.endpoints.Community+"/mobileconf/getlist", params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get confirmations: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, unexpectedStatus(resp, "failed to get confirmations")
+	}
+
+	var result struct {
+		Success  bool           `json:"success"`
+		NeedAuth bool           `json:"needauth"`
+		Message  string         `json:"message"`
+		Conf     []Confirmation `json:"conf"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode confirmations: %w", err)
+	}
+
+	if result.NeedAuth {
+		return nil, fmt.Errorf("confirmations need auth: %w", ErrSessionExpired)
+	}
+	if !result.Success {
+		return nil, fmt.Errorf("failed to get confirmations: %s", result.Message)
+	}
+
+	return result.Conf, nil
+}
+
+func (sc *SteamBot) AcceptConfirmation(ctx context.Context, conf Confirmation) error {
+	if err := sc.checkFrozen(); err != nil {
+		return err
+	}
+
+	ctx, cancel := sc.withTimeout(ctx, sc.timeouts.Confirmation)
+	defer cancel()
+
+	return sc.sendConfirmationOp(ctx, "allow", "accept", conf)
+}
+
+func (sc *SteamBot) DenyConfirmation(ctx context.Context, conf Confirmation) error {
+	ctx, cancel := sc.withTimeout(ctx, sc.timeouts.Confirmation)
+	defer cancel()
+
+	return sc.sendConfirmationOp(ctx, "cancel", "reject", conf)
+}
+
+func (sc *SteamBot) AcceptConfirmationByCreatorID(ctx context.Context, creatorID string) error {
+	ctx, cancel := sc.withTimeout(ctx, sc.timeouts.Confirmation)
+	defer cancel()
+
+	conf, err := sc.findConfirmation(ctx, creatorID)
+	if err != nil {
+		return err
+	}
+	return sc.AcceptConfirmation(ctx, *conf)
+}
+
+func (sc *SteamBot) DenyConfirmationByCreatorID(ctx context.Context, creatorID string) error {
+	ctx, cancel := sc.withTimeout(ctx, sc.timeouts.Confirmation)
+	defer cancel()
+
+	conf, err := sc.findConfirmation(ctx, creatorID)
+	if err != nil {
+		return err
+	}
+	return sc.DenyConfirmation(ctx, *conf)
+}
+
+// findConfirmation retries for a short while because a freshly created
+// offer can take a few seconds to show up in /mobileconf.
+func (sc *SteamBot) findConfirmation(ctx context.Context, creatorID string) (*Confirmation, error) {
+	for attempt := 1; attempt <= confirmationLookupAttempts; attempt++ {
+		confs, err := sc.GetConfirmations(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, conf := range confs {
+			if conf.CreatorID == creatorID {
+				return &conf, nil
+			}
+		}
+
+		if attempt < confirmationLookupAttempts {
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(confirmationLookupRetryWait):
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("confirmation for %s not found", creatorID)
+}
+
+func (sc *SteamBot) sendConfirmationOp(ctx context.Context, op, tag string, conf Confirmation) error {
+	params, err := sc.confirmationParams(ctx, tag)
+	if err != nil {
+		return err
+	}
+	params["op"] = op
+	params["cid"] = conf.ID
+	params["ck"] = conf.Nonce
+
+	resp, err := sc.apiCall(ctx, "GET", sc.endpoints.Community+"/mobileconf/ajaxop", params)
+	if err != nil {
+		return fmt.Errorf("failed to %s confirmation %s: %w", op, conf.ID, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return unexpectedStatus(resp, fmt.Sprintf("failed to %s confirmation %s", op, conf.ID))
+	}
+
+	var result struct {
+		Success bool   `json:"success"`
+		Message string `json:"message"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("failed to decode confirmation %s response: %w", op, err)
+	}
+	if !result.Success {
+		return fmt.Errorf("confirmation %s %s rejected: %s", op, conf.ID, result.Message)
+	}
+
+	log.Info().Str("confirmation_id", conf.ID).
+		Str("creator_id", conf.CreatorID).
+		Str("op", op).
+		Msg("confirmation sent")
+
+	return nil
+}
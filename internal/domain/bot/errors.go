@@ -0,0 +1,129 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrInvalidTradeToken     = errors.New("trade url token is invalid or the partner cannot trade")
+	ErrPartnerTradeBanned    = errors.New("trade partner is trade banned")
+	ErrItemNoLongerAvailable = errors.New("item is no longer available")
+	ErrTooManyOffers         = errors.New("too many trade offers")
+	ErrSessionExpired        = errors.New("steam session expired")
+	ErrEscrowRequired        = errors.New("trade would be held in escrow")
+	ErrRateLimited           = errors.New("steam rate limit exceeded")
+	ErrSteamUnavailable      = errors.New("steam is temporarily unavailable")
+	ErrTradeOfferFailed      = errors.New("steam rejected the trade offer")
+	ErrBotFrozen             = errors.New("bot is frozen")
+)
+
+// EResult values Steam puts into X-eresult headers and at the end of
+// strError texts, e.g. "... Please try again later. (26)".
+const (
+	EResultAccessDenied       = 15
+	EResultTimeout            = 16
+	EResultBanned             = 17
+	EResultServiceUnavailable = 20
+	EResultNotLoggedOn        = 21
+	EResultRevoked            = 26
+	EResultTooManyPending     = 50
+	EResultRateLimitExceeded  = 84
+)
+
+// SteamError keeps what Steam said next to the sentinel it was classified
+// as, errors.Is matches the sentinel.
+type SteamError struct {
+	StatusCode int
+	EResult    int
+	Message    string
+	Err        error
+}
+
+func (e *SteamError) Error() string {
+	msg := e.Err.Error()
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	if e.EResult != 0 {
+		msg += fmt.Sprintf(" (eresult %d)", e.EResult)
+	}
+	return msg
+}
+
+func (e *SteamError) Unwrap() error {
+	return e.Err
+}
+
+var strErrorCode = regexp.MustCompile(`\((\d+)\)\s*$`)
+
+func parseEResult(strError string) int {
+	m := strErrorCode.FindStringSubmatch(strings.TrimSpace(strError))
+	if m == nil {
+		return 0
+	}
+	code, _ := strconv.Atoi(m[1])
+	return code
+}
+
+// classifySteamError maps an HTTP status, an EResult and a strError text to
+// one of the sentinel errors. It returns nil when nothing points to a known
+// failure.
+func classifySteamError(statusCode, eresult int, strError string) error {
+	if eresult == 0 {
+		eresult = parseEResult(strError)
+	}
+	lower := strings.ToLower(strError)
+
+	var kind error
+	switch {
+	case strings.Contains(lower, "trade ban") || eresult == EResultBanned:
+		kind = ErrPartnerTradeBanned
+	case strings.Contains(lower, "escrow") || strings.Contains(lower, "held for") || strings.Contains(lower, "would be held"):
+		kind = ErrEscrowRequired
+	case eresult == EResultAccessDenied:
+		kind = ErrInvalidTradeToken
+	case eresult == EResultRevoked:
+		kind = ErrItemNoLongerAvailable
+	case eresult == EResultTooManyPending:
+		kind = ErrTooManyOffers
+	case eresult == EResultRateLimitExceeded || statusCode == http.StatusTooManyRequests:
+		kind = ErrRateLimited
+	case eresult == EResultNotLoggedOn || strings.Contains(lower, "logged in") ||
+		statusCode == http.StatusUnauthorized || statusCode == http.StatusForbidden:
+		kind = ErrSessionExpired
+	case eresult == EResultTimeout || eresult == EResultServiceUnavailable || (statusCode >= 500 && eresult == 0 && strError == ""):
+		kind = ErrSteamUnavailable
+	default:
+		return nil
+	}
+
+	return &SteamError{StatusCode: statusCode, EResult: eresult, Message: strError, Err: kind}
+}
+
+// responseError classifies a non OK Web API response by its status code and
+// X-eresult header.
+func responseError(resp *http.Response) error {
+	eresult, _ := strconv.Atoi(resp.Header.Get("X-eresult"))
+	if resp.StatusCode == http.StatusOK && (eresult == 0 || eresult == 1) {
+		return nil
+	}
+	return classifySteamError(resp.StatusCode, eresult, "")
+}
+
+func unexpectedStatus(resp *http.Response, what string) error {
+	if err := responseError(resp); err != nil {
+		return fmt.Errorf("%s: %w", what, err)
+	}
+	return fmt.Errorf("%s: unexpected status code %d", what, resp.StatusCode)
+}
+
+// IsRetryable reports whether the same call may succeed later without the
+// caller changing anything.
+func IsRetryable(err error) bool {
+	return errors.Is(err, ErrRateLimited) || errors.Is(err, ErrSteamUnavailable)
+}
@@ -0,0 +1,66 @@
+package bot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// TradeHoldDurations says how long items would be held if a trade with the
+// partner were made now. Both is the hold the trade itself gets.
+type TradeHoldDurations struct {
+	My    time.Duration
+	Their time.Duration
+	Both  time.Duration
+}
+
+func (h *TradeHoldDurations) Held() bool {
+	return h.Both > 0
+}
+
+func (sc *SteamBot) GetTradeHoldDurations(ctx context.Context, partnerSteamID, tradeURL string) (*TradeHoldDurations, error) {
+	ctx, cancel := sc.withTimeout(ctx, sc.timeouts.API)
+	defer cancel()
+
+	_, token, err := parseTradeURL(tradeURL)
+	if err != nil {
+		return nil, err
+	}
+
+	params := map[string]string{
+		"access_token":             sc.AccessToken(),
+		"steamid_target":           partnerSteamID,
+		"trade_offer_access_token": token,
+	}
+
+	resp, err := sc.apiCall(ctx, "GET", "/IEconService/GetTradeHoldDurations/v1/", params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get trade hold durations %s: %w", partnerSteamID, err)
+	}
+	defer resp.Body.Close()
+
+	if err := responseError(resp); err != nil {
+		return nil, fmt.Errorf("failed to get trade hold durations %s: %w", partnerSteamID, err)
+	}
+
+	type escrow struct {
+		Seconds int64 `json:"escrow_end_duration_seconds"`
+	}
+	var result struct {
+		Response struct {
+			MyEscrow    escrow `json:"my_escrow"`
+			TheirEscrow escrow `json:"their_escrow"`
+			BothEscrow  escrow `json:"both_escrow"`
+		} `json:"response"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode trade hold durations %s: %w", partnerSteamID, err)
+	}
+
+	return &TradeHoldDurations{
+		My:    time.Duration(result.Response.MyEscrow.Seconds) * time.Second,
+		Their: time.Duration(result.Response.TheirEscrow.Seconds) * time.Second,
+		Both:  time.Duration(result.Response.BothEscrow.Seconds) * time.Second,
+	}, nil
+}
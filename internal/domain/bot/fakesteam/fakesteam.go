@@ -0,0 +1,1122 @@
+// Package fakesteam is an in-memory stand-in for the Steam Web API and the
+// community site, built on httptest. Point a bot at it with
+// bot.WithEndpoints(srv.Endpoints()) and drive trade offers from tests.
+package fakesteam
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"csTrade/internal"
+	"csTrade/internal/domain/bot"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const steamID64Base = 76561197960265728
+
+type Account struct {
+	SteamID        string
+	Username       string
+	Password       string
+	SharedSecret   string
+	IdentitySecret string
+	TradeToken     string
+	// TradeHold is how long trades with this account are held in escrow.
+	TradeHold time.Duration
+
+	APIKey       string
+	APIKeyDomain string
+}
+
+type authSession struct {
+	steamID       string
+	requestID     string
+	authenticated bool
+}
+
+type tradeOffer struct {
+	bot.TradeOffer
+	sender  string
+	partner string
+}
+
+// trade is the receipt of an accepted offer, seen from the sender.
+type trade struct {
+	bot.TradeReceipt
+	sender  string
+	partner string
+}
+
+type inventoryItem struct {
+	asset       internal.Asset
+	description internal.Description
+}
+
+type scriptedError struct {
+	status   int
+	strError string
+}
+
+type scriptedFailure struct {
+	remaining int
+	status    int
+	header    http.Header
+}
+
+type Server struct {
+	*httptest.Server
+
+	mu          sync.Mutex
+	key         *rsa.PrivateKey
+	timeOffset  time.Duration
+	timeQueries int
+	accounts    map[string]*Account
+	auth        map[string]*authSession
+	tokens      map[string]string
+	refresh     map[string]string
+	offers      map[string]*tradeOffer
+	nextOfferID int64
+	trades      map[string]*trade
+	nextAssetID int64
+	inventories map[string][]inventoryItem
+	sendErr     *scriptedError
+	failures    map[string]*scriptedFailure
+	requests    map[string]int
+}
+
+func New() *Server {
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		panic(fmt.Sprintf("fakesteam: generate rsa key: %v", err))
+	}
+
+	s := &Server{
+		key:         key,
+		accounts:    make(map[string]*Account),
+		auth:        make(map[string]*authSession),
+		tokens:      make(map[string]string),
+		refresh:     make(map[string]string),
+		offers:      make(map[string]*tradeOffer),
+		nextOfferID: 5000000000,
+		trades:      make(map[string]*trade),
+		nextAssetID: 30000000000,
+		inventories: make(map[string][]inventoryItem),
+		failures:    make(map[string]*scriptedFailure),
+		requests:    make(map[string]int),
+	}
+
+	mux := http.NewServeMux()
+	s.routes(mux)
+	s.Server = httptest.NewServer(s.withFailures(mux))
+
+	return s
+}
+
+func (s *Server) routes(mux *http.ServeMux) {
+	mux.HandleFunc("/ITwoFactorService/QueryTime/v1/", s.handleQueryTime)
+	mux.HandleFunc("/IAuthenticationService/GetPasswordRSAPublicKey/v1/", s.handleRSAKey)
+	mux.HandleFunc("/IAuthenticationService/BeginAuthSessionViaCredentials/v1/", s.handleBeginAuth)
+	mux.HandleFunc("/IAuthenticationService/UpdateAuthSessionWithSteamGuardCode/v1/", s.handleGuardCode)
+	mux.HandleFunc("/IAuthenticationService/PollAuthSessionStatus/v1/", s.handlePollAuth)
+	mux.HandleFunc("/IAuthenticationService/GenerateAccessTokenForApp/v1/", s.handleRenewToken)
+	mux.HandleFunc("/IEconService/GetTradeOffer/v1/", s.handleGetTradeOffer)
+	mux.HandleFunc("/IEconService/GetTradeOffers/v1/", s.handleGetTradeOffers)
+	mux.HandleFunc("/IEconService/GetTradeStatus/v1/", s.handleGetTradeStatus)
+	mux.HandleFunc("/IEconService/GetTradeHoldDurations/v1/", s.handleTradeHoldDurations)
+
+	mux.HandleFunc("GET /{$}", s.handleCommunityRoot)
+	mux.HandleFunc("GET /account", s.handleStoreAccount)
+	mux.HandleFunc("GET /chat/clientjstoken", s.handleClientJSToken)
+	mux.HandleFunc("POST /tradeoffer/new/send", s.handleSendOffer)
+	mux.HandleFunc("POST /tradeoffer/{id}/cancel", s.handleCancelOffer)
+	mux.HandleFunc("POST /tradeoffer/{id}/accept", s.handleAcceptOffer)
+	mux.HandleFunc("POST /tradeoffer/{id}/decline", s.handleDeclineOffer)
+	mux.HandleFunc("GET /mobileconf/getlist", s.handleConfirmationList)
+	mux.HandleFunc("GET /mobileconf/ajaxop", s.handleConfirmationOp)
+	mux.HandleFunc("GET /dev/apikey", s.handleAPIKey)
+	mux.HandleFunc("POST /dev/registerkey", s.handleRegisterKey)
+	mux.HandleFunc("GET /inventory/{steamid}/{appid}/{contextid}", s.handleInventory)
+}
+
+func (s *Server) Endpoints() bot.Endpoints {
+	return bot.Endpoints{
+		API:       s.URL,
+		Community: s.URL,
+		Store:     s.URL,
+		Login:     s.URL,
+	}
+}
+
+func (s *Server) AddAccount(a Account) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	acc := a
+	s.accounts[a.SteamID] = &acc
+}
+
+// TradeURL builds the trade link of a registered account.
+func (s *Server) TradeURL(steamID string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	acc, ok := s.accounts[steamID]
+	if !ok {
+		return ""
+	}
+	return fmt.Sprintf("%s/tradeoffer/new/?partner=%d&token=%s", s.URL, accountID(steamID), acc.TradeToken)
+}
+
+func (s *Server) SetTimeOffset(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.timeOffset = d
+}
+
+// FailRequests makes the next n requests to path answer with status and
+// the given headers before they reach the handler.
+func (s *Server) FailRequests(path string, n, status int, header http.Header) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures[path] = &scriptedFailure{remaining: n, status: status, header: header}
+}
+
+// Requests counts the requests made to path, failed ones included.
+func (s *Server) Requests(path string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests[path]
+}
+
+func (s *Server) withFailures(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.requests[r.URL.Path]++
+		f, ok := s.failures[r.URL.Path]
+		if ok && f.remaining > 0 {
+			f.remaining--
+			s.mu.Unlock()
+			for k, v := range f.header {
+				w.Header()[k] = v
+			}
+			writeJSON(w, f.status, map[string]any{})
+			return
+		}
+		s.mu.Unlock()
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// TimeQueries counts the QueryTime calls served so far.
+func (s *Server) TimeQueries() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.timeQueries
+}
+
+// RevokeTokens invalidates every access and refresh token of the account.
+func (s *Server) RevokeTokens(steamID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for token, owner := range s.tokens {
+		if owner == steamID {
+			delete(s.tokens, token)
+		}
+	}
+	for token, owner := range s.refresh {
+		if owner == steamID {
+			delete(s.refresh, token)
+		}
+	}
+}
+
+// FailNextSend makes the next tradeoffer/new/send answer with the given
+// HTTP status and strError.
+func (s *Server) FailNextSend(status int, strError string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sendErr = &scriptedError{status: status, strError: strError}
+}
+
+// Offer returns the offer as its sender sees it.
+func (s *Server) Offer(id string) (bot.TradeOffer, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	o, ok := s.offers[id]
+	if !ok {
+		return bot.TradeOffer{}, false
+	}
+	return s.view(o, o.sender), true
+}
+
+func (s *Server) SetOfferState(id string, state bot.TradeOfferState) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	o, ok := s.offers[id]
+	if !ok {
+		return false
+	}
+	o.State = state
+	o.TimeUpdated = s.now().Unix()
+	if state == bot.TradeOfferStateAccepted && o.TradeID == "" {
+		o.TradeID = strconv.FormatInt(o.TimeUpdated*1000+int64(len(s.offers)), 10)
+		s.settle(o)
+	}
+	return true
+}
+
+// SendOffer makes from send an active offer to to, as a user doing it on the
+// site would. give and request are CS asset IDs of either side.
+func (s *Server) SendOffer(from, to string, give, request []string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	items := func(assetIDs []string) []bot.TradeItem {
+		out := []bot.TradeItem{}
+		for _, id := range assetIDs {
+			out = append(out, bot.TradeItem{AppID: bot.CSAppID, ContextID: bot.CSContextID, AssetID: id, Amount: "1"})
+		}
+		return out
+	}
+
+	now := s.now().Unix()
+	s.nextOfferID++
+	o := &tradeOffer{
+		TradeOffer: bot.TradeOffer{
+			TradeOfferID:   strconv.FormatInt(s.nextOfferID, 10),
+			ExpirationTime: now + 14*24*3600,
+			State:          bot.TradeOfferStateActive,
+			ItemsToGive:    items(give),
+			ItemsToReceive: items(request),
+			TimeCreated:    now,
+			TimeUpdated:    now,
+		},
+		sender:  from,
+		partner: to,
+	}
+	s.offers[o.TradeOfferID] = o
+	return o.TradeOfferID
+}
+
+// SetAPIKey replaces the account's Web API key, the way malware on a
+// compromised machine would.
+func (s *Server) SetAPIKey(steamID, key, domain string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if acc, ok := s.accounts[steamID]; ok {
+		acc.APIKey = key
+		acc.APIKeyDomain = domain
+	}
+}
+
+// settle moves the items of an accepted offer between inventories. Like
+// Steam, every moved item gets a new asset ID.
+func (s *Server) settle(o *tradeOffer) {
+	t := &trade{
+		TradeReceipt: bot.TradeReceipt{
+			TradeID:      o.TradeID,
+			SteamIDOther: o.partner,
+			TimeInit:     o.TimeUpdated,
+			Status:       3,
+		},
+		sender:  o.sender,
+		partner: o.partner,
+	}
+	for _, item := range o.ItemsToReceive {
+		t.AssetsReceived = append(t.AssetsReceived, s.move(item, o.partner, o.sender))
+	}
+	for _, item := range o.ItemsToGive {
+		t.AssetsGiven = append(t.AssetsGiven, s.move(item, o.sender, o.partner))
+	}
+	s.trades[o.TradeID] = t
+}
+
+func (s *Server) move(item bot.TradeItem, from, to string) bot.TradeAsset {
+	s.nextAssetID++
+	moved := bot.TradeAsset{
+		AppID:        item.AppID,
+		ContextID:    item.ContextID,
+		AssetID:      item.AssetID,
+		Amount:       item.Amount,
+		ClassID:      item.ClassID,
+		InstanceID:   item.InstanceID,
+		NewAssetID:   strconv.FormatInt(s.nextAssetID, 10),
+		NewContextID: item.ContextID,
+	}
+
+	fromKey := inventoryKey(from, strconv.Itoa(item.AppID), item.ContextID)
+	items := s.inventories[fromKey]
+	for i, it := range items {
+		if it.asset.Assetid != item.AssetID {
+			continue
+		}
+		s.inventories[fromKey] = append(items[:i:i], items[i+1:]...)
+		it.asset.Assetid = moved.NewAssetID
+		toKey := inventoryKey(to, strconv.Itoa(item.AppID), item.ContextID)
+		s.inventories[toKey] = append(s.inventories[toKey], it)
+		moved.ClassID, moved.InstanceID = it.asset.Classid, it.asset.Instanceid
+		break
+	}
+
+	return moved
+}
+
+func (s *Server) AddInventoryItem(steamID string, asset internal.Asset, desc internal.Description) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := inventoryKey(steamID, strconv.Itoa(asset.Appid), asset.Contextid)
+	s.inventories[key] = append(s.inventories[key], inventoryItem{asset: asset, description: desc})
+}
+
+func (s *Server) now() time.Time {
+	return time.Now().Add(s.timeOffset)
+}
+
+func (s *Server) handleQueryTime(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	now := s.now()
+	s.timeQueries++
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"response": map[string]any{
+			"server_time":            strconv.FormatInt(now.Unix(), 10),
+			"skew_tolerance_seconds": "60",
+		},
+	})
+}
+
+func (s *Server) handleRSAKey(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"response": map[string]any{
+			"publickey_mod": s.key.N.Text(16),
+			"publickey_exp": strconv.FormatInt(int64(s.key.E), 16),
+			"timestamp":     strconv.FormatInt(time.Now().UnixMicro(), 10),
+		},
+	})
+}
+
+func (s *Server) handleBeginAuth(w http.ResponseWriter, r *http.Request) {
+	_ = r.ParseForm()
+
+	encrypted, err := base64.StdEncoding.DecodeString(r.PostForm.Get("encrypted_password"))
+	if err != nil {
+		writeEResult(w, 8)
+		return
+	}
+	password, err := rsa.DecryptPKCS1v15(rand.Reader, s.key, encrypted)
+	if err != nil {
+		writeEResult(w, 8)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	acc := s.accountByName(r.PostForm.Get("account_name"))
+	if acc == nil || acc.Password != string(password) {
+		writeEResult(w, 5)
+		return
+	}
+
+	clientID := randomDigits(18)
+	requestID := base64.StdEncoding.EncodeToString([]byte(randomHex(16)))
+	s.auth[clientID] = &authSession{steamID: acc.SteamID, requestID: requestID}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"response": map[string]any{
+			"client_id":             clientID,
+			"request_id":            requestID,
+			"interval":              5,
+			"allowed_confirmations": []map[string]any{{"confirmation_type": 3}},
+			"steamid":               acc.SteamID,
+			"weak_token":            makeToken(acc.SteamID, s.now().Add(time.Hour)),
+		},
+	})
+}
+
+func (s *Server) handleGuardCode(w http.ResponseWriter, r *http.Request) {
+	_ = r.ParseForm()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, ok := s.auth[r.PostForm.Get("client_id")]
+	if !ok || session.steamID != r.PostForm.Get("steamid") {
+		writeEResult(w, 8)
+		return
+	}
+
+	acc := s.accounts[session.steamID]
+	if !s.validAuthCode(acc.SharedSecret, r.PostForm.Get("code")) {
+		writeEResult(w, 65)
+		return
+	}
+
+	session.authenticated = true
+	writeJSON(w, http.StatusOK, map[string]any{"response": map[string]any{}})
+}
+
+func (s *Server) handlePollAuth(w http.ResponseWriter, r *http.Request) {
+	_ = r.ParseForm()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, ok := s.auth[r.PostForm.Get("client_id")]
+	if !ok || session.requestID != r.PostForm.Get("request_id") {
+		writeEResult(w, 8)
+		return
+	}
+	if !session.authenticated {
+		writeJSON(w, http.StatusOK, map[string]any{"response": map[string]any{}})
+		return
+	}
+	delete(s.auth, r.PostForm.Get("client_id"))
+
+	access, refresh := s.issueTokens(session.steamID)
+	writeJSON(w, http.StatusOK, map[string]any{
+		"response": map[string]any{
+			"access_token":  access,
+			"refresh_token": refresh,
+			"account_name":  s.accounts[session.steamID].Username,
+		},
+	})
+}
+
+func (s *Server) handleRenewToken(w http.ResponseWriter, r *http.Request) {
+	_ = r.ParseForm()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	token := r.PostForm.Get("refresh_token")
+	steamID, ok := s.refresh[token]
+	if !ok || steamID != r.PostForm.Get("steamid") {
+		writeEResult(w, 8)
+		return
+	}
+
+	access := makeToken(steamID, s.now().Add(24*time.Hour))
+	s.tokens[access] = steamID
+
+	response := map[string]any{"access_token": access}
+	if r.PostForm.Get("renewal_type") == "1" {
+		delete(s.refresh, token)
+		refresh := makeToken(steamID, s.now().Add(200*24*time.Hour))
+		s.refresh[refresh] = steamID
+		response["refresh_token"] = refresh
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{"response": response})
+}
+
+func (s *Server) handleCommunityRoot(w http.ResponseWriter, r *http.Request) {
+	if _, err := r.Cookie("sessionid"); err != nil {
+		http.SetCookie(w, &http.Cookie{Name: "sessionid", Value: randomHex(12), Path: "/"})
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) handleStoreAccount(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) handleClientJSToken(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	steamID, ok := s.communityUser(r)
+	s.mu.Unlock()
+
+	if !ok {
+		writeJSON(w, http.StatusOK, map[string]any{"logged_in": false})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"logged_in":  true,
+		"steamid":    steamID,
+		"account_id": accountID(steamID),
+	})
+}
+
+func (s *Server) handleSendOffer(w http.ResponseWriter, r *http.Request) {
+	_ = r.ParseForm()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sender, ok := s.communityUser(r)
+	if !ok || !validSessionID(r) {
+		writeJSON(w, http.StatusUnauthorized, nil)
+		return
+	}
+
+	if s.sendErr != nil {
+		scripted := s.sendErr
+		s.sendErr = nil
+		writeJSON(w, scripted.status, map[string]any{"strError": scripted.strError})
+		return
+	}
+
+	partner := r.PostForm.Get("partner")
+	var params struct {
+		Token string `json:"trade_offer_access_token"`
+	}
+	_ = json.Unmarshal([]byte(r.PostForm.Get("trade_offer_create_params")), &params)
+	if acc, known := s.accounts[partner]; known && acc.TradeToken != "" && acc.TradeToken != params.Token {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{
+			"strError": "There was an error sending your trade offer.  Please try again later. (15)",
+		})
+		return
+	}
+
+	var offerJSON struct {
+		Me struct {
+			Assets []map[string]any `json:"assets"`
+		} `json:"me"`
+		Them struct {
+			Assets []map[string]any `json:"assets"`
+		} `json:"them"`
+	}
+	if err := json.Unmarshal([]byte(r.PostForm.Get("json_tradeoffer")), &offerJSON); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"strError": "invalid json_tradeoffer"})
+		return
+	}
+
+	now := s.now().Unix()
+	s.nextOfferID++
+	o := &tradeOffer{
+		TradeOffer: bot.TradeOffer{
+			TradeOfferID:   strconv.FormatInt(s.nextOfferID, 10),
+			Message:        r.PostForm.Get("tradeoffermessage"),
+			ExpirationTime: now + 14*24*3600,
+			State:          bot.TradeOfferStateActive,
+			ItemsToGive:    toTradeItems(offerJSON.Me.Assets),
+			ItemsToReceive: toTradeItems(offerJSON.Them.Assets),
+			TimeCreated:    now,
+			TimeUpdated:    now,
+		},
+		sender:  sender,
+		partner: partner,
+	}
+
+	needsConfirmation := len(o.ItemsToGive) > 0
+	if needsConfirmation {
+		o.State = bot.TradeOfferStateNeedsConfirmation
+	}
+	s.offers[o.TradeOfferID] = o
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"tradeofferid":              o.TradeOfferID,
+		"needs_mobile_confirmation": needsConfirmation,
+		"needs_email_confirmation":  false,
+		"email_domain":              "",
+	})
+}
+
+func (s *Server) handleCancelOffer(w http.ResponseWriter, r *http.Request) {
+	_ = r.ParseForm()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sender, ok := s.communityUser(r)
+	if !ok || !validSessionID(r) {
+		writeJSON(w, http.StatusUnauthorized, nil)
+		return
+	}
+
+	o, ok := s.offers[r.PathValue("id")]
+	if !ok || o.sender != sender || o.State.IsFinal() {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"success": 11})
+		return
+	}
+
+	o.State = bot.TradeOfferStateCanceled
+	o.TimeUpdated = s.now().Unix()
+	writeJSON(w, http.StatusOK, map[string]any{"tradeofferid": o.TradeOfferID})
+}
+
+func (s *Server) handleAcceptOffer(w http.ResponseWriter, r *http.Request) {
+	_ = r.ParseForm()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	partner, ok := s.communityUser(r)
+	if !ok || !validSessionID(r) {
+		writeJSON(w, http.StatusUnauthorized, nil)
+		return
+	}
+
+	o, ok := s.offers[r.PathValue("id")]
+	if !ok || o.partner != partner || o.State != bot.TradeOfferStateActive {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{
+			"strError": "There was an error accepting this trade offer.  Please try again later. (11)",
+		})
+		return
+	}
+
+	o.State = bot.TradeOfferStateAccepted
+	o.TimeUpdated = s.now().Unix()
+	o.TradeID = strconv.FormatInt(o.TimeUpdated*1000+int64(len(s.offers)), 10)
+	s.settle(o)
+	writeJSON(w, http.StatusOK, map[string]any{"tradeid": o.TradeID})
+}
+
+func (s *Server) handleDeclineOffer(w http.ResponseWriter, r *http.Request) {
+	_ = r.ParseForm()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	partner, ok := s.communityUser(r)
+	if !ok || !validSessionID(r) {
+		writeJSON(w, http.StatusUnauthorized, nil)
+		return
+	}
+
+	o, ok := s.offers[r.PathValue("id")]
+	if !ok || o.partner != partner || o.State.IsFinal() {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"success": 11})
+		return
+	}
+
+	o.State = bot.TradeOfferStateDeclined
+	o.TimeUpdated = s.now().Unix()
+	writeJSON(w, http.StatusOK, map[string]any{"tradeofferid": o.TradeOfferID})
+}
+
+func (s *Server) handleGetTradeOffer(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	viewer, ok := s.apiUser(r)
+	if !ok {
+		writeJSON(w, http.StatusUnauthorized, nil)
+		return
+	}
+
+	o, ok := s.offers[r.URL.Query().Get("tradeofferid")]
+	if !ok || (o.sender != viewer && o.partner != viewer) {
+		writeJSON(w, http.StatusOK, map[string]any{"response": map[string]any{}})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"response": map[string]any{"offer": s.view(o, viewer)},
+	})
+}
+
+func (s *Server) handleGetTradeOffers(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	viewer, ok := s.apiUser(r)
+	if !ok {
+		writeJSON(w, http.StatusUnauthorized, nil)
+		return
+	}
+
+	q := r.URL.Query()
+	activeOnly := isTrue(q.Get("active_only"))
+	cutoff, _ := strconv.ParseInt(q.Get("time_historical_cutoff"), 10, 64)
+
+	sent := []bot.TradeOffer{}
+	received := []bot.TradeOffer{}
+	for _, o := range s.offers {
+		if activeOnly && o.State.IsFinal() && o.TimeUpdated < cutoff {
+			continue
+		}
+		if o.sender == viewer && isTrue(q.Get("get_sent_offers")) {
+			sent = append(sent, s.view(o, viewer))
+		}
+		if o.partner == viewer && isTrue(q.Get("get_received_offers")) {
+			received = append(received, s.view(o, viewer))
+		}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"response": map[string]any{
+			"trade_offers_sent":     sent,
+			"trade_offers_received": received,
+		},
+	})
+}
+
+func (s *Server) handleGetTradeStatus(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	viewer, ok := s.apiUser(r)
+	if !ok {
+		writeJSON(w, http.StatusUnauthorized, nil)
+		return
+	}
+
+	trades := []bot.TradeReceipt{}
+	if t, ok := s.trades[r.URL.Query().Get("tradeid")]; ok {
+		switch viewer {
+		case t.sender:
+			trades = append(trades, t.TradeReceipt)
+		case t.partner:
+			receipt := t.TradeReceipt
+			receipt.SteamIDOther = t.sender
+			receipt.AssetsReceived, receipt.AssetsGiven = t.AssetsGiven, t.AssetsReceived
+			trades = append(trades, receipt)
+		}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"response": map[string]any{"trades": trades},
+	})
+}
+
+func (s *Server) handleTradeHoldDurations(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	viewer, ok := s.apiUser(r)
+	if !ok {
+		writeJSON(w, http.StatusUnauthorized, nil)
+		return
+	}
+
+	q := r.URL.Query()
+	target, known := s.accounts[q.Get("steamid_target")]
+	if !known || (target.TradeToken != "" && target.TradeToken != q.Get("trade_offer_access_token")) {
+		writeEResult(w, 15)
+		return
+	}
+
+	mine := int64(s.accounts[viewer].TradeHold.Seconds())
+	theirs := int64(target.TradeHold.Seconds())
+	writeJSON(w, http.StatusOK, map[string]any{
+		"response": map[string]any{
+			"my_escrow":    map[string]any{"escrow_end_duration_seconds": mine},
+			"their_escrow": map[string]any{"escrow_end_duration_seconds": theirs},
+			"both_escrow":  map[string]any{"escrow_end_duration_seconds": max(mine, theirs)},
+		},
+	})
+}
+
+func (s *Server) handleAPIKey(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	steamID, ok := s.communityUser(r)
+	if !ok {
+		http.Redirect(w, r, "/login/home/?goto=dev%2Fapikey", http.StatusFound)
+		return
+	}
+
+	acc := s.accounts[steamID]
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if acc.APIKey == "" {
+		fmt.Fprint(w, `<div id="bodyContents_ex"><h2>Register for a new Steam Web API Key</h2><form action="/dev/registerkey" method="POST"></form></div>`)
+		return
+	}
+	fmt.Fprintf(w, `<div id="bodyContents_ex"><h2>Your Steam Web API Key</h2><p>Key: %s</p><p>Domain Name: %s</p></div>`,
+		acc.APIKey, acc.APIKeyDomain)
+}
+
+func (s *Server) handleRegisterKey(w http.ResponseWriter, r *http.Request) {
+	_ = r.ParseForm()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	steamID, ok := s.communityUser(r)
+	if !ok || !validSessionID(r) || r.PostForm.Get("agreeToTerms") != "agreed" {
+		writeJSON(w, http.StatusForbidden, nil)
+		return
+	}
+
+	acc := s.accounts[steamID]
+	acc.APIKey = strings.ToUpper(randomHex(16))
+	acc.APIKeyDomain = r.PostForm.Get("domain")
+	http.Redirect(w, r, "/dev/apikey", http.StatusFound)
+}
+
+func (s *Server) handleConfirmationList(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	steamID, ok := s.confirmationUser(r, "list")
+	if !ok {
+		writeJSON(w, http.StatusOK, map[string]any{"success": false, "needauth": true})
+		return
+	}
+
+	confs := []bot.Confirmation{}
+	for _, o := range s.offers {
+		if o.sender != steamID || o.State != bot.TradeOfferStateNeedsConfirmation {
+			continue
+		}
+		confs = append(confs, bot.Confirmation{
+			Type:         bot.ConfirmationTypeTrade,
+			TypeName:     "Trade Offer",
+			ID:           "9" + o.TradeOfferID,
+			CreatorID:    o.TradeOfferID,
+			Nonce:        "n" + o.TradeOfferID,
+			CreationTime: o.TimeCreated,
+			Headline:     "Trade offer",
+		})
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{"success": true, "needauth": false, "conf": confs})
+}
+
+func (s *Server) handleConfirmationOp(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	steamID, ok := s.confirmationUser(r, q.Get("tag"))
+	if !ok {
+		writeJSON(w, http.StatusOK, map[string]any{"success": false, "needauth": true})
+		return
+	}
+
+	offerID := strings.TrimPrefix(q.Get("cid"), "9")
+	o, ok := s.offers[offerID]
+	if !ok || o.sender != steamID || q.Get("ck") != "n"+offerID || o.State != bot.TradeOfferStateNeedsConfirmation {
+		writeJSON(w, http.StatusOK, map[string]any{"success": false})
+		return
+	}
+
+	switch q.Get("op") {
+	case "allow":
+		o.State = bot.TradeOfferStateActive
+	case "cancel":
+		o.State = bot.TradeOfferStateCanceledBySecondFactor
+	default:
+		writeJSON(w, http.StatusOK, map[string]any{"success": false})
+		return
+	}
+	o.TimeUpdated = s.now().Unix()
+
+	writeJSON(w, http.StatusOK, map[string]any{"success": true})
+}
+
+func (s *Server) handleInventory(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	count, err := strconv.Atoi(q.Get("count"))
+	if err != nil || count <= 0 {
+		count = 75
+	}
+	startAssetID := q.Get("start_assetid")
+
+	s.mu.Lock()
+	items := s.inventories[inventoryKey(r.PathValue("steamid"), r.PathValue("appid"), r.PathValue("contextid"))]
+	s.mu.Unlock()
+
+	start := 0
+	if startAssetID != "" {
+		for i, it := range items {
+			if it.asset.Assetid == startAssetID {
+				start = i + 1
+				break
+			}
+		}
+	}
+
+	end := min(start+count, len(items))
+	page := items[start:end]
+
+	assets := []internal.Asset{}
+	descriptions := []internal.Description{}
+	seen := make(map[string]bool)
+	for _, it := range page {
+		assets = append(assets, it.asset)
+		key := it.asset.Classid + "_" + it.asset.Instanceid
+		if !seen[key] {
+			seen[key] = true
+			descriptions = append(descriptions, it.description)
+		}
+	}
+
+	response := map[string]any{
+		"assets":                assets,
+		"descriptions":          descriptions,
+		"total_inventory_count": len(items),
+		"success":               1,
+	}
+	if end < len(items) {
+		response["more_items"] = 1
+		response["last_assetid"] = page[len(page)-1].asset.Assetid
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (s *Server) view(o *tradeOffer, viewer string) bot.TradeOffer {
+	v := o.TradeOffer
+	if viewer == o.sender {
+		v.IsOurOffer = true
+		v.AccountIDOther = accountID(o.partner)
+		return v
+	}
+
+	v.IsOurOffer = false
+	v.AccountIDOther = accountID(o.sender)
+	v.ItemsToGive, v.ItemsToReceive = o.ItemsToReceive, o.ItemsToGive
+	return v
+}
+
+func (s *Server) issueTokens(steamID string) (string, string) {
+	access := makeToken(steamID, s.now().Add(24*time.Hour))
+	refresh := makeToken(steamID, s.now().Add(200*24*time.Hour))
+	s.tokens[access] = steamID
+	s.refresh[refresh] = steamID
+	return access, refresh
+}
+
+func (s *Server) accountByName(username string) *Account {
+	for _, acc := range s.accounts {
+		if acc.Username == username {
+			return acc
+		}
+	}
+	return nil
+}
+
+func (s *Server) apiUser(r *http.Request) (string, bool) {
+	steamID, ok := s.tokens[r.URL.Query().Get("access_token")]
+	return steamID, ok
+}
+
+func (s *Server) communityUser(r *http.Request) (string, bool) {
+	c, err := r.Cookie("steamLoginSecure")
+	if err != nil {
+		return "", false
+	}
+
+	value := strings.ReplaceAll(c.Value, "%7C%7C", "||")
+	parts := strings.SplitN(value, "||", 2)
+	if len(parts) != 2 {
+		return "", false
+	}
+
+	steamID, ok := s.tokens[parts[1]]
+	if !ok || steamID != parts[0] {
+		return "", false
+	}
+	return steamID, true
+}
+
+func (s *Server) confirmationUser(r *http.Request, tag string) (string, bool) {
+	steamID, ok := s.communityUser(r)
+	if !ok {
+		return "", false
+	}
+
+	q := r.URL.Query()
+	acc := s.accounts[steamID]
+	if q.Get("a") != steamID || q.Get("p") == "" {
+		return "", false
+	}
+
+	t, err := strconv.ParseInt(q.Get("t"), 10, 64)
+	if err != nil {
+		return "", false
+	}
+	key, err := bot.GenerateConfirmationKey(acc.IdentitySecret, time.Unix(t, 0), tag)
+	if err != nil || key != q.Get("k") {
+		return "", false
+	}
+	return steamID, true
+}
+
+func (s *Server) validAuthCode(sharedSecret, code string) bool {
+	now := s.now()
+	for _, skew := range []time.Duration{0, -30 * time.Second, 30 * time.Second} {
+		if expected, err := bot.GenerateAuthCode(sharedSecret, now.Add(skew)); err == nil && expected == code {
+			return true
+		}
+	}
+	return false
+}
+
+func validSessionID(r *http.Request) bool {
+	c, err := r.Cookie("sessionid")
+	return err == nil && c.Value != "" && c.Value == r.PostForm.Get("sessionid")
+}
+
+func makeToken(steamID string, exp time.Time) string {
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"typ":"JWT","alg":"EdDSA"}`))
+	payload, _ := json.Marshal(map[string]any{
+		"iss": "steam",
+		"sub": steamID,
+		"exp": exp.Unix(),
+		"jti": randomHex(8),
+	})
+	return header + "." + base64.RawURLEncoding.EncodeToString(payload) + "." + randomHex(16)
+}
+
+func toTradeItems(assets []map[string]any) []bot.TradeItem {
+	items := []bot.TradeItem{}
+	for _, a := range assets {
+		appID, _ := strconv.Atoi(fmt.Sprint(a["appid"]))
+		amount := "1"
+		if v, ok := a["amount"]; ok {
+			amount = fmt.Sprint(v)
+		}
+		items = append(items, bot.TradeItem{
+			AppID:     appID,
+			ContextID: fmt.Sprint(a["contextid"]),
+			AssetID:   fmt.Sprint(a["assetid"]),
+			Amount:    amount,
+		})
+	}
+	return items
+}
+
+func accountID(steamID string) uint32 {
+	id, err := strconv.ParseUint(steamID, 10, 64)
+	if err != nil || id < steamID64Base {
+		return uint32(id)
+	}
+	return uint32(id - steamID64Base)
+}
+
+func inventoryKey(steamID, appID, contextID string) string {
+	return steamID + "/" + appID + "/" + contextID
+}
+
+func isTrue(v string) bool {
+	return v == "1" || v == "true"
+}
+
+func writeEResult(w http.ResponseWriter, eresult int) {
+	w.Header().Set("X-eresult", strconv.Itoa(eresult))
+	writeJSON(w, http.StatusOK, map[string]any{"response": map[string]any{}})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func randomDigits(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	for i := range b {
+		b[i] = '1' + b[i]%9
+	}
+	return string(b)
+}
@@ -0,0 +1,92 @@
+package bot
+
+import (
+	"context"
+	"csTrade/internal"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+const (
+	CSAppID          = 730
+	CSContextID      = "2"
+	inventoryPerPage = 2000
+)
+
+func (sc *SteamBot) GetInventory(ctx context.Context, appID int, contextID string) (*internal.InventoryResponse, error) {
+	return sc.GetUserInventory(ctx, sc.SteamID, appID, contextID)
+}
+
+// GetUserInventory pages through the community inventory endpoint with
+// start_assetid and merges the pages into one response.
+func (sc *SteamBot) GetUserInventory(ctx context.Context, steamID string, appID int, contextID string) (*internal.InventoryResponse, error) {
+	ctx, cancel := sc.withTimeout(ctx, sc.timeouts.Inventory)
+	defer cancel()
+
+	inventory := &internal.InventoryResponse{Success: 1}
+	seen := make(map[string]bool)
+	startAssetID := ""
+
+	for {
+		page, err := sc.getInventoryPage(ctx, steamID, appID, contextID, startAssetID)
+		if err != nil {
+			return nil, err
+		}
+
+		inventory.Assets = append(inventory.Assets, page.Assets...)
+		inventory.TotalInventoryCount = page.TotalInventoryCount
+		for _, d := range page.Descriptions {
+			key := d.Classid + "_" + d.Instanceid
+			if !seen[key] {
+				seen[key] = true
+				inventory.Descriptions = append(inventory.Descriptions, d)
+			}
+		}
+
+		if page.MoreItems == 0 || page.LastAssetid == "" {
+			break
+		}
+		startAssetID = page.LastAssetid
+	}
+
+	return inventory, nil
+}
+
+func (sc *SteamBot) getInventoryPage(ctx context.Context, steamID string, appID int, contextID, startAssetID string) (*internal.InventoryResponse, error) {
+	params := map[string]string{
+		"l":     "english",
+		"count": strconv.Itoa(inventoryPerPage),
+	}
+	if startAssetID != "" {
+		params["start_assetid"] = startAssetID
+	}
+
+	endpoint := fmt.Sprintf("%s/inventory/%s/%d/%s", sc.endpoints.Community, steamID, appID, contextID)
+	resp, err := sc.apiCall(ctx, "GET", endpoint, params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get inventory %s: %w", steamID, err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusForbidden:
+		return nil, fmt.Errorf("inventory %s is private", steamID)
+	case http.StatusTooManyRequests:
+		return nil, fmt.Errorf("inventory %s: %w", steamID, ErrRateLimited)
+	default:
+		return nil, unexpectedStatus(resp, "failed to get inventory "+steamID)
+	}
+
+	var page internal.InventoryResponse
+	if err := json.NewDecoder(resp.Body).Decode(&page); err != nil {
+		return nil, fmt.Errorf("failed to decode inventory %s: %w", steamID, err)
+	}
+	if page.Success != 1 {
+		return nil, fmt.Errorf("failed to get inventory %s: success %d", steamID, page.Success)
+	}
+
+	return &page, nil
+}
@@ -0,0 +1,103 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const proxyCheckTimeout = 10 * time.Second
+
+// ParseProxyURL accepts http, https and socks5 proxies, the schemes
+// net/http can dial by itself.
+func ParseProxyURL(raw string) (*url.URL, error) {
+	// the parse error quotes the url, which may carry credentials
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy url")
+	}
+
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %q", u.Scheme)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("proxy url has no host")
+	}
+
+	return u, nil
+}
+
+// newTransport dials through the bot's current proxy. The proxy is read per
+// request, so SetProxy takes effect without rebuilding the client.
+func (sc *SteamBot) newTransport() *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = func(r *http.Request) (*url.URL, error) {
+		if u := sc.proxy.Load(); u != nil {
+			return u, nil
+		}
+		return http.ProxyFromEnvironment(r)
+	}
+	return transport
+}
+
+// SetProxy routes the bot through proxyURL, an empty string goes direct.
+func (sc *SteamBot) SetProxy(proxyURL string) error {
+	if proxyURL == "" {
+		sc.proxy.Store(nil)
+		return nil
+	}
+
+	u, err := ParseProxyURL(proxyURL)
+	if err != nil {
+		return err
+	}
+	sc.proxy.Store(u)
+
+	if t, ok := sc.Client.Transport.(*http.Transport); ok {
+		t.CloseIdleConnections()
+	}
+	return nil
+}
+
+func (sc *SteamBot) ProxyURL() string {
+	if u := sc.proxy.Load(); u != nil {
+		return u.String()
+	}
+	return ""
+}
+
+// CheckProxy makes a plain request to the community site through proxyURL.
+func (sc *SteamBot) CheckProxy(ctx context.Context, proxyURL string) error {
+	u, err := ParseProxyURL(proxyURL)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, proxyCheckTimeout)
+	defer cancel()
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = http.ProxyURL(u)
+	defer transport.CloseIdleConnections()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", sc.endpoints.Community+"/", nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64)")
+
+	resp, err := (&http.Client{Transport: transport}).Do(req)
+	if err != nil {
+		return fmt.Errorf("proxy %s unreachable: %w", u.Redacted(), err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= 500 || resp.StatusCode == http.StatusProxyAuthRequired {
+		return fmt.Errorf("proxy %s: unexpected status code %d", u.Redacted(), resp.StatusCode)
+	}
+	return nil
+}
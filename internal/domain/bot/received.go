@@ -0,0 +1,106 @@
+package bot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+const steamID64Base = 76561197960265728
+
+// SteamID64 turns the 32-bit account ID used in trade offers into a SteamID64.
+func SteamID64(accountID uint32) string {
+	return strconv.FormatUint(steamID64Base+uint64(accountID), 10)
+}
+
+// AcceptTradeOffer accepts an offer someone sent to the bot and returns the
+// trade ID. Offers that take items from the bot still need a mobile
+// confirmation, which is not done here.
+func (sc *SteamBot) AcceptTradeOffer(ctx context.Context, tradeOfferID, partnerSteamID string) (string, error) {
+	if err := sc.checkFrozen(); err != nil {
+		return "", err
+	}
+
+	ctx, cancel := sc.withTimeout(ctx, sc.timeouts.Trade)
+	defer cancel()
+
+	form := url.Values{
+		"sessionid":    {sc.GetSessionID()},
+		"serverid":     {"1"},
+		"tradeofferid": {tradeOfferID},
+		"partner":      {partnerSteamID},
+		"captcha":      {""},
+	}
+
+	offerURL := fmt.Sprintf("%s/tradeoffer/%s/", sc.endpoints.Community, tradeOfferID)
+	req, err := http.NewRequestWithContext(ctx, "POST", offerURL+"accept", strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Referer", offerURL)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	// a lost response may still mean the trade went through, never resend
+	resp, err := sc.send(req, nonIdempotent)
+	if err != nil {
+		return "", fmt.Errorf("failed to accept trade offer %s: %w", tradeOfferID, err)
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		TradeID  string `json:"tradeid"`
+		StrError string `json:"strError"`
+	}
+	decodeErr := json.NewDecoder(resp.Body).Decode(&result)
+
+	if err := classifySteamError(resp.StatusCode, 0, result.StrError); err != nil {
+		return "", fmt.Errorf("failed to accept trade offer %s: %w", tradeOfferID, err)
+	}
+	if resp.StatusCode != http.StatusOK || result.StrError != "" {
+		return "", fmt.Errorf("failed to accept trade offer %s: %w", tradeOfferID,
+			&SteamError{StatusCode: resp.StatusCode, EResult: parseEResult(result.StrError), Message: result.StrError, Err: ErrTradeOfferFailed})
+	}
+	if decodeErr != nil {
+		return "", fmt.Errorf("failed to decode accept response %s: %w", tradeOfferID, decodeErr)
+	}
+
+	log.Info().Str("trade_offer_id", tradeOfferID).Str("trade_id", result.TradeID).Msg("trade offer accepted")
+	return result.TradeID, nil
+}
+
+// DeclineTradeOffer declines an offer someone sent to the bot. Offers the
+// bot sent itself are withdrawn with DeclineTrade.
+func (sc *SteamBot) DeclineTradeOffer(ctx context.Context, tradeOfferID string) error {
+	ctx, cancel := sc.withTimeout(ctx, sc.timeouts.Trade)
+	defer cancel()
+
+	params := map[string]string{
+		"sessionid": sc.GetSessionID(),
+	}
+
+	resp, err := sc.apiCall(ctx, "POST",
+		fmt.Sprintf("%s/tradeoffer/%s/decline", sc.endpoints.Community, tradeOfferID), params)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var result struct {
+			Success int `json:"success"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&result)
+		if err := classifySteamError(resp.StatusCode, result.Success, ""); err != nil {
+			return fmt.Errorf("failed to decline trade %s: %w", tradeOfferID, err)
+		}
+		return fmt.Errorf("failed to decline trade %s, status: %d, eresult: %d", tradeOfferID, resp.StatusCode, result.Success)
+	}
+
+	return nil
+}
@@ -0,0 +1,149 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const eresultRateLimitExceeded = "84"
+
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 4,
+	BaseDelay:   500 * time.Millisecond,
+	MaxDelay:    10 * time.Second,
+}
+
+// sendMode tells send which failures are safe to repeat.
+type sendMode int
+
+const (
+	// idempotent requests are retried on network errors, gateway errors,
+	// 429 and EResult 84.
+	idempotent sendMode = iota
+	// nonIdempotent requests, like sending or accepting an offer or
+	// registering an API key, are only retried when Steam turned them away
+	// before doing anything (429 or EResult 84). A lost response may still
+	// mean the change happened, so it is never resent.
+	nonIdempotent
+)
+
+// send runs req through the bot and process wide rate limits and retries it
+// with exponential backoff and jitter according to mode.
+func (sc *SteamBot) send(req *http.Request, mode sendMode) (*http.Response, error) {
+	ctx := req.Context()
+	attempts := max(sc.retry.MaxAttempts, 1)
+
+	for attempt := 1; ; attempt++ {
+		if err := sc.limiter.Wait(ctx); err != nil {
+			return nil, err
+		}
+		if err := sc.globalLimiter.Wait(ctx); err != nil {
+			return nil, err
+		}
+
+		r := req
+		if attempt > 1 {
+			r = req.Clone(ctx)
+			if req.GetBody != nil {
+				body, err := req.GetBody()
+				if err != nil {
+					return nil, err
+				}
+				r.Body = body
+			}
+		}
+
+		resp, err := sc.Client.Do(r)
+		if err == nil && sc.onAuthFailure != nil && isAuthFailure(resp) {
+			sc.onAuthFailure(sc, resp)
+		}
+		if attempt >= attempts || !shouldRetry(ctx, mode, resp, err) {
+			return resp, err
+		}
+
+		wait := sc.retryDelay(attempt, resp)
+		log.Warn().Err(err).
+			Str("bot", sc.SteamID).
+			Str("url", req.URL.Path).
+			Int("attempt", attempt).
+			Dur("wait", wait).
+			Msg("retrying steam request")
+
+		if resp != nil {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func shouldRetry(ctx context.Context, mode sendMode, resp *http.Response, err error) bool {
+	if err != nil {
+		if ctx.Err() != nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return false
+		}
+		return mode == idempotent
+	}
+
+	if resp.StatusCode == http.StatusTooManyRequests || resp.Header.Get("X-eresult") == eresultRateLimitExceeded {
+		return true
+	}
+
+	switch resp.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return mode == idempotent
+	}
+	return false
+}
+
+// retryDelay honours Retry-After and otherwise backs off exponentially with
+// jitter in [d/2, d].
+func (sc *SteamBot) retryDelay(attempt int, resp *http.Response) time.Duration {
+	if resp != nil {
+		if d, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+			return d
+		}
+	}
+
+	d := sc.retry.BaseDelay << (attempt - 1)
+	if d <= 0 || d > sc.retry.MaxDelay {
+		d = sc.retry.MaxDelay
+	}
+	if d <= 0 {
+		return 0
+	}
+	return d/2 + rand.N(d/2+1)
+}
+
+func parseRetryAfter(v string) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return max(time.Until(t), 0), true
+	}
+	return 0, false
+}
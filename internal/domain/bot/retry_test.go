@@ -0,0 +1,88 @@
+package bot_test
+
+import (
+	"csTrade/internal/domain/bot"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var fastRetry = bot.WithRetryPolicy(bot.RetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   10 * time.Millisecond,
+	MaxDelay:    50 * time.Millisecond,
+})
+
+func TestRetryHonoursRetryAfterAndEResult(t *testing.T) {
+	srv := newFakeSteam(t)
+	b := newBot(t, srv, fastRetry)
+
+	tradeID, err := b.ReceiveFromUser(t.Context(), []bot.Asset{bot.CSAsset("1001")}, srv.TradeURL(userSteamID), userSteamID, "")
+	require.NoError(t, err)
+
+	const path = "/IEconService/GetTradeOffer/v1/"
+	srv.FailRequests(path, 1, http.StatusTooManyRequests, http.Header{"Retry-After": {"0"}})
+	_, err = b.GetTradeOffer(t.Context(), tradeID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, srv.Requests(path))
+
+	srv.FailRequests(path, 1, http.StatusOK, http.Header{"X-Eresult": {"84"}})
+	_, err = b.GetTradeOffer(t.Context(), tradeID)
+	require.NoError(t, err)
+	assert.Equal(t, 4, srv.Requests(path))
+
+	srv.FailRequests(path, 5, http.StatusServiceUnavailable, nil)
+	_, err = b.GetTradeOffer(t.Context(), tradeID)
+	assert.Error(t, err)
+	assert.Equal(t, 7, srv.Requests(path))
+}
+
+func TestOfferCreationIsNotResent(t *testing.T) {
+	srv := newFakeSteam(t)
+	b := newBot(t, srv, fastRetry)
+
+	const path = "/tradeoffer/new/send"
+	srv.FailRequests(path, 1, http.StatusBadGateway, nil)
+	_, err := b.SendToBuyer(t.Context(), []bot.Asset{bot.CSAsset("2002")}, srv.TradeURL(buyerSteamID), buyerSteamID, "")
+	assert.Error(t, err)
+	assert.Equal(t, 1, srv.Requests(path))
+
+	srv.FailRequests(path, 1, http.StatusTooManyRequests, nil)
+	_, err = b.SendToBuyer(t.Context(), []bot.Asset{bot.CSAsset("2002")}, srv.TradeURL(buyerSteamID), buyerSteamID, "")
+	require.NoError(t, err)
+	assert.Equal(t, 3, srv.Requests(path))
+
+	offers, err := b.GetTradeOffers(t.Context(), true, false, time.Time{})
+	require.NoError(t, err)
+	assert.Len(t, offers.Sent, 1)
+}
+
+func TestAPIKeyRegistrationIsNotResent(t *testing.T) {
+	srv := newFakeSteam(t)
+	b := newBot(t, srv, fastRetry)
+
+	const path = "/dev/registerkey"
+	srv.FailRequests(path, 1, http.StatusBadGateway, nil)
+	_, err := b.RegisterAPIKey(t.Context(), "cstrade.test")
+	assert.Error(t, err)
+	assert.Equal(t, 1, srv.Requests(path))
+
+	srv.FailRequests(path, 1, http.StatusTooManyRequests, nil)
+	key, err := b.RegisterAPIKey(t.Context(), "cstrade.test")
+	require.NoError(t, err)
+	assert.Equal(t, "cstrade.test", key.Domain)
+	assert.Equal(t, 3, srv.Requests(path))
+}
+
+func TestRateLimiterSpacesCalls(t *testing.T) {
+	l := bot.NewRateLimiter(20, 1)
+
+	start := time.Now()
+	for range 3 {
+		require.NoError(t, l.Wait(t.Context()))
+	}
+	assert.GreaterOrEqual(t, time.Since(start), 90*time.Millisecond)
+}
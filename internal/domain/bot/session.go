@@ -0,0 +1,259 @@
+package bot
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const accessTokenRenewMargin = 30 * time.Minute
+
+var ErrRefreshTokenRejected = errors.New("refresh token rejected")
+
+// tokenExpiry reads the exp claim of a Steam JWT without verifying it.
+func tokenExpiry(token string) (time.Time, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}, fmt.Errorf("malformed jwt")
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("jwt payload decode failed: %w", err)
+	}
+
+	var claims struct {
+		Exp int64 `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return time.Time{}, fmt.Errorf("jwt claims decode failed: %w", err)
+	}
+	if claims.Exp == 0 {
+		return time.Time{}, fmt.Errorf("jwt has no exp claim")
+	}
+
+	return time.Unix(claims.Exp, 0), nil
+}
+
+func (sc *SteamBot) AccessToken() string {
+	sc.credMu.RLock()
+	defer sc.credMu.RUnlock()
+	return sc.accessToken
+}
+
+func (sc *SteamBot) RefreshToken() string {
+	sc.credMu.RLock()
+	defer sc.credMu.RUnlock()
+	return sc.refreshToken
+}
+
+// setTokens stores a new token pair. An empty refresh token keeps the
+// current one, Steam does not always rotate it.
+func (sc *SteamBot) setTokens(accessToken, refreshToken string) {
+	sc.credMu.Lock()
+	defer sc.credMu.Unlock()
+
+	sc.accessToken = accessToken
+	if refreshToken != "" {
+		sc.refreshToken = refreshToken
+	}
+}
+
+func (sc *SteamBot) AccessTokenExpiry() time.Time {
+	exp, err := tokenExpiry(sc.AccessToken())
+	if err != nil {
+		return time.Time{}
+	}
+	return exp
+}
+
+func (sc *SteamBot) RefreshTokenExpiry() time.Time {
+	exp, err := tokenExpiry(sc.RefreshToken())
+	if err != nil {
+		return time.Time{}
+	}
+	return exp
+}
+
+// RenewAccessToken trades the refresh token for a new access token and
+// rebuilds the steamLoginSecure cookie from it.
+func (sc *SteamBot) RenewAccessToken(ctx context.Context) error {
+	ctx, cancel := sc.withTimeout(ctx, sc.timeouts.API)
+	defer cancel()
+
+	refreshToken := sc.RefreshToken()
+	if refreshToken == "" {
+		return ErrRefreshTokenRejected
+	}
+
+	data := map[string]string{
+		"refresh_token": refreshToken,
+		"steamid":       sc.SteamID,
+		"renewal_type":  "1",
+	}
+
+	resp, err := sc.apiCall(ctx, "POST", "/IAuthenticationService/GenerateAccessTokenForApp/v1/", data)
+	if err != nil {
+		return fmt.Errorf("renew access token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return ErrRefreshTokenRejected
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("renew access token: unexpected status code %d", resp.StatusCode)
+	}
+
+	var result struct {
+		Response struct {
+			AccessToken  string `json:"access_token"`
+			RefreshToken string `json:"refresh_token"`
+		} `json:"response"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("renew access token decode: %w", err)
+	}
+
+	if result.Response.AccessToken == "" {
+		return ErrRefreshTokenRejected
+	}
+
+	sc.setTokens(result.Response.AccessToken, result.Response.RefreshToken)
+
+	if err := sc.setSteamLoginSecure(); err != nil {
+		return fmt.Errorf("err set steamLoginSecure: %w", err)
+	}
+
+	log.Info().Str("bot", sc.SteamID).
+		Time("expires_at", sc.AccessTokenExpiry()).
+		Msg("access token renewed")
+
+	return nil
+}
+
+// EnsureSession keeps the web session alive. It renews the access token
+// shortly before it lapses and only falls back to the full password login
+// when Steam rejects the refresh token.
+func (sc *SteamBot) EnsureSession(ctx context.Context) error {
+	if sc.AccessToken() != "" && time.Until(sc.AccessTokenExpiry()) > accessTokenRenewMargin {
+		return nil
+	}
+
+	if sc.RefreshToken() != "" && time.Until(sc.RefreshTokenExpiry()) > 0 {
+		err := sc.RenewAccessToken(ctx)
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, ErrRefreshTokenRejected) {
+			return err
+		}
+		log.Warn().Str("bot", sc.SteamID).Msg("refresh token rejected, logging in with password")
+	}
+
+	return sc.Login(ctx)
+}
+
+// Reauthenticate gets a working session after Steam dropped the current one.
+// Unlike EnsureSession it does not trust the token expiry: it renews the
+// access token and checks the result, then falls back to a password login.
+func (sc *SteamBot) Reauthenticate(ctx context.Context) error {
+	if sc.RefreshToken() != "" && time.Until(sc.RefreshTokenExpiry()) > 0 {
+		err := sc.RenewAccessToken(ctx)
+		if err == nil {
+			if err = sc.ValidateSession(ctx); err == nil {
+				return nil
+			}
+		}
+		log.Warn().Err(err).Str("bot", sc.SteamID).Msg("session renewal did not help, logging in with password")
+	}
+
+	return sc.Login(ctx)
+}
+
+var ErrSessionInvalid = errors.New("steam session invalid")
+
+// isAuthFailure reports answers Steam gives to requests without a valid
+// session: 401, 403 or a redirect to the login page.
+func isAuthFailure(resp *http.Response) bool {
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return true
+	}
+	return resp.Request != nil && strings.HasPrefix(resp.Request.URL.Path, "/login")
+}
+
+type Session struct {
+	SessionID             string    `json:"session_id"`
+	SteamLoginSecure      string    `json:"steam_login_secure"`
+	AccessToken           string    `json:"access_token"`
+	RefreshToken          string    `json:"refresh_token"`
+	AccessTokenExpiresAt  time.Time `json:"access_token_expires_at"`
+	RefreshTokenExpiresAt time.Time `json:"refresh_token_expires_at"`
+}
+
+func (sc *SteamBot) ExportSession() *Session {
+	return &Session{
+		SessionID:             sc.GetSessionID(),
+		SteamLoginSecure:      sc.GetSteamLoginSecure(),
+		AccessToken:           sc.AccessToken(),
+		RefreshToken:          sc.RefreshToken(),
+		AccessTokenExpiresAt:  sc.AccessTokenExpiry(),
+		RefreshTokenExpiresAt: sc.RefreshTokenExpiry(),
+	}
+}
+
+func (sc *SteamBot) RestoreSession(s *Session) {
+	sc.setTokens(s.AccessToken, s.RefreshToken)
+
+	for _, u := range sc.cookieURLs() {
+		sc.Client.Jar.SetCookies(u, []*http.Cookie{
+			sessionCookie(u, "sessionid", s.SessionID, false),
+			sessionCookie(u, "steamLoginSecure", s.SteamLoginSecure, true),
+		})
+	}
+}
+
+// ValidateSession asks the community site whether the cookies are still
+// logged in. It is cheap enough to run on every start.
+func (sc *SteamBot) ValidateSession(ctx context.Context) error {
+	ctx, cancel := sc.withTimeout(ctx, sc.timeouts.API)
+	defer cancel()
+
+	if sc.GetSessionID() == "" || sc.GetSteamLoginSecure() == "" {
+		return ErrSessionInvalid
+	}
+
+	resp, err := sc.apiCall(ctx, "GET", sc.endpoints.Community+"/chat/clientjstoken", nil)
+	if err != nil {
+		return fmt.Errorf("validate session: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if isAuthFailure(resp) {
+		return fmt.Errorf("%w: status %d at %s", ErrSessionInvalid, resp.StatusCode, resp.Request.URL.Path)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("validate session: unexpected status code %d", resp.StatusCode)
+	}
+
+	var result struct {
+		LoggedIn bool   `json:"logged_in"`
+		SteamID  string `json:"steamid"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("validate session decode: %w", err)
+	}
+
+	if !result.LoggedIn || result.SteamID != sc.SteamID {
+		return ErrSessionInvalid
+	}
+
+	return nil
+}
@@ -0,0 +1,146 @@
+package bot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	DefaultTimeSyncInterval = time.Hour
+	timeSyncRetryWait       = time.Minute
+)
+
+// TimeSync keeps the offset between the local clock and Steam's. One
+// instance is meant to be shared by every bot, so QueryTime is called once
+// per interval instead of on every login and confirmation.
+type TimeSync struct {
+	apiURL   string
+	client   *http.Client
+	interval time.Duration
+
+	mu       sync.Mutex
+	offset   time.Duration
+	syncedAt time.Time
+	failedAt time.Time
+}
+
+func NewTimeSync(apiURL string, client *http.Client, interval time.Duration) *TimeSync {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	if interval <= 0 {
+		interval = DefaultTimeSyncInterval
+	}
+
+	return &TimeSync{
+		apiURL:   strings.TrimRight(apiURL, "/"),
+		client:   client,
+		interval: interval,
+	}
+}
+
+// Now returns the current Steam time. It syncs on first use and when the
+// offset is older than the interval. If a resync fails the last known
+// offset is kept.
+func (ts *TimeSync) Now(ctx context.Context) (time.Time, error) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	if ts.stale() {
+		if err := ts.sync(ctx); err != nil {
+			if ts.syncedAt.IsZero() {
+				return time.Time{}, err
+			}
+			log.Warn().Err(err).Dur("offset", ts.offset).Msg("steam time sync failed, keeping last offset")
+		}
+	} else if ts.syncedAt.IsZero() {
+		return time.Time{}, fmt.Errorf("steam time not synced yet")
+	}
+
+	return time.Now().Add(ts.offset), nil
+}
+
+func (ts *TimeSync) Offset() time.Duration {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	return ts.offset
+}
+
+func (ts *TimeSync) Sync(ctx context.Context) error {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	return ts.sync(ctx)
+}
+
+// Run refreshes the offset every interval until ctx is done.
+func (ts *TimeSync) Run(ctx context.Context) {
+	ticker := time.NewTicker(ts.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := ts.Sync(ctx); err != nil {
+				log.Error().Err(err).Msg("err sync steam time")
+			}
+		}
+	}
+}
+
+func (ts *TimeSync) stale() bool {
+	if !ts.failedAt.IsZero() && time.Since(ts.failedAt) < timeSyncRetryWait {
+		return false
+	}
+	return ts.syncedAt.IsZero() || time.Since(ts.syncedAt) > ts.interval
+}
+
+func (ts *TimeSync) sync(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, "POST", ts.apiURL+"/ITwoFactorService/QueryTime/v1/", nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64)")
+
+	sent := time.Now()
+	resp, err := ts.client.Do(req)
+	if err != nil {
+		ts.failedAt = time.Now()
+		return fmt.Errorf("query steam time: %w", err)
+	}
+	defer resp.Body.Close()
+	received := time.Now()
+
+	var response struct {
+		Response struct {
+			ServerTime string `json:"server_time"`
+		} `json:"response"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		ts.failedAt = time.Now()
+		return fmt.Errorf("failed to unmarshal server time: %w", err)
+	}
+
+	serverTime, err := strconv.ParseInt(response.Response.ServerTime, 10, 64)
+	if err != nil {
+		ts.failedAt = time.Now()
+		return fmt.Errorf("invalid server time format: %w", err)
+	}
+
+	local := sent.Add(received.Sub(sent) / 2)
+	ts.offset = time.Unix(serverTime, 0).Sub(local).Round(time.Second)
+	ts.syncedAt = received
+	ts.failedAt = time.Time{}
+
+	log.Debug().Dur("offset", ts.offset).Msg("steam time synced")
+	return nil
+}
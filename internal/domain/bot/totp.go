@@ -0,0 +1,61 @@
+package bot
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const authCodeChars = "23456789BCDFGHJKMNPQRTVWXY"
+
+// GenerateAuthCode returns the Steam Guard code for the shared secret at
+// time t. t must already be in Steam time.
+func GenerateAuthCode(sharedSecret string, t time.Time) (string, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(sharedSecret))
+	if err != nil {
+		return "", fmt.Errorf("shared secret base64 decode failed: %w", err)
+	}
+
+	timeBytes := make([]byte, 8)
+	binary.BigEndian.PutUint64(timeBytes, uint64(t.Unix()/30))
+
+	h := hmac.New(sha1.New, key)
+	h.Write(timeBytes)
+	hash := h.Sum(nil)
+
+	offset := hash[19] & 0x0F
+	code := binary.BigEndian.Uint32(hash[offset:offset+4]) & 0x7FFFFFFF
+
+	result := make([]byte, 5)
+	for i := range result {
+		result[i] = authCodeChars[code%uint32(len(authCodeChars))]
+		code /= uint32(len(authCodeChars))
+	}
+
+	return string(result), nil
+}
+
+// GenerateConfirmationKey signs a mobile confirmation request for tag at
+// Steam time t.
+func GenerateConfirmationKey(identitySecret string, t time.Time, tag string) (string, error) {
+	secret, err := base64.StdEncoding.DecodeString(strings.TrimSpace(identitySecret))
+	if err != nil {
+		return "", fmt.Errorf("identity secret base64 decode failed: %w", err)
+	}
+
+	buf := make([]byte, 8, 8+len(tag))
+	binary.BigEndian.PutUint64(buf, uint64(t.Unix()))
+	if len(tag) > 32 {
+		tag = tag[:32]
+	}
+	buf = append(buf, tag...)
+
+	h := hmac.New(sha1.New, secret)
+	h.Write(buf)
+
+	return base64.StdEncoding.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,35 @@
+package bot_test
+
+import (
+	"csTrade/internal/domain/bot"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateAuthCode(t *testing.T) {
+	const secret = "zvIayp3JPvtvX/QGHqsqKBk/44s="
+
+	code, err := bot.GenerateAuthCode(secret, time.Unix(1616374841, 0))
+	require.NoError(t, err)
+	assert.Equal(t, "2F9J5", code)
+
+	sameWindow, err := bot.GenerateAuthCode(secret, time.Unix(1616374830, 0))
+	require.NoError(t, err)
+	assert.Equal(t, code, sameWindow)
+
+	nextWindow, err := bot.GenerateAuthCode(secret, time.Unix(1616374860, 0))
+	require.NoError(t, err)
+	assert.NotEqual(t, code, nextWindow)
+
+	_, err = bot.GenerateAuthCode("not base64!", time.Now())
+	assert.Error(t, err)
+}
+
+func TestGenerateConfirmationKey(t *testing.T) {
+	key, err := bot.GenerateConfirmationKey("GQP46b73Ws7gr8GmZFR0sDuau5c=", time.Unix(1617591917, 0), "conf")
+	require.NoError(t, err)
+	assert.Equal(t, "NaL8EIMhfy/7vBounJ0CvpKbrPk=", key)
+}
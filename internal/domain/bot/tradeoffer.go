@@ -0,0 +1,267 @@
+package bot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// TradeOfferState mirrors Steam's ETradeOfferState.
+type TradeOfferState int
+
+const (
+	TradeOfferStateInvalid                TradeOfferState = 1
+	TradeOfferStateActive                 TradeOfferState = 2
+	TradeOfferStateAccepted               TradeOfferState = 3
+	TradeOfferStateCountered              TradeOfferState = 4
+	TradeOfferStateExpired                TradeOfferState = 5
+	TradeOfferStateCanceled               TradeOfferState = 6
+	TradeOfferStateDeclined               TradeOfferState = 7
+	TradeOfferStateInvalidItems           TradeOfferState = 8
+	TradeOfferStateNeedsConfirmation      TradeOfferState = 9
+	TradeOfferStateCanceledBySecondFactor TradeOfferState = 10
+	TradeOfferStateInEscrow               TradeOfferState = 11
+)
+
+func (s TradeOfferState) String() string {
+	switch s {
+	case TradeOfferStateInvalid:
+		return "Invalid"
+	case TradeOfferStateActive:
+		return "Active"
+	case TradeOfferStateAccepted:
+		return "Accepted"
+	case TradeOfferStateCountered:
+		return "Countered"
+	case TradeOfferStateExpired:
+		return "Expired"
+	case TradeOfferStateCanceled:
+		return "Canceled"
+	case TradeOfferStateDeclined:
+		return "Declined"
+	case TradeOfferStateInvalidItems:
+		return "InvalidItems"
+	case TradeOfferStateNeedsConfirmation:
+		return "NeedsConfirmation"
+	case TradeOfferStateCanceledBySecondFactor:
+		return "CanceledBySecondFactor"
+	case TradeOfferStateInEscrow:
+		return "InEscrow"
+	}
+	return fmt.Sprintf("Unknown(%d)", int(s))
+}
+
+// IsFinal reports whether the offer can no longer change state.
+func (s TradeOfferState) IsFinal() bool {
+	switch s {
+	case TradeOfferStateActive, TradeOfferStateNeedsConfirmation, TradeOfferStateInEscrow:
+		return false
+	}
+	return true
+}
+
+type TradeItem struct {
+	AppID      int    `json:"appid"`
+	ContextID  string `json:"contextid"`
+	AssetID    string `json:"assetid"`
+	ClassID    string `json:"classid"`
+	InstanceID string `json:"instanceid"`
+	Amount     string `json:"amount"`
+	Missing    bool   `json:"missing"`
+}
+
+type TradeOffer struct {
+	TradeOfferID       string          `json:"tradeofferid"`
+	AccountIDOther     uint32          `json:"accountid_other"`
+	Message            string          `json:"message"`
+	ExpirationTime     int64           `json:"expiration_time"`
+	State              TradeOfferState `json:"trade_offer_state"`
+	ItemsToGive        []TradeItem     `json:"items_to_give"`
+	ItemsToReceive     []TradeItem     `json:"items_to_receive"`
+	IsOurOffer         bool            `json:"is_our_offer"`
+	TimeCreated        int64           `json:"time_created"`
+	TimeUpdated        int64           `json:"time_updated"`
+	TradeID            string          `json:"tradeid"`
+	FromRealTimeTrade  bool            `json:"from_real_time_trade"`
+	EscrowEndDate      int64           `json:"escrow_end_date"`
+	ConfirmationMethod int             `json:"confirmation_method"`
+}
+
+type TradeOfferStatus struct {
+	TradeOfferID   string          `json:"trade_offer_id"`
+	State          TradeOfferState `json:"state"`
+	StateName      string          `json:"state_name"`
+	IsFinal        bool            `json:"is_final"`
+	ItemsToGive    []TradeItem     `json:"items_to_give"`
+	ItemsToReceive []TradeItem     `json:"items_to_receive"`
+	EscrowEndDate  *time.Time      `json:"escrow_end_date"`
+	UpdatedAt      time.Time       `json:"updated_at"`
+}
+
+func (o *TradeOffer) Status() *TradeOfferStatus {
+	status := &TradeOfferStatus{
+		TradeOfferID:   o.TradeOfferID,
+		State:          o.State,
+		StateName:      o.State.String(),
+		IsFinal:        o.State.IsFinal(),
+		ItemsToGive:    o.ItemsToGive,
+		ItemsToReceive: o.ItemsToReceive,
+		UpdatedAt:      time.Unix(o.TimeUpdated, 0).UTC(),
+	}
+	if o.EscrowEndDate > 0 {
+		escrowEnd := time.Unix(o.EscrowEndDate, 0).UTC()
+		status.EscrowEndDate = &escrowEnd
+	}
+	return status
+}
+
+func (sc *SteamBot) GetTradeOffer(ctx context.Context, tradeOfferID string) (*TradeOffer, error) {
+	ctx, cancel := sc.withTimeout(ctx, sc.timeouts.API)
+	defer cancel()
+
+	params := map[string]string{
+		"access_token":     sc.AccessToken(),
+		"tradeofferid":     tradeOfferID,
+		"language":         "english",
+		"get_descriptions": "false",
+	}
+
+	resp, err := sc.apiCall(ctx, "GET", "/IEconService/GetTradeOffer/v1/", params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get trade offer %s: %w", tradeOfferID, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, unexpectedStatus(resp, "failed to get trade offer "+tradeOfferID)
+	}
+
+	var result struct {
+		Response struct {
+			Offer *TradeOffer `json:"offer"`
+		} `json:"response"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode trade offer %s: %w", tradeOfferID, err)
+	}
+
+	if result.Response.Offer == nil {
+		return nil, fmt.Errorf("trade offer %s not found", tradeOfferID)
+	}
+
+	return result.Response.Offer, nil
+}
+
+type TradeOffers struct {
+	Sent     []TradeOffer `json:"trade_offers_sent"`
+	Received []TradeOffer `json:"trade_offers_received"`
+}
+
+// GetTradeOffers returns active offers plus every offer that changed state
+// after historicalCutoff.
+func (sc *SteamBot) GetTradeOffers(ctx context.Context, sent, received bool, historicalCutoff time.Time) (*TradeOffers, error) {
+	ctx, cancel := sc.withTimeout(ctx, sc.timeouts.API)
+	defer cancel()
+
+	params := map[string]string{
+		"access_token":           sc.AccessToken(),
+		"get_sent_offers":        strconv.FormatBool(sent),
+		"get_received_offers":    strconv.FormatBool(received),
+		"active_only":            "true",
+		"get_descriptions":       "false",
+		"language":               "english",
+		"time_historical_cutoff": strconv.FormatInt(historicalCutoff.Unix(), 10),
+	}
+
+	resp, err := sc.apiCall(ctx, "GET", "/IEconService/GetTradeOffers/v1/", params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get trade offers: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, unexpectedStatus(resp, "failed to get trade offers")
+	}
+
+	var result struct {
+		Response TradeOffers `json:"response"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode trade offers: %w", err)
+	}
+
+	return &result.Response, nil
+}
+
+// TradeAsset is an item moved by a completed trade. Steam gives the item a
+// new asset ID on the receiving side.
+type TradeAsset struct {
+	AppID        int    `json:"appid"`
+	ContextID    string `json:"contextid"`
+	AssetID      string `json:"assetid"`
+	Amount       string `json:"amount"`
+	ClassID      string `json:"classid"`
+	InstanceID   string `json:"instanceid"`
+	NewAssetID   string `json:"new_assetid"`
+	NewContextID string `json:"new_contextid"`
+}
+
+type TradeReceipt struct {
+	TradeID        string       `json:"tradeid"`
+	SteamIDOther   string       `json:"steamid_other"`
+	TimeInit       int64        `json:"time_init"`
+	Status         int          `json:"status"`
+	AssetsReceived []TradeAsset `json:"assets_received"`
+	AssetsGiven    []TradeAsset `json:"assets_given"`
+}
+
+// NewAssetID returns the ID a received item got in our inventory.
+func (r *TradeReceipt) NewAssetID(assetID string) (string, bool) {
+	for _, a := range r.AssetsReceived {
+		if a.AssetID == assetID && a.NewAssetID != "" {
+			return a.NewAssetID, true
+		}
+	}
+	return "", false
+}
+
+// GetTradeReceipt looks up a completed trade by the tradeid of its accepted
+// offer, not by the trade offer ID.
+func (sc *SteamBot) GetTradeReceipt(ctx context.Context, tradeID string) (*TradeReceipt, error) {
+	ctx, cancel := sc.withTimeout(ctx, sc.timeouts.API)
+	defer cancel()
+
+	params := map[string]string{
+		"access_token":     sc.AccessToken(),
+		"tradeid":          tradeID,
+		"get_descriptions": "false",
+	}
+
+	resp, err := sc.apiCall(ctx, "GET", "/IEconService/GetTradeStatus/v1/", params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get trade status %s: %w", tradeID, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, unexpectedStatus(resp, "failed to get trade status "+tradeID)
+	}
+
+	var result struct {
+		Response struct {
+			Trades []TradeReceipt `json:"trades"`
+		} `json:"response"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode trade status %s: %w", tradeID, err)
+	}
+
+	if len(result.Response.Trades) == 0 {
+		return nil, fmt.Errorf("trade %s not found", tradeID)
+	}
+
+	return &result.Response.Trades[0], nil
+}
@@ -33,3 +33,30 @@ type OfferToBuyerReq struct {
 	ClassID    string `json:"class_id"`
 	InstanceID string `json:"instance_id"`
 }
+
+type PurchaseReq struct {
+	BuyerID string `json:"buyer_id"`
+}
+
+type PurchaseManyReq struct {
+	BuyerID  string   `json:"buyer_id"`
+	OfferIDs []string `json:"offer_ids"`
+}
+
+type ListSkinReq struct {
+	SellerID string         `json:"seller_id"`
+	Items    []ListSkinItem `json:"items"`
+}
+
+type ListSkinItem struct {
+	AssetID string  `json:"asset_id"`
+	Price   float64 `json:"price"`
+}
+
+// TradeVerification is all an unauthenticated caller learns about a trade
+// offer: whether it is ours and what kind of operation it belongs to.
+type TradeVerification struct {
+	TradeOfferID string `json:"trade_offer_id"`
+	Verified     bool   `json:"verified"`
+	Operation    string `json:"operation,omitempty"`
+}
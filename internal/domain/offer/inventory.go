@@ -0,0 +1,93 @@
+package offer
+
+import (
+	"csTrade/internal"
+	"strings"
+)
+
+type InventoryItem struct {
+	AssetID    string `json:"asset_id"`
+	ClassID    string `json:"class_id"`
+	InstanceID string `json:"instance_id"`
+	Tradable   bool   `json:"tradable"`
+
+	Name                      string  `json:"name"`
+	FullName                  string  `json:"full_name"`
+	MarketTradableRestriction int     `json:"market_tradable_restriction"`
+	IconURL                   string  `json:"icon_url"`
+	NameColor                 string  `json:"name_color"`
+	ActionLink                *string `json:"action_link"`
+	TagType                   string  `json:"tag_type"`
+	TagWeaponInternal         string  `json:"tag_weapon_internal"`
+	TagWeaponName             string  `json:"tag_weapon_name"`
+	TagQuality                string  `json:"tag_quality"`
+	TagRarity                 string  `json:"tag_rarity"`
+	TagRarityColor            string  `json:"tag_rarity_color"`
+	TagExterior               string  `json:"tag_exterior"`
+}
+
+// NewInventoryItem takes the listing metadata from Steam's description of
+// the asset instead of trusting the client.
+func NewInventoryItem(ownerID string, asset internal.Asset, desc internal.Description) InventoryItem {
+	item := InventoryItem{
+		AssetID:                   asset.Assetid,
+		ClassID:                   asset.Classid,
+		InstanceID:                asset.Instanceid,
+		Tradable:                  desc.Tradable == 1,
+		Name:                      desc.Name,
+		FullName:                  desc.MarketHashName,
+		MarketTradableRestriction: desc.MarketTradableRestriction,
+		IconURL:                   desc.IconURL,
+		NameColor:                 desc.NameColor,
+	}
+
+	if len(desc.Actions) > 0 {
+		link := strings.NewReplacer(
+			"%owner_steamid%", ownerID,
+			"%assetid%", asset.Assetid,
+		).Replace(desc.Actions[0].Link)
+		item.ActionLink = &link
+	}
+
+	for _, tag := range desc.Tags {
+		switch tag.Category {
+		case "Type":
+			item.TagType = tag.LocalizedTagName
+		case "Weapon":
+			item.TagWeaponInternal = tag.InternalName
+			item.TagWeaponName = tag.LocalizedTagName
+		case "Quality":
+			item.TagQuality = tag.LocalizedTagName
+		case "Rarity":
+			item.TagRarity = tag.LocalizedTagName
+			item.TagRarityColor = tag.Color
+		case "Exterior":
+			item.TagExterior = tag.LocalizedTagName
+		}
+	}
+
+	return item
+}
+
+func (i InventoryItem) ToCreateReq(sellerID string, price float64) *OfferCreateReq {
+	return &OfferCreateReq{
+		SellerID:                  sellerID,
+		Price:                     price,
+		AssetID:                   i.AssetID,
+		ClassID:                   i.ClassID,
+		InstanceID:                i.InstanceID,
+		Name:                      i.Name,
+		FullName:                  i.FullName,
+		MarketTradableRestriction: i.MarketTradableRestriction,
+		IconURL:                   i.IconURL,
+		NameColor:                 i.NameColor,
+		ActionLink:                i.ActionLink,
+		TagType:                   i.TagType,
+		TagWeaponInternal:         i.TagWeaponInternal,
+		TagWeaponName:             i.TagWeaponName,
+		TagQuality:                i.TagQuality,
+		TagRarity:                 i.TagRarity,
+		TagRarityColor:            i.TagRarityColor,
+		TagExterior:               i.TagExterior,
+	}
+}
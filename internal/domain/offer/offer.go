@@ -12,15 +12,18 @@ type OfferDB struct {
 	SellerID     string    `db:"seller_id"`
 	BotSteamID   string    `db:"bot_steam_id"`
 	SteamTradeId *string   `db:"steam_trade_id"`
+	SecurityCode *string   `db:"security_code" json:"-"`
 	Price        float64   `db:"price"`
 
 	Status        OfferStatus `db:"status"`
 	ReservedUntil *time.Time  `db:"reserved_until"`
+	EscrowEndDate *time.Time  `db:"escrow_end_date"`
 
 	CreatedAt time.Time `db:"created_at"`
 	UpdatedAt time.Time `db:"updated_at"`
 
 	AssetID                   string  `db:"asset_id"`
+	BotAssetID                *string `db:"bot_asset_id"`
 	ClassID                   string  `db:"class_id"`
 	InstanceID                string  `db:"instance_id"`
 	Name                      string  `db:"name"`
@@ -41,6 +44,7 @@ type OfferDB struct {
 type OfferStatus string
 
 const (
+	OfferPending  OfferStatus = "pending"
 	OfferOnSale   OfferStatus = "onsale"
 	OfferReserved OfferStatus = "reserved"
 	OfferSold     OfferStatus = "sold"
@@ -48,6 +52,7 @@ const (
 )
 
 var AllOfferStatuses = []OfferStatus{
+	OfferPending,
 	OfferOnSale,
 	OfferReserved,
 	OfferSold,
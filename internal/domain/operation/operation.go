@@ -0,0 +1,48 @@
+package operation
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// OperationDB tracks a trade the API accepted and handed to the dispatcher.
+type OperationDB struct {
+	ID           uuid.UUID       `db:"id"`
+	Kind         OperationKind   `db:"kind"`
+	Status       OperationStatus `db:"status"`
+	BotSteamID   string          `db:"bot_steam_id"`
+	UserSteamID  string          `db:"user_steam_id"`
+	SecurityCode string          `db:"security_code"`
+	TradeOfferID *string         `db:"trade_offer_id"`
+	Error        *string         `db:"error"`
+	// OfferIDs are the listings the trade covers.
+	OfferIDs  []string  `db:"offer_ids"`
+	CreatedAt time.Time `db:"created_at"`
+	UpdatedAt time.Time `db:"updated_at"`
+}
+
+type OperationKind string
+
+const (
+	OperationDeposit  OperationKind = "deposit"
+	OperationPurchase OperationKind = "purchase"
+)
+
+type OperationStatus string
+
+const (
+	OperationPending   OperationStatus = "pending"
+	OperationSucceeded OperationStatus = "succeeded"
+	OperationFailed    OperationStatus = "failed"
+)
+
+func (s OperationStatus) String() string {
+	return string(s)
+}
+
+// OperationResp is returned with 202 when a trade was queued.
+type OperationResp struct {
+	OperationID  string `json:"operation_id"`
+	SecurityCode string `json:"security_code"`
+}
@@ -16,6 +16,9 @@ type TransactionDB struct {
 	Price     float64           `db:"price"`
 	CreatedAt time.Time         `db:"created_at"`
 
+	SteamTradeID *string `db:"steam_trade_id"`
+	SecurityCode *string `db:"security_code"`
+
 	// Name                      string  `db:"name"`
 	// FullName                  string  `db:"full_name"`
 	// MarketTradableRestriction int     `db:"market_tradable_restriction"`
@@ -35,12 +38,15 @@ type TransactionDB struct {
 type TransactionStatus string
 
 const (
+	TransactionPending   TransactionStatus = "pending"
 	TransactionCompleted TransactionStatus = "completed"
 	TransactionFailed    TransactionStatus = "failed"
 )
 
 func (s TransactionStatus) GetString() string {
 	switch s {
+	case TransactionPending:
+		return string(TransactionPending)
 	case TransactionFailed:
 		return string(TransactionFailed)
 	case TransactionCompleted:
@@ -51,7 +57,7 @@ func (s TransactionStatus) GetString() string {
 
 func (s TransactionStatus) IsValid() bool {
 	switch s {
-	case TransactionCompleted, TransactionFailed:
+	case TransactionPending, TransactionCompleted, TransactionFailed:
 		return true
 	}
 	return false
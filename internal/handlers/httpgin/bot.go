@@ -0,0 +1,102 @@
+package httpgin
+
+import (
+	"csTrade/internal/service"
+	"csTrade/internal/service/bots"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+type BotHandler struct {
+	service *service.BotService
+}
+
+func NewBotHandler(service *service.BotService) *BotHandler {
+	return &BotHandler{service: service}
+}
+
+func (bh *BotHandler) GetStatuses(c *gin.Context) {
+	statuses, err := bh.service.GetStatuses(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, statuses)
+}
+
+// Readiness answers 503 until startup logins are over and a bot is online.
+func (bh *BotHandler) Readiness(c *gin.Context) {
+	readiness := bh.service.Readiness()
+	if !readiness.Ready {
+		c.JSON(http.StatusServiceUnavailable, readiness)
+		return
+	}
+	c.JSON(http.StatusOK, readiness)
+}
+
+// AddBot stores a bot and starts logging it in, poll the status list for
+// the result.
+func (bh *BotHandler) AddBot(c *gin.Context) {
+	var req service.AddBotReq
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := bh.service.AddBot(c.Request.Context(), &req); err != nil {
+		botError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "bot added, logging in"})
+}
+
+func (bh *BotHandler) EnableBot(c *gin.Context) {
+	bh.setEnabled(c, true)
+}
+
+func (bh *BotHandler) DisableBot(c *gin.Context) {
+	bh.setEnabled(c, false)
+}
+
+func (bh *BotHandler) setEnabled(c *gin.Context, enabled bool) {
+	if err := bh.service.SetEnabled(c.Request.Context(), c.Param("id"), enabled); err != nil {
+		botError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "ok"})
+}
+
+func (bh *BotHandler) RemoveBot(c *gin.Context) {
+	if err := bh.service.RemoveBot(c.Request.Context(), c.Param("id")); err != nil {
+		botError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "bot removed"})
+}
+
+func (bh *BotHandler) Relogin(c *gin.Context) {
+	if err := bh.service.Relogin(c.Request.Context(), c.Param("id")); err != nil {
+		botError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "re-login started"})
+}
+
+func botError(c *gin.Context, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, pgx.ErrNoRows), errors.Is(err, bots.ErrBotNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, bots.ErrBotExists), errors.Is(err, service.ErrBotHasOffers):
+		status = http.StatusConflict
+	}
+	c.JSON(status, gin.H{"error": err.Error()})
+}
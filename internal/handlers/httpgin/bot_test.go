@@ -0,0 +1,203 @@
+package httpgin_test
+
+import (
+	"bytes"
+	"context"
+	"csTrade/internal/domain/bot"
+	"csTrade/internal/domain/bot/fakesteam"
+	"csTrade/internal/handlers/httpgin"
+	"csTrade/internal/repository"
+	"csTrade/internal/repository/memrepo"
+	"csTrade/internal/service"
+	"csTrade/internal/service/bots"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	adminToken   = "s3cret"
+	botSteamID   = "76561198000000100"
+	sharedSecret = "cnOgv/KdpLoP6Nbh0GMkXkPXALQ="
+)
+
+// heldOffers answers CountByBot from a map, the admin API needs nothing else
+// of the offer store.
+type heldOffers struct {
+	repository.OfferStore
+	held map[string]int
+}
+
+func (o heldOffers) CountByBot(ctx context.Context, statuses ...string) (map[string]int, error) {
+	return o.held, nil
+}
+
+type testServer struct {
+	srv    *fakesteam.Server
+	store  *memrepo.Bots
+	m      *bots.BotManager
+	held   map[string]int
+	router *gin.Engine
+}
+
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	ts := &testServer{srv: fakesteam.New(), store: memrepo.NewBots(), held: make(map[string]int)}
+	t.Cleanup(ts.srv.Close)
+
+	ts.m = bots.NewBotManager(ts.store, nil,
+		bot.WithEndpoints(ts.srv.Endpoints()),
+		bot.WithRetryPolicy(bot.RetryPolicy{MaxAttempts: 1}),
+	)
+	repo := &repository.Repository{Offer: heldOffers{held: ts.held}, Bot: ts.store}
+	ts.router = httpgin.Init(repo, ts.m, nil, service.EscrowReject, adminToken)
+	return ts
+}
+
+func (ts *testServer) do(method, path, token string, body any) *httptest.ResponseRecorder {
+	var buf bytes.Buffer
+	if body != nil {
+		json.NewEncoder(&buf).Encode(body)
+	}
+	req := httptest.NewRequest(method, path, &buf)
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	ts.router.ServeHTTP(w, req)
+	return w
+}
+
+func (ts *testServer) admin(method, path string, body any) *httptest.ResponseRecorder {
+	return ts.do(method, "/api/v1/admin/bots"+path, adminToken, body)
+}
+
+func (ts *testServer) addAccount() {
+	ts.srv.AddAccount(fakesteam.Account{
+		SteamID:        botSteamID,
+		Username:       "bot0",
+		Password:       "hunter2",
+		SharedSecret:   sharedSecret,
+		IdentitySecret: sharedSecret,
+	})
+}
+
+func eventually(t *testing.T, cond func() bool, msg string) {
+	t.Helper()
+	require.Eventually(t, cond, time.Second, 5*time.Millisecond, msg)
+}
+
+func TestAdminBotsNeedToken(t *testing.T) {
+	ts := newTestServer(t)
+	ts.m.InitBots(t.Context(), 1)
+
+	assert.Equal(t, http.StatusUnauthorized, ts.do(http.MethodGet, "/api/v1/admin/bots", "", nil).Code)
+	assert.Equal(t, http.StatusUnauthorized, ts.do(http.MethodGet, "/api/v1/admin/bots", "wrong", nil).Code)
+	assert.Equal(t, http.StatusUnauthorized, ts.do(http.MethodDelete, "/api/v1/admin/bots/"+botSteamID, "", nil).Code)
+	assert.Equal(t, http.StatusOK, ts.admin(http.MethodGet, "", nil).Code)
+}
+
+func TestAdminBotLifecycle(t *testing.T) {
+	ts := newTestServer(t)
+	ts.addAccount()
+	ts.m.InitBots(t.Context(), 1)
+
+	w := ts.admin(http.MethodPost, "", service.AddBotReq{
+		SteamID:        botSteamID,
+		Username:       "bot0",
+		Password:       "hunter2",
+		SharedSecret:   sharedSecret,
+		IdentitySecret: sharedSecret,
+	})
+	require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+	eventually(t, func() bool { return ts.m.IsOnline(botSteamID) }, "the added bot logs in")
+
+	statuses := func() []bots.BotStatus {
+		w := ts.admin(http.MethodGet, "", nil)
+		require.Equal(t, http.StatusOK, w.Code)
+		var out []bots.BotStatus
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &out))
+		return out
+	}
+	ts.held[botSteamID] = 2
+	got := statuses()
+	require.Len(t, got, 1)
+	assert.Equal(t, bots.StatusOnline, got[0].Status)
+	assert.Equal(t, 2, got[0].PendingOffers)
+
+	assert.Equal(t, http.StatusOK, ts.admin(http.MethodPost, "/"+botSteamID+"/disable", nil).Code)
+	assert.False(t, statuses()[0].Enabled)
+	assert.Equal(t, http.StatusOK, ts.admin(http.MethodPost, "/"+botSteamID+"/enable", nil).Code)
+	assert.True(t, statuses()[0].Enabled)
+
+	assert.Equal(t, http.StatusAccepted, ts.admin(http.MethodPost, "/"+botSteamID+"/relogin", nil).Code)
+	eventually(t, func() bool { return ts.m.IsOnline(botSteamID) }, "the bot is back online")
+
+	assert.Equal(t, http.StatusConflict, ts.admin(http.MethodDelete, "/"+botSteamID, nil).Code, "the bot still holds offers")
+	assert.NotNil(t, ts.m.GetBotByID(botSteamID))
+
+	delete(ts.held, botSteamID)
+	assert.Equal(t, http.StatusOK, ts.admin(http.MethodDelete, "/"+botSteamID, nil).Code)
+	assert.Nil(t, ts.m.GetBotByID(botSteamID))
+	assert.Empty(t, statuses())
+	_, err := ts.store.GetBot(t.Context(), botSteamID)
+	assert.Error(t, err)
+}
+
+func TestAdminUnknownBot(t *testing.T) {
+	ts := newTestServer(t)
+	ts.m.InitBots(t.Context(), 1)
+
+	assert.Equal(t, http.StatusNotFound, ts.admin(http.MethodPost, "/"+botSteamID+"/enable", nil).Code)
+	assert.Equal(t, http.StatusNotFound, ts.admin(http.MethodPost, "/"+botSteamID+"/relogin", nil).Code)
+	assert.Equal(t, http.StatusBadRequest, ts.admin(http.MethodPost, "", map[string]string{"steam_id": botSteamID}).Code)
+}
+
+func TestReadyz(t *testing.T) {
+	ts := newTestServer(t)
+	ts.addAccount()
+	require.NoError(t, ts.store.CreateBots(t.Context(), &repository.Bot{
+		SteamID:        botSteamID,
+		Username:       "bot0",
+		Password:       "hunter2",
+		SharedSecret:   sharedSecret,
+		IdentitySecret: sharedSecret,
+		DeviceID:       "android:0a1b2c3d",
+		Enabled:        true,
+	}))
+
+	readyz := func() (int, bots.Readiness) {
+		w := ts.do(http.MethodGet, "/readyz", "", nil)
+		var r bots.Readiness
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &r))
+		return w.Code, r
+	}
+
+	code, r := readyz()
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.True(t, r.Starting)
+
+	ts.srv.FailRequests("/IAuthenticationService/BeginAuthSessionViaCredentials/v1/", 1, http.StatusBadGateway, nil)
+	ts.m.InitBots(t.Context(), 1)
+	code, r = readyz()
+	assert.Equal(t, http.StatusServiceUnavailable, code, "no bot is online")
+	assert.False(t, r.Starting)
+	assert.Equal(t, 1, r.Offline)
+
+	require.Equal(t, http.StatusAccepted, ts.admin(http.MethodPost, "/"+botSteamID+"/relogin", nil).Code)
+	eventually(t, func() bool { return ts.m.IsOnline(botSteamID) }, "the bot logs in")
+	code, r = readyz()
+	assert.Equal(t, http.StatusOK, code)
+	assert.True(t, r.Ready)
+	assert.Equal(t, 1, r.Online)
+	ts.m.RemoveBot(botSteamID)
+}
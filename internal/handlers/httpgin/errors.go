@@ -0,0 +1,43 @@
+package httpgin
+
+import (
+	"context"
+	"csTrade/internal/domain/bot"
+	"csTrade/internal/service/bots"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+const steamRetryAfter = "30"
+
+// respondError answers with the status that matches a typed Steam error and
+// falls back to the given status for everything else.
+func respondError(c *gin.Context, fallback int, err error) {
+	status := fallback
+	switch {
+	case errors.Is(err, bot.ErrInvalidTradeToken):
+		status = http.StatusUnprocessableEntity
+	case errors.Is(err, bot.ErrPartnerTradeBanned):
+		status = http.StatusForbidden
+	case errors.Is(err, bot.ErrItemNoLongerAvailable), errors.Is(err, bot.ErrEscrowRequired):
+		status = http.StatusConflict
+	case errors.Is(err, bot.ErrTooManyOffers), errors.Is(err, bot.ErrRateLimited):
+		status = http.StatusTooManyRequests
+	case errors.Is(err, bot.ErrSessionExpired), errors.Is(err, bot.ErrSteamUnavailable), errors.Is(err, bot.ErrBotFrozen),
+		errors.Is(err, bots.ErrDispatcherBusy):
+		status = http.StatusServiceUnavailable
+	case errors.Is(err, bot.ErrTradeOfferFailed):
+		status = http.StatusBadGateway
+	case errors.Is(err, context.DeadlineExceeded):
+		status = http.StatusGatewayTimeout
+	}
+
+	retryable := bot.IsRetryable(err) || errors.Is(err, bot.ErrSessionExpired) || errors.Is(err, bots.ErrDispatcherBusy)
+	if retryable {
+		c.Header("Retry-After", steamRetryAfter)
+	}
+
+	c.JSON(status, gin.H{"error": err.Error(), "retryable": retryable})
+}
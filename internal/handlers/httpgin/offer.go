@@ -22,36 +22,53 @@ func NewOfferHandler(service *service.OfferService) *OfferHandler {
 
 func (ofh *OfferHandler) ListSkin(c *gin.Context) {
 
-	var req offer.OfferCreateReq
+	var req offer.ListSkinReq
 	if err := c.BindJSON(&req); err != nil {
 		c.JSON(400, gin.H{"error": err.Error()})
 		return
 	}
 
-	err := ofh.service.ReceiveFromUserOffer(c.Request.Context(), &req)
+	res, err := ofh.service.ReceiveFromUserOffer(c.Request.Context(), &req)
 	if err != nil {
-		c.JSON(400, gin.H{"error": err.Error()})
+		respondError(c, http.StatusBadRequest, err)
 		return
 	}
 
-	c.JSON(200, gin.H{"message": "ok"})
+	c.JSON(http.StatusAccepted, res)
 }
 
 func (ofh *OfferHandler) Purchase(c *gin.Context) {
+	offerID := c.Param("id")
 
-	var req offer.OfferCreateReq
+	var req offer.PurchaseReq
 	if err := c.BindJSON(&req); err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 		return
 	}
 
-	err := ofh.service.SendToBuyerOffer(c.Request.Context(), &req)
+	res, err := ofh.service.SendToBuyerOffer(c.Request.Context(), []string{offerID}, req.BuyerID)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		respondError(c, http.StatusNotFound, err)
 		return
 	}
 
-	c.JSON(200, gin.H{"message": "ok"})
+	c.JSON(http.StatusAccepted, res)
+}
+
+func (ofh *OfferHandler) PurchaseMany(c *gin.Context) {
+	var req offer.PurchaseManyReq
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	res, err := ofh.service.SendToBuyerOffer(c.Request.Context(), req.OfferIDs, req.BuyerID)
+	if err != nil {
+		respondError(c, http.StatusNotFound, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, res)
 }
 
 func (ofh *OfferHandler) GetOfferByID(c *gin.Context) {
@@ -80,11 +97,39 @@ func (ofh *OfferHandler) GetTradeStatus(c *gin.Context) {
 	log.Info().Msg("start")
 	status, err := ofh.service.GetTradeStatus(c.Request.Context(), steamOfferID)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		respondError(c, http.StatusNotFound, err)
 		return
 	}
 
-	c.JSON(200, gin.H{"message": status})
+	c.JSON(200, status)
+}
+
+func (ofh *OfferHandler) VerifyTradeOffer(c *gin.Context) {
+	steamOfferID := c.Query("steam_id")
+	if steamOfferID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "steam_id is required"})
+		return
+	}
+
+	res, err := ofh.service.VerifyTradeOffer(c.Request.Context(), steamOfferID)
+	if err != nil {
+		respondError(c, http.StatusNotFound, err)
+		return
+	}
+
+	c.JSON(200, res)
+}
+
+// GetOperation reports a queued trade: pending until the dispatcher ran it,
+// then succeeded with the trade offer ID or failed with the error.
+func (ofh *OfferHandler) GetOperation(c *gin.Context) {
+	op, err := ofh.service.GetOperation(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "operation not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, op)
 }
 
 func (ofh *OfferHandler) CancelTrade(c *gin.Context) {
@@ -93,7 +138,7 @@ func (ofh *OfferHandler) CancelTrade(c *gin.Context) {
 	log.Info().Msg("start")
 	err := ofh.service.CancelTrade(c.Request.Context(), steamOfferID)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		respondError(c, http.StatusNotFound, err)
 		return
 	}
 
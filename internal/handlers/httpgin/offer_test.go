@@ -0,0 +1,66 @@
+package httpgin_test
+
+import (
+	"context"
+	"csTrade/internal/domain/offer"
+	"csTrade/internal/handlers/httpgin"
+	"csTrade/internal/repository"
+	"csTrade/internal/repository/memrepo"
+	"csTrade/internal/service"
+	"csTrade/internal/service/bots"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// listedOffers serves one stored offer to the public listing routes.
+type listedOffers struct {
+	repository.OfferStore
+	offer offer.OfferDB
+}
+
+func (o listedOffers) GetAll(ctx context.Context) ([]offer.OfferDB, error) {
+	return []offer.OfferDB{o.offer}, nil
+}
+
+func (o listedOffers) GetByID(ctx context.Context, offerID string) (*offer.OfferDB, error) {
+	return &o.offer, nil
+}
+
+func (o listedOffers) GetOfferBySellerID(ctx context.Context, sellerID string) ([]offer.OfferDB, error) {
+	return []offer.OfferDB{o.offer}, nil
+}
+
+func TestListingsHideSecurityCode(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	code, tradeOfferID := "ABC234", "7001"
+	listed := offer.OfferDB{
+		ID:           uuid.New(),
+		SellerID:     "76561198000000200",
+		BotSteamID:   botSteamID,
+		SteamTradeId: &tradeOfferID,
+		SecurityCode: &code,
+		Status:       offer.OfferPending,
+	}
+	store := memrepo.NewBots()
+	repo := &repository.Repository{Offer: listedOffers{offer: listed}, Bot: store}
+	router := httpgin.Init(repo, bots.NewBotManager(store, nil), nil, service.EscrowReject, adminToken)
+
+	for _, path := range []string{
+		"/api/v1/market/listings",
+		"/api/v1/market/listings/" + listed.ID.String(),
+		"/api/v1/market/listings/user/" + listed.SellerID,
+	} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		require.Equal(t, http.StatusOK, w.Code, path)
+		assert.Contains(t, w.Body.String(), listed.ID.String(), path)
+		assert.NotContains(t, w.Body.String(), code, path)
+	}
+}
@@ -14,7 +14,7 @@ import (
 	ginSwagger "github.com/swaggo/gin-swagger"
 )
 
-func Init(repo *repository.Repository, botmanager *bots.BotManager) *gin.Engine {
+func Init(repo *repository.Repository, botmanager *bots.BotManager, dispatcher *bots.Dispatcher, escrowPolicy service.EscrowPolicy, adminToken string) *gin.Engine {
 	r := gin.Default()
 	r.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"https://*", "http://*"},
@@ -25,20 +25,24 @@ func Init(repo *repository.Repository, botmanager *bots.BotManager) *gin.Engine
 		MaxAge:           300,
 	}))
 
-	offerServ := service.NewOfferService(repo, botmanager)
+	offerServ := service.NewOfferService(repo, botmanager, dispatcher, escrowPolicy)
 	offerHandler := NewOfferHandler(offerServ)
 
-	userServ := service.NewUserService(repo)
+	userServ := service.NewUserService(repo, botmanager)
 	userHandler := NewUserHandler(userServ)
 
 	transactionServ := service.NewTransactionService(repo)
 	transactionHandler := NewTransactionHandler(transactionServ)
 
+	botServ := service.NewBotService(repo, botmanager)
+	botHandler := NewBotHandler(botServ)
+
 	{
 		r.GET("/swagger", ginSwagger.WrapHandler(swaggerfiles.Handler))
 		r.GET("/healthz", func(c *gin.Context) {
 			c.String(200, "ok")
 		})
+		r.GET("/readyz", botHandler.Readiness)
 		r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerfiles.Handler))
 	}
 
@@ -50,6 +54,7 @@ func Init(repo *repository.Repository, botmanager *bots.BotManager) *gin.Engine
 	{
 		users.GET("/:id")
 		users.GET("/:id/cash")
+		users.GET("/:id/inventory", userHandler.GetInventory)
 		users.PATCH("/:id/cash")
 	}
 
@@ -58,14 +63,18 @@ func Init(repo *repository.Repository, botmanager *bots.BotManager) *gin.Engine
 		listings.GET("", offerHandler.GetAllOffers)
 		listings.POST("", offerHandler.ListSkin)              // sell
 		listings.POST("/:id/purchase", offerHandler.Purchase) // buy
+		listings.POST("/purchase", offerHandler.PurchaseMany)
 		listings.GET("/:id", offerHandler.GetOfferByID)
 		listings.GET("/user/:id", offerHandler.UserOffers)
 		listings.POST("/cancel", offerHandler.CancelTrade)
 		listings.PATCH("/:id/price", offerHandler.ChangePrice)
-		// listings.GET("status", offerHandler.GetTradeStatus)
+		listings.GET("/status", offerHandler.GetTradeStatus)
+		listings.GET("/verify", offerHandler.VerifyTradeOffer)
 		listings.DELETE("/:id", offerHandler.DeleteByID)
 	}
 
+	api.GET("/operations/:id", offerHandler.GetOperation)
+
 	transaction := api.Group("/transaction").Use(middleware.AuthMiddleware())
 	{
 		transaction.GET("/:id")
@@ -73,5 +82,15 @@ func Init(repo *repository.Repository, botmanager *bots.BotManager) *gin.Engine
 		transaction.GET("/user/:id", transactionHandler.GetByuerTransaction)
 	}
 
+	admin := api.Group("/admin/bots").Use(middleware.AdminMiddleware(adminToken))
+	{
+		admin.GET("", botHandler.GetStatuses)
+		admin.POST("", botHandler.AddBot)
+		admin.POST("/:id/enable", botHandler.EnableBot)
+		admin.POST("/:id/disable", botHandler.DisableBot)
+		admin.POST("/:id/relogin", botHandler.Relogin)
+		admin.DELETE("/:id", botHandler.RemoveBot)
+	}
+
 	return r
 }
@@ -3,6 +3,7 @@ package httpgin
 import (
 	"csTrade/internal/domain/user"
 	"csTrade/internal/service"
+	"net/http"
 
 	"github.com/gin-gonic/gin"
 )
@@ -30,3 +31,15 @@ func (uh *UserHandler) CreateUser(c *gin.Context) {
 
 	c.JSON(200, gin.H{"message": "ok"})
 }
+
+func (uh *UserHandler) GetInventory(c *gin.Context) {
+	id := c.Param("id")
+
+	items, err := uh.service.GetInventory(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, http.StatusNotFound, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, items)
+}
@@ -0,0 +1,21 @@
+package middleware
+
+import (
+	"crypto/subtle"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminMiddleware lets through requests bearing the admin token. Without a
+// configured token the admin API stays closed.
+func AdminMiddleware(token string) gin.HandlerFunc {
+	want := []byte("Bearer " + token)
+	return func(c *gin.Context) {
+		got := []byte(c.Request.Header.Get("Authorization"))
+		if token == "" || subtle.ConstantTimeCompare(got, want) != 1 {
+			c.AbortWithStatus(401)
+			return
+		}
+		c.Next()
+	}
+}
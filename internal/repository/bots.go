@@ -3,23 +3,50 @@ package repository
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 )
 
 type Bot struct {
-	Username       string `db:"username"`
-	Password       string `db:"password"`
-	SteamID        string `db:"steam_id"`
-	SharedSecret   string `db:"shared_secret"`
-	SkinCount      int    `db:"skin_count"`
-	IdentitySecret string `db:"identity_secret"`
-	DeviceID       string `db:"device_id"`
+	Username       string  `db:"username"`
+	Password       string  `db:"password"`
+	SteamID        string  `db:"steam_id"`
+	SharedSecret   string  `db:"shared_secret"`
+	SkinCount      int     `db:"skin_count"`
+	IdentitySecret string  `db:"identity_secret"`
+	DeviceID       string  `db:"device_id"`
+	ProxyURL       *string `db:"proxy_url"`
+
+	APIKey       []byte     `db:"api_key"`
+	APIKeyDomain *string    `db:"api_key_domain"`
+	FrozenAt     *time.Time `db:"frozen_at"`
+	FrozenReason *string    `db:"frozen_reason"`
+
+	Enabled bool `db:"enabled"`
+}
+
+type BotSession struct {
+	SteamID               string     `db:"steam_id"`
+	Session               []byte     `db:"session"`
+	AccessTokenExpiresAt  *time.Time `db:"access_token_expires_at"`
+	RefreshTokenExpiresAt *time.Time `db:"refresh_token_expires_at"`
+	UpdatedAt             time.Time  `db:"updated_at"`
 }
 
 type BotsStore interface {
 	GetBots(ctx context.Context) ([]Bot, error)
+	GetBot(ctx context.Context, steamID string) (*Bot, error)
 	CreateBots(ctx context.Context, arg *Bot) error
+	SetEnabled(ctx context.Context, steamID string, enabled bool) error
+	DeleteBot(ctx context.Context, steamID string) error
+	UpdateSkinCount(ctx context.Context, steamID string, count int) error
+	UpdateProxy(ctx context.Context, steamID string, proxyURL *string) error
+	UpdateAPIKey(ctx context.Context, steamID string, sealedKey []byte, domain string) error
+	Freeze(ctx context.Context, steamID, reason string) error
+
+	GetSession(ctx context.Context, steamID string) (*BotSession, error)
+	SaveSession(ctx context.Context, arg *BotSession) error
 }
 
 type BotsRepository struct {
@@ -35,10 +62,10 @@ func NewBotsRepo(db Querier) *BotsRepository {
 func (o *BotsRepository) CreateBots(ctx context.Context, arg *Bot) error {
 	query := `
 		INSERT INTO bots (
-			steam_id, username, password, shared_secret, skin_count, identity_secret, device_id
+			steam_id, username, password, shared_secret, skin_count, identity_secret, device_id, proxy_url
 		)
 		VALUES (
-			@steam_id, @username, @password, @shared_secret, @skin_count, @identity_secret, @device_id
+			@steam_id, @username, @password, @shared_secret, @skin_count, @identity_secret, @device_id, @proxy_url
 		);
 	`
 	_, err := o.db.Exec(ctx, query, pgx.NamedArgs{
@@ -49,6 +76,7 @@ func (o *BotsRepository) CreateBots(ctx context.Context, arg *Bot) error {
 		"skin_count":      arg.SkinCount,
 		"identity_secret": arg.IdentitySecret,
 		"device_id":       arg.DeviceID,
+		"proxy_url":       arg.ProxyURL,
 	})
 	if err != nil {
 		return fmt.Errorf("failed to exec bot : %w", err)
@@ -67,3 +95,129 @@ func (o *BotsRepository) GetBots(ctx context.Context) ([]Bot, error) {
 
 	return pgx.CollectRows(rows, pgx.RowToStructByName[Bot])
 }
+
+func (o *BotsRepository) GetBot(ctx context.Context, steamID string) (*Bot, error) {
+	query := `SELECT * FROM bots WHERE steam_id = $1`
+
+	rows, err := o.db.Query(ctx, query, steamID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch bot : %w", err)
+	}
+
+	b, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[Bot])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect bot : %w", err)
+	}
+
+	return &b, nil
+}
+
+func (o *BotsRepository) SetEnabled(ctx context.Context, steamID string, enabled bool) error {
+	query := `UPDATE bots SET enabled = $1 WHERE steam_id = $2`
+	tag, err := o.db.Exec(ctx, query, enabled, steamID)
+	if err != nil {
+		return fmt.Errorf("failed to update bot enabled : %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("failed to update bot enabled : %w", pgx.ErrNoRows)
+	}
+
+	return nil
+}
+
+// DeleteBot removes the bot, its stored session goes with it.
+func (o *BotsRepository) DeleteBot(ctx context.Context, steamID string) error {
+	query := `DELETE FROM bots WHERE steam_id = $1`
+	tag, err := o.db.Exec(ctx, query, steamID)
+	if err != nil {
+		return fmt.Errorf("failed to delete bot : %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("failed to delete bot : %w", pgx.ErrNoRows)
+	}
+
+	return nil
+}
+
+func (o *BotsRepository) UpdateSkinCount(ctx context.Context, steamID string, count int) error {
+	query := `UPDATE bots SET skin_count = $1 WHERE steam_id = $2`
+	_, err := o.db.Exec(ctx, query, count, steamID)
+	if err != nil {
+		return fmt.Errorf("failed to update bot skin_count : %w", err)
+	}
+
+	return nil
+}
+
+func (o *BotsRepository) UpdateProxy(ctx context.Context, steamID string, proxyURL *string) error {
+	query := `UPDATE bots SET proxy_url = $1 WHERE steam_id = $2`
+	_, err := o.db.Exec(ctx, query, proxyURL, steamID)
+	if err != nil {
+		return fmt.Errorf("failed to update bot proxy_url : %w", err)
+	}
+
+	return nil
+}
+
+func (o *BotsRepository) UpdateAPIKey(ctx context.Context, steamID string, sealedKey []byte, domain string) error {
+	query := `UPDATE bots SET api_key = $1, api_key_domain = $2 WHERE steam_id = $3`
+	_, err := o.db.Exec(ctx, query, sealedKey, domain, steamID)
+	if err != nil {
+		return fmt.Errorf("failed to update bot api_key : %w", err)
+	}
+
+	return nil
+}
+
+func (o *BotsRepository) Freeze(ctx context.Context, steamID, reason string) error {
+	query := `UPDATE bots SET frozen_at = now(), frozen_reason = $1 WHERE steam_id = $2`
+	_, err := o.db.Exec(ctx, query, reason, steamID)
+	if err != nil {
+		return fmt.Errorf("failed to freeze bot : %w", err)
+	}
+
+	return nil
+}
+
+func (o *BotsRepository) GetSession(ctx context.Context, steamID string) (*BotSession, error) {
+	query := `SELECT * FROM bot_sessions WHERE steam_id = $1`
+
+	rows, err := o.db.Query(ctx, query, steamID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch bot session : %w", err)
+	}
+
+	session, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[BotSession])
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect bot session : %w", err)
+	}
+
+	return &session, nil
+}
+
+func (o *BotsRepository) SaveSession(ctx context.Context, arg *BotSession) error {
+	query := `
+		INSERT INTO bot_sessions (
+			steam_id, session, access_token_expires_at, refresh_token_expires_at
+		)
+		VALUES (
+			@steam_id, @session, @access_token_expires_at, @refresh_token_expires_at
+		)
+		ON CONFLICT (steam_id) DO UPDATE SET
+			session = EXCLUDED.session,
+			access_token_expires_at = EXCLUDED.access_token_expires_at,
+			refresh_token_expires_at = EXCLUDED.refresh_token_expires_at,
+			updated_at = now();
+	`
+	_, err := o.db.Exec(ctx, query, pgx.NamedArgs{
+		"steam_id":                 arg.SteamID,
+		"session":                  arg.Session,
+		"access_token_expires_at":  arg.AccessTokenExpiresAt,
+		"refresh_token_expires_at": arg.RefreshTokenExpiresAt,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save bot session : %w", err)
+	}
+
+	return nil
+}
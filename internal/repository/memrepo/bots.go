@@ -0,0 +1,122 @@
+// Package memrepo keeps repository stores in memory for tests that run
+// without a database.
+package memrepo
+
+import (
+	"context"
+	"csTrade/internal/repository"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Bots is a repository.BotsStore kept in memory.
+type Bots struct {
+	mu       sync.Mutex
+	bots     []repository.Bot
+	sessions map[string]*repository.BotSession
+	frozen   map[string]string
+}
+
+var _ repository.BotsStore = (*Bots)(nil)
+
+func NewBots() *Bots {
+	return &Bots{sessions: make(map[string]*repository.BotSession), frozen: make(map[string]string)}
+}
+
+func (s *Bots) GetBots(ctx context.Context) ([]repository.Bot, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.bots), nil
+}
+
+func (s *Bots) GetBot(ctx context.Context, steamID string) (*repository.Bot, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, b := range s.bots {
+		if b.SteamID == steamID {
+			return &b, nil
+		}
+	}
+	return nil, pgx.ErrNoRows
+}
+
+func (s *Bots) CreateBots(ctx context.Context, arg *repository.Bot) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if slices.ContainsFunc(s.bots, func(b repository.Bot) bool { return b.SteamID == arg.SteamID }) {
+		return fmt.Errorf("bot %s already exists", arg.SteamID)
+	}
+	s.bots = append(s.bots, *arg)
+	return nil
+}
+
+func (s *Bots) SetEnabled(ctx context.Context, steamID string, enabled bool) error {
+	return s.update(steamID, func(b *repository.Bot) { b.Enabled = enabled })
+}
+
+func (s *Bots) DeleteBot(ctx context.Context, steamID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.bots = slices.DeleteFunc(s.bots, func(b repository.Bot) bool { return b.SteamID == steamID })
+	return nil
+}
+
+func (s *Bots) UpdateSkinCount(ctx context.Context, steamID string, count int) error {
+	return s.update(steamID, func(b *repository.Bot) { b.SkinCount = count })
+}
+
+func (s *Bots) UpdateProxy(ctx context.Context, steamID string, proxyURL *string) error {
+	return s.update(steamID, func(b *repository.Bot) { b.ProxyURL = proxyURL })
+}
+
+func (s *Bots) UpdateAPIKey(ctx context.Context, steamID string, sealedKey []byte, domain string) error {
+	return s.update(steamID, func(b *repository.Bot) {
+		b.APIKey = sealedKey
+		b.APIKeyDomain = &domain
+	})
+}
+
+func (s *Bots) Freeze(ctx context.Context, steamID, reason string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.frozen[steamID] = reason
+	return nil
+}
+
+func (s *Bots) GetSession(ctx context.Context, steamID string) (*repository.BotSession, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if session, ok := s.sessions[steamID]; ok {
+		return session, nil
+	}
+	return nil, pgx.ErrNoRows
+}
+
+func (s *Bots) SaveSession(ctx context.Context, arg *repository.BotSession) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sessions[arg.SteamID] = arg
+	return nil
+}
+
+// FrozenReason is the reason stored by Freeze, empty for bots never frozen.
+func (s *Bots) FrozenReason(steamID string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.frozen[steamID]
+}
+
+func (s *Bots) update(steamID string, fn func(b *repository.Bot)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.bots {
+		if s.bots[i].SteamID == steamID {
+			fn(&s.bots[i])
+			return nil
+		}
+	}
+	return pgx.ErrNoRows
+}
@@ -14,6 +14,7 @@ import (
 type OfferStore interface {
 	CreateOffer(ctx context.Context, arg *offer.OfferCreateReq) (string, error)
 	GetByID(ctx context.Context, offerID string) (*offer.OfferDB, error)
+	GetByIDForUpdate(ctx context.Context, offerID string) (*offer.OfferDB, error)
 	GetOfferBySellerID(ctx context.Context, sellerID string) ([]offer.OfferDB, error)
 	GetAll(ctx context.Context) ([]offer.OfferDB, error)
 	AddBotSteamID(ctx context.Context, botSteamId string, offerID string) error
@@ -21,8 +22,18 @@ type OfferStore interface {
 	UpdateOfferAfterReceive(ctx context.Context, botSteamId, steamTradeId, offerID string) error
 	ChangePriceByID(ctx context.Context, offerID string, newPrice float64) error
 	ChangeStatusByID(ctx context.Context, newStatus string, offerId string) error
+	ChangeStatusByIDFrom(ctx context.Context, offerId, fromStatus, toStatus string) (int64, error)
+	ChangeStatusBySteamTradeID(ctx context.Context, steamTradeID, fromStatus, toStatus string) (int64, error)
+	SetBotAssetID(ctx context.Context, offerID, botAssetID string) error
+	SetEscrowEndDate(ctx context.Context, offerID string, escrowEndDate time.Time) error
+	SetSecurityCode(ctx context.Context, offerID, securityCode string) error
+	MovePendingToSteamTradeID(ctx context.Context, fromSteamTradeID, toSteamTradeID string) (int64, error)
 	GetOfferBySteamOfferID(ctx context.Context, steamTradeID string) (*offer.OfferDB, error)
 	GetOfferBySteamOfferIDForUpdate(ctx context.Context, steamTradeID string) (*offer.OfferDB, error)
+	GetOffersBySteamTradeID(ctx context.Context, steamTradeID string) ([]offer.OfferDB, error)
+	GetOffersBySteamTradeIDForUpdate(ctx context.Context, steamTradeID string) ([]offer.OfferDB, error)
+	CountByBot(ctx context.Context, statuses ...string) (map[string]int, error)
+	GetListedAssetIDs(ctx context.Context, assetIDs []string, statuses ...string) ([]string, error)
 }
 
 type OfferRepository struct {
@@ -39,13 +50,13 @@ func (o *OfferRepository) CreateOffer(ctx context.Context, arg *offer.OfferCreat
 	// log.Info().Msg("CREate offer DB")
 	query := `
 		INSERT INTO offers (
-			seller_id, price,
+			seller_id, price, status,
 			asset_id, class_id, instance_id,
 			name, full_name, market_tradable_restriction, icon_url, name_color, action_link,
 			tag_type, tag_weapon_internal, tag_weapon_name, tag_quality, tag_rarity, tag_rarity_color, tag_exterior
 		)
 		VALUES (
-			@seller_id, @price,
+			@seller_id, @price, @status,
 			@asset_id, @class_id, @instance_id,
 			@name, @full_name, @market_tradable_restriction, @icon_url, @name_color, @action_link,
 			@tag_type, @tag_weapon_internal, @tag_weapon_name, @tag_quality, @tag_rarity, @tag_rarity_color, @tag_exterior
@@ -56,6 +67,7 @@ func (o *OfferRepository) CreateOffer(ctx context.Context, arg *offer.OfferCreat
 	err := o.db.QueryRow(ctx, query, pgx.NamedArgs{
 		"seller_id":                   arg.SellerID,
 		"price":                       arg.Price,
+		"status":                      offer.OfferPending,
 		"asset_id":                    arg.AssetID,
 		"class_id":                    arg.ClassID,
 		"instance_id":                 arg.InstanceID,
@@ -96,6 +108,21 @@ func (t *OfferRepository) GetByID(ctx context.Context, offerID string) (*offer.O
 	return &offer, err
 }
 
+func (t *OfferRepository) GetByIDForUpdate(ctx context.Context, offerID string) (*offer.OfferDB, error) {
+	query := `SELECT * FROM offers WHERE id = $1 FOR UPDATE`
+	rows, err := t.db.Query(ctx, query, offerID)
+
+	if err != nil {
+		return nil, fmt.Errorf("err fetch offer by offer_id %w", err)
+	}
+
+	offer, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[offer.OfferDB])
+	if err != nil {
+		return nil, fmt.Errorf("err collect offer by offer_id %w", err)
+	}
+	return &offer, err
+}
+
 func (t *OfferRepository) GetAll(ctx context.Context) ([]offer.OfferDB, error) {
 	query := `SELECT * FROM offers`
 	rows, err := t.db.Query(ctx, query)
@@ -149,6 +176,28 @@ func (t *OfferRepository) GetOfferBySteamOfferIDForUpdate(ctx context.Context, s
 
 	return &offerData, nil
 }
+func (t *OfferRepository) GetOffersBySteamTradeID(ctx context.Context, steamTradeID string) ([]offer.OfferDB, error) {
+	query := `SELECT * FROM offers WHERE steam_trade_id = $1 ORDER BY created_at`
+
+	rows, err := t.db.Query(ctx, query, steamTradeID)
+	if err != nil {
+		return nil, fmt.Errorf("err fetch offers by steam_trade_id %w", err)
+	}
+
+	return pgx.CollectRows(rows, pgx.RowToStructByName[offer.OfferDB])
+}
+
+func (t *OfferRepository) GetOffersBySteamTradeIDForUpdate(ctx context.Context, steamTradeID string) ([]offer.OfferDB, error) {
+	query := `SELECT * FROM offers WHERE steam_trade_id = $1 ORDER BY created_at FOR UPDATE`
+
+	rows, err := t.db.Query(ctx, query, steamTradeID)
+	if err != nil {
+		return nil, fmt.Errorf("err fetch offers by steam_trade_id %w", err)
+	}
+
+	return pgx.CollectRows(rows, pgx.RowToStructByName[offer.OfferDB])
+}
+
 func (t *OfferRepository) UpdateOfferAfterReceive(ctx context.Context, botSteamId, steamTradeId, offerID string) error {
 	reservedUntil := time.Now().UTC().Add(15 * time.Minute)
 
@@ -190,3 +239,107 @@ func (t *OfferRepository) ChangePriceByID(ctx context.Context, offerID string, n
 
 	return err
 }
+
+func (t *OfferRepository) ChangeStatusByIDFrom(ctx context.Context, offerId, fromStatus, toStatus string) (int64, error) {
+	query := `UPDATE offers SET status = $1, updated_at = now() WHERE id = $2 AND status = $3`
+	tag, err := t.db.Exec(ctx, query, toStatus, offerId, fromStatus)
+	if err != nil {
+		return 0, fmt.Errorf("err change offer status by id %w", err)
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+func (t *OfferRepository) ChangeStatusBySteamTradeID(ctx context.Context, steamTradeID, fromStatus, toStatus string) (int64, error) {
+	query := `UPDATE offers SET status = $1, updated_at = now() WHERE steam_trade_id = $2 AND status = $3`
+	tag, err := t.db.Exec(ctx, query, toStatus, steamTradeID, fromStatus)
+	if err != nil {
+		return 0, fmt.Errorf("err change offer status by steam_trade_id %w", err)
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+func (t *OfferRepository) SetBotAssetID(ctx context.Context, offerID, botAssetID string) error {
+	query := `UPDATE offers SET bot_asset_id = $1, updated_at = now() WHERE id = $2`
+	_, err := t.db.Exec(ctx, query, botAssetID, offerID)
+	if err != nil {
+		return fmt.Errorf("err set bot asset id %w", err)
+	}
+
+	return nil
+}
+
+func (t *OfferRepository) SetEscrowEndDate(ctx context.Context, offerID string, escrowEndDate time.Time) error {
+	query := `UPDATE offers SET escrow_end_date = $1, updated_at = now() WHERE id = $2`
+	_, err := t.db.Exec(ctx, query, escrowEndDate, offerID)
+	if err != nil {
+		return fmt.Errorf("err set escrow end date %w", err)
+	}
+
+	return nil
+}
+
+func (t *OfferRepository) SetSecurityCode(ctx context.Context, offerID, securityCode string) error {
+	query := `UPDATE offers SET security_code = $1, updated_at = now() WHERE id = $2`
+	_, err := t.db.Exec(ctx, query, securityCode, offerID)
+	if err != nil {
+		return fmt.Errorf("err set security code %w", err)
+	}
+
+	return nil
+}
+
+// MovePendingToSteamTradeID points the pending offers of one trade offer at
+// another one, used when the seller sends the items in an offer of their own.
+func (t *OfferRepository) MovePendingToSteamTradeID(ctx context.Context, fromSteamTradeID, toSteamTradeID string) (int64, error) {
+	query := `UPDATE offers SET steam_trade_id = $1, updated_at = now() WHERE steam_trade_id = $2 AND status = 'pending'`
+	tag, err := t.db.Exec(ctx, query, toSteamTradeID, fromSteamTradeID)
+	if err != nil {
+		return 0, fmt.Errorf("err move offers to steam_trade_id %w", err)
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+// CountByBot counts the offers in any of the statuses per bot.
+func (t *OfferRepository) CountByBot(ctx context.Context, statuses ...string) (map[string]int, error) {
+	query := `
+		SELECT bot_steam_id, count(*) FROM offers
+		WHERE status = ANY($1) AND bot_steam_id IS NOT NULL
+		GROUP BY bot_steam_id
+	`
+	rows, err := t.db.Query(ctx, query, statuses)
+	if err != nil {
+		return nil, fmt.Errorf("err count offers by bot %w", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var botSteamID string
+		var n int
+		if err := rows.Scan(&botSteamID, &n); err != nil {
+			return nil, fmt.Errorf("err scan offers by bot %w", err)
+		}
+		counts[botSteamID] = n
+	}
+
+	return counts, rows.Err()
+}
+
+// GetListedAssetIDs returns those of the assets that have an offer in any
+// of the statuses.
+func (t *OfferRepository) GetListedAssetIDs(ctx context.Context, assetIDs []string, statuses ...string) ([]string, error) {
+	query := `
+		SELECT DISTINCT asset_id FROM offers
+		WHERE asset_id = ANY($1) AND status = ANY($2)
+		ORDER BY asset_id
+	`
+	rows, err := t.db.Query(ctx, query, assetIDs, statuses)
+	if err != nil {
+		return nil, fmt.Errorf("err fetch listed asset ids %w", err)
+	}
+
+	return pgx.CollectRows(rows, pgx.RowTo[string])
+}
@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"context"
+	"csTrade/internal/domain/operation"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type OperationStore interface {
+	CreateOperation(ctx context.Context, arg *operation.OperationDB) (string, error)
+	GetOperation(ctx context.Context, id string) (*operation.OperationDB, error)
+	GetPendingOperations(ctx context.Context, createdBefore time.Time) ([]operation.OperationDB, error)
+	CompleteOperation(ctx context.Context, id, tradeOfferID string) error
+	FailOperation(ctx context.Context, id, tradeOfferID, errMsg string) error
+}
+
+type OperationRepository struct {
+	db Querier
+}
+
+func NewOperationRepo(db Querier) *OperationRepository {
+	return &OperationRepository{
+		db: db,
+	}
+}
+
+func (o *OperationRepository) CreateOperation(ctx context.Context, arg *operation.OperationDB) (string, error) {
+	query := `
+		INSERT INTO trade_operations (
+			kind, status, bot_steam_id, user_steam_id, security_code, offer_ids
+		) VALUES (
+			@kind, @status, @bot_steam_id, @user_steam_id, @security_code, @offer_ids
+		) RETURNING id;
+	`
+
+	var id string
+	err := o.db.QueryRow(ctx, query, pgx.NamedArgs{
+		"kind":          arg.Kind,
+		"status":        operation.OperationPending,
+		"bot_steam_id":  arg.BotSteamID,
+		"user_steam_id": arg.UserSteamID,
+		"security_code": arg.SecurityCode,
+		"offer_ids":     arg.OfferIDs,
+	}).Scan(&id)
+	if err != nil {
+		return "", fmt.Errorf("err create trade operation %w", err)
+	}
+
+	return id, nil
+}
+
+func (o *OperationRepository) GetOperation(ctx context.Context, id string) (*operation.OperationDB, error) {
+	query := `SELECT * FROM trade_operations WHERE id = $1`
+
+	rows, err := o.db.Query(ctx, query, id)
+	if err != nil {
+		return nil, fmt.Errorf("err fetch trade operation %w", err)
+	}
+
+	op, err := pgx.CollectOneRow(rows, pgx.RowToStructByName[operation.OperationDB])
+	if err != nil {
+		return nil, fmt.Errorf("err collect row trade operation %w", err)
+	}
+
+	return &op, nil
+}
+
+// GetPendingOperations returns operations created before the given time
+// that never got a result.
+func (o *OperationRepository) GetPendingOperations(ctx context.Context, createdBefore time.Time) ([]operation.OperationDB, error) {
+	query := `SELECT * FROM trade_operations WHERE status = $1 AND created_at < $2 ORDER BY created_at`
+
+	rows, err := o.db.Query(ctx, query, operation.OperationPending, createdBefore)
+	if err != nil {
+		return nil, fmt.Errorf("err fetch pending trade operations %w", err)
+	}
+
+	ops, err := pgx.CollectRows(rows, pgx.RowToStructByName[operation.OperationDB])
+	if err != nil {
+		return nil, fmt.Errorf("err collect rows pending trade operations %w", err)
+	}
+
+	return ops, nil
+}
+
+func (o *OperationRepository) CompleteOperation(ctx context.Context, id, tradeOfferID string) error {
+	query := `
+		UPDATE trade_operations
+		SET status = $1, trade_offer_id = $2, updated_at = now()
+		WHERE id = $3 AND status = $4
+	`
+	_, err := o.db.Exec(ctx, query, operation.OperationSucceeded, tradeOfferID, id, operation.OperationPending)
+	if err != nil {
+		return fmt.Errorf("err complete trade operation %w", err)
+	}
+
+	return nil
+}
+
+// FailOperation marks the operation failed. tradeOfferID is kept when Steam
+// created an offer before the failure, empty otherwise.
+func (o *OperationRepository) FailOperation(ctx context.Context, id, tradeOfferID, errMsg string) error {
+	query := `
+		UPDATE trade_operations
+		SET status = $1, error = $2, trade_offer_id = NULLIF($3, ''), updated_at = now()
+		WHERE id = $4 AND status = $5
+	`
+	_, err := o.db.Exec(ctx, query, operation.OperationFailed, errMsg, tradeOfferID, id, operation.OperationPending)
+	if err != nil {
+		return fmt.Errorf("err fail trade operation %w", err)
+	}
+
+	return nil
+}
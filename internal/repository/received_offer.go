@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// ReceivedOfferAudit records what a bot did with an offer someone sent it.
+type ReceivedOfferAudit struct {
+	ID             uuid.UUID `db:"id"`
+	BotSteamID     string    `db:"bot_steam_id"`
+	TradeOfferID   string    `db:"trade_offer_id"`
+	PartnerSteamID string    `db:"partner_steam_id"`
+	Decision       string    `db:"decision"`
+	Reason         string    `db:"reason"`
+	ItemsToGive    int       `db:"items_to_give"`
+	ItemsToReceive int       `db:"items_to_receive"`
+	Error          *string   `db:"error"`
+	CreatedAt      time.Time `db:"created_at"`
+}
+
+type ReceivedOfferStore interface {
+	CreateAudit(ctx context.Context, arg *ReceivedOfferAudit) error
+	GetAuditByBot(ctx context.Context, botSteamID string, limit int) ([]ReceivedOfferAudit, error)
+}
+
+type ReceivedOfferRepository struct {
+	db Querier
+}
+
+func NewReceivedOfferRepo(db Querier) *ReceivedOfferRepository {
+	return &ReceivedOfferRepository{
+		db: db,
+	}
+}
+
+func (r *ReceivedOfferRepository) CreateAudit(ctx context.Context, arg *ReceivedOfferAudit) error {
+	query := `
+		INSERT INTO received_offer_audit (
+			bot_steam_id, trade_offer_id, partner_steam_id, decision, reason, items_to_give, items_to_receive, error
+		) VALUES (
+			@bot_steam_id, @trade_offer_id, @partner_steam_id, @decision, @reason, @items_to_give, @items_to_receive, @error
+		);
+	`
+	_, err := r.db.Exec(ctx, query, pgx.NamedArgs{
+		"bot_steam_id":     arg.BotSteamID,
+		"trade_offer_id":   arg.TradeOfferID,
+		"partner_steam_id": arg.PartnerSteamID,
+		"decision":         arg.Decision,
+		"reason":           arg.Reason,
+		"items_to_give":    arg.ItemsToGive,
+		"items_to_receive": arg.ItemsToReceive,
+		"error":            arg.Error,
+	})
+	if err != nil {
+		return fmt.Errorf("err create received offer audit %w", err)
+	}
+
+	return nil
+}
+
+func (r *ReceivedOfferRepository) GetAuditByBot(ctx context.Context, botSteamID string, limit int) ([]ReceivedOfferAudit, error) {
+	query := `SELECT * FROM received_offer_audit WHERE bot_steam_id = $1 ORDER BY created_at DESC LIMIT $2`
+
+	rows, err := r.db.Query(ctx, query, botSteamID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("err fetch received offer audit %w", err)
+	}
+
+	return pgx.CollectRows(rows, pgx.RowToStructByName[ReceivedOfferAudit])
+}
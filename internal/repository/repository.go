@@ -24,6 +24,9 @@ type Repository struct {
 	User        UserStore
 	Transaction TransactionStore
 	Bot         BotsStore
+
+	ReceivedOffer ReceivedOfferStore
+	Operation     OperationStore
 }
 
 func NewRepository(pool *pgxpool.Pool) *Repository {
@@ -36,6 +39,8 @@ func NewRepository(pool *pgxpool.Pool) *Repository {
 	r.User = NewUserRepository(pool)
 	r.Transaction = NewTransactionRepo(pool)
 	r.Bot = NewBotsRepo(pool)
+	r.ReceivedOffer = NewReceivedOfferRepo(pool)
+	r.Operation = NewOperationRepo(pool)
 
 	return r
 }
@@ -48,6 +53,9 @@ func (r *Repository) newWithTx(tx pgx.Tx) *Repository {
 		User:        NewUserRepository(tx),
 		Transaction: NewTransactionRepo(tx),
 		Bot:         NewBotsRepo(tx),
+
+		ReceivedOffer: NewReceivedOfferRepo(tx),
+		Operation:     NewOperationRepo(tx),
 	}
 }
 
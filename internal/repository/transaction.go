@@ -15,7 +15,9 @@ type TransactionStore interface {
 	GetTransactionByID(ctx context.Context, id string) (*transaction.TransactionDB, error)
 	GetTransactionBySellerID(ctx context.Context, id string) ([]transaction.TransactionDB, error)
 	GetTransactionByBuyerID(ctx context.Context, id string) ([]transaction.TransactionDB, error)
+	GetTransactionsBySteamTradeID(ctx context.Context, steamTradeID string) ([]transaction.TransactionDB, error)
 	UpdateTransactionStatusByID(ctx context.Context, status, id string) error
+	UpdateTransactionStatusFrom(ctx context.Context, id, fromStatus, toStatus string) (int64, error)
 }
 
 type TransactionRepository struct {
@@ -31,19 +33,21 @@ func NewTransactionRepo(db Querier) *TransactionRepository {
 func (t *TransactionRepository) CreateTransaction(ctx context.Context, arg transaction.TransactionDB) error {
 	query := `
 		INSERT INTO transactions (
-			offer_id, seller_id, buyer_id, bot_id, status, price
+			offer_id, seller_id, buyer_id, bot_id, status, price, steam_trade_id, security_code
 		) VALUES (
-			@offer_id, @seller_id, @buyer_id, @bot_id, @status, @price
+			@offer_id, @seller_id, @buyer_id, @bot_id, @status, @price, @steam_trade_id, @security_code
 		);
 	`
 
 	_, err := t.db.Exec(ctx, query, pgx.NamedArgs{
-		"offer_id":  arg.OfferID,
-		"seller_id": arg.SellerID,
-		"buyer_id":  arg.BuyerID,
-		"bot_id":    arg.BotID,
-		"status":    arg.Status,
-		"price":     arg.Price,
+		"offer_id":       arg.OfferID,
+		"seller_id":      arg.SellerID,
+		"buyer_id":       arg.BuyerID,
+		"bot_id":         arg.BotID,
+		"status":         arg.Status,
+		"price":          arg.Price,
+		"steam_trade_id": arg.SteamTradeID,
+		"security_code":  arg.SecurityCode,
 	})
 	if err != nil {
 		log.Error().Err(err).Msg("CreateTransaction")
@@ -108,3 +112,29 @@ func (t *TransactionRepository) UpdateTransactionStatusByID(ctx context.Context,
 	return err
 
 }
+
+func (t *TransactionRepository) GetTransactionsBySteamTradeID(ctx context.Context, steamTradeID string) ([]transaction.TransactionDB, error) {
+	query := `SELECT * FROM transactions WHERE steam_trade_id = $1`
+
+	rows, err := t.db.Query(ctx, query, steamTradeID)
+	if err != nil {
+		return nil, fmt.Errorf("err fetch transactions by steam_trade_id %w", err)
+	}
+
+	transactions, err := pgx.CollectRows(rows, pgx.RowToStructByName[transaction.TransactionDB])
+	if err != nil {
+		return nil, fmt.Errorf("err collect rows transactions by steam_trade_id %w", err)
+	}
+
+	return transactions, nil
+}
+
+func (t *TransactionRepository) UpdateTransactionStatusFrom(ctx context.Context, id, fromStatus, toStatus string) (int64, error) {
+	query := `UPDATE transactions SET status = $1 WHERE id = $2 AND status = $3`
+	tag, err := t.db.Exec(ctx, query, toStatus, id, fromStatus)
+	if err != nil {
+		return 0, fmt.Errorf("err update transaction status %w", err)
+	}
+
+	return tag.RowsAffected(), nil
+}
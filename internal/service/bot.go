@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+	"csTrade/internal/domain/offer"
+	"csTrade/internal/repository"
+	"csTrade/internal/service/bots"
+	"errors"
+	"fmt"
+)
+
+var ErrBotHasOffers = errors.New("bot still holds offers")
+
+type BotService struct {
+	repo        *repository.Repository
+	botsManager *bots.BotManager
+}
+
+func NewBotService(repo *repository.Repository, botsManager *bots.BotManager) *BotService {
+	return &BotService{repo: repo, botsManager: botsManager}
+}
+
+type AddBotReq struct {
+	SteamID        string  `json:"steam_id" binding:"required"`
+	Username       string  `json:"username" binding:"required"`
+	Password       string  `json:"password" binding:"required"`
+	SharedSecret   string  `json:"shared_secret" binding:"required"`
+	IdentitySecret string  `json:"identity_secret" binding:"required"`
+	DeviceID       string  `json:"device_id"`
+	ProxyURL       *string `json:"proxy_url"`
+}
+
+// GetStatuses returns the live status of every bot with the offers it has
+// waiting on Steam.
+func (bs *BotService) GetStatuses(ctx context.Context) ([]bots.BotStatus, error) {
+	statuses := bs.botsManager.Statuses()
+
+	pending, err := bs.repo.Offer.CountByBot(ctx, offer.OfferPending.String(), offer.OfferReserved.String())
+	if err != nil {
+		return nil, err
+	}
+	stored, err := bs.repo.Bot.GetBots(ctx)
+	if err != nil {
+		return nil, err
+	}
+	enabled := make(map[string]bool, len(stored))
+	for _, b := range stored {
+		enabled[b.SteamID] = b.Enabled
+	}
+
+	for i := range statuses {
+		statuses[i].PendingOffers = pending[statuses[i].SteamID]
+		if e, ok := enabled[statuses[i].SteamID]; ok {
+			statuses[i].Enabled = e
+		}
+	}
+	return statuses, nil
+}
+
+func (bs *BotService) Readiness() bots.Readiness {
+	return bs.botsManager.Readiness()
+}
+
+// AddBot stores the bot and starts it in the running manager.
+func (bs *BotService) AddBot(ctx context.Context, req *AddBotReq) error {
+	err := bs.repo.Bot.CreateBots(ctx, &repository.Bot{
+		SteamID:        req.SteamID,
+		Username:       req.Username,
+		Password:       req.Password,
+		SharedSecret:   req.SharedSecret,
+		IdentitySecret: req.IdentitySecret,
+		DeviceID:       req.DeviceID,
+		ProxyURL:       req.ProxyURL,
+	})
+	if err != nil {
+		return err
+	}
+
+	stored, err := bs.repo.Bot.GetBot(ctx, req.SteamID)
+	if err != nil {
+		return err
+	}
+	return bs.botsManager.AddBot(stored)
+}
+
+func (bs *BotService) SetEnabled(ctx context.Context, steamID string, enabled bool) error {
+	if err := bs.repo.Bot.SetEnabled(ctx, steamID, enabled); err != nil {
+		return err
+	}
+
+	err := bs.botsManager.SetEnabled(steamID, enabled)
+	if err != nil && !errors.Is(err, bots.ErrBotNotFound) {
+		return err
+	}
+	return nil
+}
+
+// RemoveBot deletes a bot that no longer holds any listing or trade.
+func (bs *BotService) RemoveBot(ctx context.Context, steamID string) error {
+	held, err := bs.repo.Offer.CountByBot(ctx,
+		offer.OfferPending.String(), offer.OfferOnSale.String(), offer.OfferReserved.String())
+	if err != nil {
+		return err
+	}
+	if n := held[steamID]; n > 0 {
+		return fmt.Errorf("%w: %d offers", ErrBotHasOffers, n)
+	}
+
+	if err := bs.repo.Bot.DeleteBot(ctx, steamID); err != nil {
+		return err
+	}
+	bs.botsManager.RemoveBot(steamID)
+	return nil
+}
+
+func (bs *BotService) Relogin(ctx context.Context, steamID string) error {
+	return bs.botsManager.Relogin(ctx, steamID)
+}
@@ -0,0 +1,97 @@
+package bots
+
+import (
+	"context"
+	"csTrade/internal/domain/bot"
+	"csTrade/internal/repository"
+	"errors"
+
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	ErrBotExists   = errors.New("bot already running")
+	ErrBotNotFound = errors.New("bot not found")
+)
+
+// AddBot logs a bot stored after startup in and registers it with all
+// loops. The login runs in the background, its progress shows in Statuses.
+func (m *BotManager) AddBot(stored *repository.Bot) error {
+	if m.GetBotByID(stored.SteamID) != nil {
+		return ErrBotExists
+	}
+
+	m.startBot(stored, "added")
+	return nil
+}
+
+// startBot logs the bot in in the background. A RemoveBot from here on
+// keeps the login from registering it.
+func (m *BotManager) startBot(stored *repository.Bot, reason string) {
+	gen := m.generation(stored.SteamID)
+	b := bot.NewSteamClient(stored, m.botOpts...)
+	m.setStatus(b, StatusLoggingIn, reason)
+	go m.initBot(m.baseCtx(), b, stored, gen)
+}
+
+// SetEnabled takes the bot in or out of rotation for new deposits. A
+// disabled bot keeps its session so trades already running can finish.
+func (m *BotManager) SetEnabled(steamID string, enabled bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	rb, ok := m.bots[steamID]
+	if !ok {
+		return ErrBotNotFound
+	}
+	rb.disabled = !enabled
+
+	log.Info().Str("bot", steamID).Bool("enabled", enabled).Msg("Bot rotation changed")
+	return nil
+}
+
+// RemoveBot stops all loops of the bot and forgets it.
+func (m *BotManager) RemoveBot(steamID string) {
+	m.unregister(steamID)
+
+	m.statusMu.Lock()
+	delete(m.states, steamID)
+	m.statusMu.Unlock()
+
+	log.Info().Str("bot", steamID).Msg("Bot removed")
+}
+
+// Relogin drops the bot's session and logs it in again in the background.
+// A bot that failed to start is started again from its stored row.
+func (m *BotManager) Relogin(ctx context.Context, steamID string) error {
+	b := m.GetBotByID(steamID)
+	if b == nil {
+		stored, err := m.repo.GetBot(ctx, steamID)
+		if err != nil {
+			return ErrBotNotFound
+		}
+		m.startBot(stored, "re-login")
+		return nil
+	}
+
+	m.setStatus(b, StatusReconnecting, "forced re-login")
+	go func() {
+		ctx := m.baseCtx()
+
+		err := m.Do(ctx, b, func() error { return b.Login(ctx) })
+		if !m.current(b) {
+			return
+		}
+		if err != nil {
+			log.Error().Err(err).Str("username", b.Username).Msg("Forced bot re-login failed")
+			m.setStatus(b, StatusOffline, err.Error())
+			m.wakeWatchdog(b.SteamID)
+			return
+		}
+
+		m.saveSession(ctx, b)
+		m.markLogin(b.SteamID)
+		m.setStatus(b, StatusOnline, "forced re-login")
+	}()
+	return nil
+}
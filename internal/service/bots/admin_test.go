@@ -0,0 +1,47 @@
+package bots_test
+
+import (
+	"csTrade/internal/domain/bot"
+	"csTrade/internal/domain/bot/fakesteam"
+	"csTrade/internal/repository/memrepo"
+	"csTrade/internal/service/bots"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const beginAuthPath = "/IAuthenticationService/BeginAuthSessionViaCredentials/v1/"
+
+func TestRemoveBotDuringLoginKeepsItRemoved(t *testing.T) {
+	srv := fakesteam.New()
+	t.Cleanup(srv.Close)
+	store := memrepo.NewBots()
+
+	m := bots.NewBotManager(store, nil,
+		bot.WithEndpoints(srv.Endpoints()),
+		bot.WithRetryPolicy(bot.RetryPolicy{MaxAttempts: 2, BaseDelay: 50 * time.Millisecond, MaxDelay: 50 * time.Millisecond}),
+	)
+	m.InitBots(t.Context(), 1)
+	m.StartPollers(time.Millisecond, time.Minute)
+
+	stored := addBot(srv, store, 0)
+	srv.FailRequests(beginAuthPath, 1, http.StatusBadGateway, nil)
+
+	require.NoError(t, m.AddBot(&stored))
+	m.RemoveBot(stored.SteamID)
+
+	eventually(t, func() bool { return srv.Requests(beginAuthPath) >= 2 }, "the login is retried")
+	time.Sleep(100 * time.Millisecond)
+
+	assert.Nil(t, m.GetBotByID(stored.SteamID), "the finished login does not register the removed bot")
+	_, ok := statusOf(m, stored.SteamID)
+	assert.False(t, ok)
+	assert.Zero(t, srv.Requests("/IEconService/GetTradeOffers/v1/"), "no loop started for the removed bot")
+
+	require.NoError(t, m.Relogin(t.Context(), stored.SteamID), "a stored bot can be started again")
+	eventually(t, func() bool { return m.IsOnline(stored.SteamID) }, "the bot logs in")
+	m.RemoveBot(stored.SteamID)
+}
@@ -0,0 +1,158 @@
+package bots
+
+import (
+	"context"
+	"csTrade/internal/domain/bot"
+	"csTrade/internal/repository"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// SetAPIKeyDomain sets the domain new Web API keys are registered for.
+func (m *BotManager) SetAPIKeyDomain(domain string) {
+	m.apiKeyDomain = domain
+}
+
+// setupAPIKey gives the bot its Web API key. A key stored earlier must still
+// be the one on the account, otherwise the bot is frozen. Without a stored
+// key the account's key is read, or registered when there is none, and
+// stored encrypted. A key someone registered for another domain is the
+// mark of a hijacked account and freezes the bot too. When the key cannot
+// be read the bot stays unchecked and the next api key check tries again.
+func (m *BotManager) setupAPIKey(ctx context.Context, b *bot.SteamBot, stored *repository.Bot) {
+	if _, frozen := b.Frozen(); frozen {
+		return
+	}
+
+	var live *bot.WebAPIKey
+	err := m.Do(ctx, b, func() error {
+		var err error
+		live, err = b.GetAPIKey(ctx)
+		return err
+	})
+	if err != nil {
+		m.recordError(b.SteamID, fmt.Errorf("api key unchecked: %w", err))
+		log.Error().Err(err).Str("username", b.Username).Msg("Failed to read bot api key, retrying on the next check")
+		return
+	}
+
+	if len(stored.APIKey) > 0 && m.cipher != nil {
+		plain, err := m.cipher.Decrypt(stored.APIKey)
+		if err != nil {
+			log.Error().Err(err).Str("username", b.Username).Msg("Failed to decrypt bot api key")
+			return
+		}
+		key := bot.WebAPIKey{Key: string(plain)}
+		if stored.APIKeyDomain != nil {
+			key.Domain = *stored.APIKeyDomain
+		}
+		b.SetAPIKey(key)
+		m.compareAPIKey(ctx, b, live)
+		return
+	}
+
+	switch {
+	case live.Key == "" && m.apiKeyDomain == "":
+		log.Warn().Str("username", b.Username).Msg("Bot has no api key and no domain to register one")
+		return
+	case live.Key == "":
+		err = m.Do(ctx, b, func() error {
+			var err error
+			live, err = b.RegisterAPIKey(ctx, m.apiKeyDomain)
+			return err
+		})
+		if err != nil {
+			log.Error().Err(err).Str("username", b.Username).Msg("Failed to register bot api key")
+			return
+		}
+	case m.apiKeyDomain != "" && live.Domain != m.apiKeyDomain:
+		m.Freeze(ctx, b, fmt.Sprintf("api key registered for another domain %q", live.Domain))
+		return
+	}
+
+	b.SetAPIKey(*live)
+
+	if m.cipher == nil {
+		log.Warn().Str("username", b.Username).Msg("BOT_SECRET_KEY not set, bot api key is not stored")
+		return
+	}
+	sealed, err := m.cipher.Encrypt([]byte(live.Key))
+	if err != nil {
+		log.Error().Err(err).Str("username", b.Username).Msg("Failed to encrypt bot api key")
+		return
+	}
+	if err := m.repo.UpdateAPIKey(ctx, b.SteamID, sealed, live.Domain); err != nil {
+		log.Error().Err(err).Str("username", b.Username).Msg("Failed to save bot api key")
+	}
+}
+
+// StartAPIKeyCheck compares every bot's key and domain with the account on
+// each tick and freezes bots whose key was swapped.
+func (m *BotManager) StartAPIKeyCheck(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			for _, b := range m.List() {
+				m.CheckAPIKey(ctx, b)
+			}
+		}
+	}()
+}
+
+func (m *BotManager) CheckAPIKey(ctx context.Context, b *bot.SteamBot) {
+	if _, frozen := b.Frozen(); frozen {
+		return
+	}
+	if b.APIKey().Key == "" {
+		stored, err := m.repo.GetBot(ctx, b.SteamID)
+		if err != nil {
+			log.Error().Err(err).Str("username", b.Username).Msg("Failed to load bot for api key setup")
+			return
+		}
+		m.setupAPIKey(ctx, b, stored)
+		return
+	}
+
+	var live *bot.WebAPIKey
+	err := m.Do(ctx, b, func() error {
+		var err error
+		live, err = b.GetAPIKey(ctx)
+		return err
+	})
+	if err != nil {
+		log.Error().Err(err).Str("username", b.Username).Msg("Failed to check bot api key")
+		return
+	}
+	m.compareAPIKey(ctx, b, live)
+}
+
+func (m *BotManager) compareAPIKey(ctx context.Context, b *bot.SteamBot, live *bot.WebAPIKey) {
+	known := b.APIKey()
+	switch {
+	case live.Key != known.Key:
+		m.Freeze(ctx, b, "api key changed")
+	case live.Domain != known.Domain:
+		m.Freeze(ctx, b, fmt.Sprintf("api key domain changed to %q", live.Domain))
+	}
+}
+
+// Freeze stops all trading on the bot right away and keeps it frozen across
+// restarts.
+func (m *BotManager) Freeze(ctx context.Context, b *bot.SteamBot, reason string) {
+	b.Freeze(reason)
+	log.Error().Str("username", b.Username).Str("bot", b.SteamID).Str("reason", reason).Msg("Bot frozen")
+
+	if err := m.repo.Freeze(ctx, b.SteamID, reason); err != nil {
+		log.Error().Err(err).Str("username", b.Username).Msg("Failed to store bot freeze")
+	}
+}
@@ -0,0 +1,56 @@
+package bots_test
+
+import (
+	"csTrade/internal/service/bots"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	foreignKey = "0123456789ABCDEF0123456789ABCDEF"
+	keyDomain  = "cstrade.test"
+)
+
+func TestAPIKeyForAnotherDomainFreezesBot(t *testing.T) {
+	srv, store, _ := newFleet(t, 0)
+	stored := addBot(srv, store, 0)
+	srv.SetAPIKey(stored.SteamID, foreignKey, "steamcommunlty.example")
+
+	m := startManager(t, srv, store, func(m *bots.BotManager) { m.SetAPIKeyDomain(keyDomain) })
+
+	b := m.GetBotByID(stored.SteamID)
+	require.NotNil(t, b)
+	reason, frozen := b.Frozen()
+	assert.True(t, frozen)
+	assert.Contains(t, reason, "steamcommunlty.example")
+	assert.Equal(t, reason, store.FrozenReason(stored.SteamID))
+	assert.Empty(t, b.APIKey().Key, "the foreign key is not adopted")
+}
+
+func TestAPIKeyReadFailureIsRetried(t *testing.T) {
+	srv, store, _ := newFleet(t, 0)
+	stored := addBot(srv, store, 0)
+	srv.FailRequests("/dev/apikey", 1, http.StatusInternalServerError, nil)
+
+	m := startManager(t, srv, store, func(m *bots.BotManager) { m.SetAPIKeyDomain(keyDomain) })
+
+	b := m.GetBotByID(stored.SteamID)
+	require.NotNil(t, b)
+	assert.Empty(t, b.APIKey().Key)
+	st, _ := statusOf(m, b.SteamID)
+	assert.Contains(t, st.LastError, "api key unchecked")
+
+	m.CheckAPIKey(t.Context(), b)
+	key := b.APIKey()
+	require.NotEmpty(t, key.Key, "the next check registers the key")
+	assert.Equal(t, keyDomain, key.Domain)
+
+	srv.SetAPIKey(b.SteamID, foreignKey, keyDomain)
+	m.CheckAPIKey(t.Context(), b)
+	reason, frozen := b.Frozen()
+	assert.True(t, frozen)
+	assert.Equal(t, "api key changed", reason)
+}
@@ -0,0 +1,221 @@
+package bots
+
+import (
+	"context"
+	"csTrade/internal/domain/bot"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	ErrDispatcherBusy    = errors.New("trade dispatcher queue is full")
+	ErrDispatcherStopped = errors.New("trade dispatcher stopped")
+)
+
+// TradeResult is handed to the command's Done callback once Steam answered.
+type TradeResult struct {
+	BotSteamID   string
+	TradeOfferID string
+	Err          error
+}
+
+// TradeCommand is a trade the dispatcher sends through one bot.
+type TradeCommand interface {
+	botSteamID() string
+	send(ctx context.Context, b *bot.SteamBot) (string, error)
+	done(ctx context.Context, res TradeResult)
+}
+
+// ReceiveFromUserTrade asks the seller for the items of a deposit.
+type ReceiveFromUserTrade struct {
+	BotSteamID string
+	Assets     []bot.Asset
+	TradeURL   string
+	SellerID   string
+	Message    string
+	Done       func(ctx context.Context, res TradeResult)
+}
+
+func (t ReceiveFromUserTrade) botSteamID() string { return t.BotSteamID }
+
+func (t ReceiveFromUserTrade) send(ctx context.Context, b *bot.SteamBot) (string, error) {
+	return b.ReceiveFromUser(ctx, t.Assets, t.TradeURL, t.SellerID, t.Message)
+}
+
+func (t ReceiveFromUserTrade) done(ctx context.Context, res TradeResult) {
+	if t.Done != nil {
+		t.Done(ctx, res)
+	}
+}
+
+// SendToBuyerEventTrade gives purchased items to the buyer.
+type SendToBuyerEventTrade struct {
+	BotSteamID    string
+	Assets        []bot.Asset
+	BuyerTradeURL string
+	BuyerID       string
+	Message       string
+	Done          func(ctx context.Context, res TradeResult)
+}
+
+func (t SendToBuyerEventTrade) botSteamID() string { return t.BotSteamID }
+
+func (t SendToBuyerEventTrade) send(ctx context.Context, b *bot.SteamBot) (string, error) {
+	return b.SendToBuyer(ctx, t.Assets, t.BuyerTradeURL, t.BuyerID, t.Message)
+}
+
+func (t SendToBuyerEventTrade) done(ctx context.Context, res TradeResult) {
+	if t.Done != nil {
+		t.Done(ctx, res)
+	}
+}
+
+// Dispatcher sends queued trade commands from a pool of workers. Commands
+// for the same bot still run one at a time through the bot's queue.
+type Dispatcher struct {
+	m        *BotManager
+	commands chan TradeCommand
+	workers  int
+
+	mu      sync.RWMutex
+	stopped bool
+	wg      sync.WaitGroup
+	drained chan struct{}
+}
+
+func NewDispatcher(m *BotManager, workers, queueSize int) *Dispatcher {
+	return &Dispatcher{
+		m:        m,
+		commands: make(chan TradeCommand, queueSize),
+		workers:  max(workers, 1),
+		drained:  make(chan struct{}),
+	}
+}
+
+// Start runs the workers until ctx ends. Trades already being sent then
+// finish, commands still queued fail with ErrDispatcherStopped so their
+// callbacks can release what they hold.
+func (d *Dispatcher) Start(ctx context.Context) {
+	for range d.workers {
+		d.wg.Add(1)
+		go d.work(ctx)
+	}
+	go d.stop(ctx)
+
+	log.Info().Int("workers", d.workers).Int("queue", cap(d.commands)).Msg("Trade dispatcher started")
+}
+
+// Submit queues the command without waiting for a free worker.
+func (d *Dispatcher) Submit(cmd TradeCommand) error {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if d.stopped {
+		return ErrDispatcherStopped
+	}
+	select {
+	case d.commands <- cmd:
+		return nil
+	default:
+		return ErrDispatcherBusy
+	}
+}
+
+// Wait blocks until the dispatcher stopped and every command got its
+// result.
+func (d *Dispatcher) Wait() {
+	<-d.drained
+}
+
+func (d *Dispatcher) stop(ctx context.Context) {
+	<-ctx.Done()
+
+	d.mu.Lock()
+	d.stopped = true
+	d.mu.Unlock()
+	d.wg.Wait()
+
+	doneCtx := context.WithoutCancel(ctx)
+	n := 0
+	for {
+		select {
+		case cmd := <-d.commands:
+			cmd.done(doneCtx, TradeResult{BotSteamID: cmd.botSteamID(), Err: ErrDispatcherStopped})
+			n++
+		default:
+			log.Info().Int("failed", n).Msg("Trade dispatcher stopped")
+			close(d.drained)
+			return
+		}
+	}
+}
+
+func (d *Dispatcher) work(ctx context.Context) {
+	defer d.wg.Done()
+
+	// A trade that started is sent to the end, bot timeouts bound it.
+	// Cutting it off could leave an offer on Steam nobody knows about.
+	sendCtx := context.WithoutCancel(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case cmd := <-d.commands:
+			if ctx.Err() != nil {
+				cmd.done(sendCtx, TradeResult{BotSteamID: cmd.botSteamID(), Err: ErrDispatcherStopped})
+				continue
+			}
+			cmd.done(sendCtx, d.execute(sendCtx, cmd))
+		}
+	}
+}
+
+func (d *Dispatcher) execute(ctx context.Context, cmd TradeCommand) TradeResult {
+	res := TradeResult{BotSteamID: cmd.botSteamID()}
+
+	b := d.m.GetBotByID(res.BotSteamID)
+	if b == nil {
+		res.Err = fmt.Errorf("err get bot by id %s", res.BotSteamID)
+		return res
+	}
+
+	res.TradeOfferID, res.Err = d.m.SendWithSessionRetry(ctx, b, func() (string, error) {
+		return cmd.send(ctx, b)
+	})
+	if res.Err != nil {
+		log.Error().Err(res.Err).Str("bot", res.BotSteamID).Msg("err dispatch trade")
+	}
+	return res
+}
+
+// SendWithSessionRetry runs send in the bot's operation queue. It
+// re-authenticates the bot like the watchdog does and repeats send once
+// when Steam answered that the web session is gone. Such requests are refused before anything is created, so
+// the repeat cannot duplicate a trade. If a trade offer ID came back the
+// offer exists and is never resent.
+func (m *BotManager) SendWithSessionRetry(ctx context.Context, b *bot.SteamBot, send func() (string, error)) (string, error) {
+	var tradeOfferID string
+	err := m.Do(ctx, b, func() error {
+		var err error
+		tradeOfferID, err = send()
+		if tradeOfferID != "" || !errors.Is(err, bot.ErrSessionExpired) {
+			return err
+		}
+
+		log.Warn().Err(err).Str("bot", b.SteamID).Msg("steam session expired, re-authenticating")
+		if err := b.Reauthenticate(ctx); err != nil {
+			m.recordError(b.SteamID, err)
+			return fmt.Errorf("err re-authenticate bot %s: %w", b.SteamID, err)
+		}
+		m.saveSession(ctx, b)
+		m.markLogin(b.SteamID)
+		m.setStatus(b, StatusOnline, "re-authenticated after expired session")
+
+		tradeOfferID, err = send()
+		return err
+	})
+	return tradeOfferID, err
+}
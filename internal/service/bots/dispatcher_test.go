@@ -0,0 +1,163 @@
+package bots_test
+
+import (
+	"context"
+	"csTrade/internal/domain/bot"
+	"csTrade/internal/domain/bot/fakesteam"
+	"csTrade/internal/repository/memrepo"
+	"csTrade/internal/secret"
+	"csTrade/internal/service/bots"
+	"encoding/base64"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type dispatched struct {
+	res    bots.TradeResult
+	ctxErr error
+}
+
+// deposit builds a command asking the partner for one item and reports its
+// result on the returned channel.
+func deposit(srv *fakesteam.Server, botSteamID, assetID string) (bots.ReceiveFromUserTrade, chan dispatched) {
+	results := make(chan dispatched, 1)
+	return bots.ReceiveFromUserTrade{
+		BotSteamID: botSteamID,
+		Assets:     []bot.Asset{bot.CSAsset(assetID)},
+		TradeURL:   srv.TradeURL(partnerSteamID),
+		SellerID:   partnerSteamID,
+		Message:    "code ABC234",
+		Done: func(ctx context.Context, res bots.TradeResult) {
+			results <- dispatched{res: res, ctxErr: ctx.Err()}
+		},
+	}, results
+}
+
+func result(t *testing.T, results chan dispatched) dispatched {
+	t.Helper()
+	select {
+	case r := <-results:
+		return r
+	case <-time.After(time.Second):
+		t.Fatal("trade command got no result")
+		return dispatched{}
+	}
+}
+
+func TestDispatcherSendsTrade(t *testing.T) {
+	srv, _, m := newFleet(t, 1)
+	srv.AddAccount(fakesteam.Account{SteamID: partnerSteamID, Username: "seller", TradeToken: "sellertk"})
+	b := m.List()[0]
+
+	d := bots.NewDispatcher(m, 2, 4)
+	d.Start(t.Context())
+
+	cmd, results := deposit(srv, b.SteamID, "1001")
+	require.NoError(t, d.Submit(cmd))
+
+	r := result(t, results)
+	require.NoError(t, r.res.Err)
+	assert.Equal(t, b.SteamID, r.res.BotSteamID)
+	o, ok := srv.Offer(r.res.TradeOfferID)
+	require.True(t, ok)
+	assert.Equal(t, "code ABC234", o.Message)
+
+	cmd, results = deposit(srv, botSteamID(9), "1002")
+	require.NoError(t, d.Submit(cmd))
+	assert.Error(t, result(t, results).res.Err, "an unknown bot fails the command")
+}
+
+func TestDispatcherRefusesWhenFull(t *testing.T) {
+	srv, _, m := newFleet(t, 1)
+	b := m.List()[0]
+
+	d := bots.NewDispatcher(m, 1, 1)
+	first, _ := deposit(srv, b.SteamID, "1001")
+	second, _ := deposit(srv, b.SteamID, "1002")
+	require.NoError(t, d.Submit(first))
+	assert.ErrorIs(t, d.Submit(second), bots.ErrDispatcherBusy)
+}
+
+func TestDispatcherShutdown(t *testing.T) {
+	srv, _, m := newFleet(t, 1)
+	srv.AddAccount(fakesteam.Account{SteamID: partnerSteamID, Username: "seller", TradeToken: "sellertk"})
+	b := m.List()[0]
+
+	// Hold the bot so the first command waits in the worker and the others
+	// stay queued.
+	release := make(chan struct{})
+	held := make(chan struct{})
+	go m.Do(t.Context(), b, func() error {
+		close(held)
+		<-release
+		return nil
+	})
+	<-held
+
+	ctx, cancel := context.WithCancel(t.Context())
+	d := bots.NewDispatcher(m, 1, 4)
+	d.Start(ctx)
+
+	running, runningRes := deposit(srv, b.SteamID, "1001")
+	require.NoError(t, d.Submit(running))
+	time.Sleep(20 * time.Millisecond)
+	queued, queuedRes := deposit(srv, b.SteamID, "1002")
+	require.NoError(t, d.Submit(queued))
+
+	cancel()
+	close(release)
+	d.Wait()
+
+	r := result(t, runningRes)
+	require.NoError(t, r.res.Err, "a trade being sent is finished")
+	assert.NotEmpty(t, r.res.TradeOfferID)
+	assert.NoError(t, r.ctxErr)
+
+	r = result(t, queuedRes)
+	assert.ErrorIs(t, r.res.Err, bots.ErrDispatcherStopped)
+	assert.NoError(t, r.ctxErr, "the callback can still write its result")
+
+	late, _ := deposit(srv, b.SteamID, "1003")
+	assert.ErrorIs(t, d.Submit(late), bots.ErrDispatcherStopped)
+}
+
+func TestDispatcherRenewsExpiredSession(t *testing.T) {
+	srv := fakesteam.New()
+	t.Cleanup(srv.Close)
+	srv.AddAccount(fakesteam.Account{SteamID: partnerSteamID, Username: "seller", TradeToken: "sellertk"})
+	store := memrepo.NewBots()
+	stored := addBot(srv, store, 0)
+
+	cipher, err := secret.NewCipher(base64.StdEncoding.EncodeToString(make([]byte, 32)))
+	require.NoError(t, err)
+	m := bots.NewBotManager(store, cipher,
+		bot.WithEndpoints(srv.Endpoints()),
+		bot.WithRetryPolicy(bot.RetryPolicy{MaxAttempts: 1}),
+	)
+	m.InitBots(t.Context(), 1)
+	before, err := store.GetSession(t.Context(), stored.SteamID)
+	require.NoError(t, err)
+	loggedIn, _ := statusOf(m, stored.SteamID)
+
+	d := bots.NewDispatcher(m, 1, 1)
+	d.Start(t.Context())
+	srv.FailRequests("/tradeoffer/new/send", 1, http.StatusUnauthorized, nil)
+	cmd, results := deposit(srv, stored.SteamID, "1001")
+	require.NoError(t, d.Submit(cmd))
+
+	r := result(t, results)
+	require.NoError(t, r.res.Err, "the trade is sent again after the session was renewed")
+	assert.Equal(t, 1, srv.Requests(beginAuthPath), "the refresh token is used instead of a password login")
+	assert.Equal(t, 1, srv.Requests("/IAuthenticationService/GenerateAccessTokenForApp/v1/"))
+
+	after, err := store.GetSession(t.Context(), stored.SteamID)
+	require.NoError(t, err)
+	assert.NotEqual(t, before.Session, after.Session, "the renewed session is stored")
+	st, _ := statusOf(m, stored.SteamID)
+	assert.Equal(t, bots.StatusOnline, st.Status)
+	assert.True(t, st.LastLoginAt.After(*loggedIn.LastLoginAt))
+}
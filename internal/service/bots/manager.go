@@ -4,161 +4,282 @@ import (
 	"context"
 	"csTrade/internal/domain/bot"
 	"csTrade/internal/repository"
-	"fmt"
+	"csTrade/internal/secret"
+	"encoding/json"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/rs/zerolog/log"
 )
 
 type BotManager struct {
-	Bots   map[string]*bot.SteamBot
 	Events chan interface{}
 	repo   repository.BotsStore
-}
+	cipher *secret.Cipher
 
-func NewBotManager(repo repository.BotsStore) *BotManager {
-	return &BotManager{
-		Bots:   make(map[string]*bot.SteamBot),
-		Events: make(chan interface{}),
-		repo:   repo,
-	}
-}
+	botOpts []bot.Option
+
+	proxyMu      sync.Mutex
+	spareProxies []string
+
+	apiKeyDomain string
 
-type ReceiveFromUserTrade struct {
-	// OfferID  string
-	// SellerID string
-	AssetID  string
-	TradeURL string
+	statusMu sync.RWMutex
+	states   map[string]*botState
+
+	initDone atomic.Bool
+	ctx      context.Context
+
+	mu       sync.RWMutex
+	bots     map[string]*registeredBot
+	removals map[string]uint64
+	holds    map[string]*Reservation
+	capacity int
+	selector BotSelector
+	loops    []botLoop
 }
 
-type SendToBuyerEventTrade struct {
-	AssetID       string
-	BuyerTradeURL string
-	// TransactionID string
-	BotSteamID uint64
+// NewBotManager keeps bot sessions in the database when cipher is set,
+// with a nil cipher every start logs the bots in from scratch.
+func NewBotManager(repo repository.BotsStore, cipher *secret.Cipher, botOpts ...bot.Option) *BotManager {
+	m := &BotManager{
+		Events:   make(chan interface{}, 256),
+		repo:     repo,
+		cipher:   cipher,
+		states:   make(map[string]*botState),
+		bots:     make(map[string]*registeredBot),
+		removals: make(map[string]uint64),
+		holds:    make(map[string]*Reservation),
+		capacity: DefaultBotCapacity,
+		selector: LeastLoadedSelector{},
+	}
+	m.botOpts = append(botOpts, bot.WithAuthFailureHandler(m.reportAuthFailure))
+	return m
 }
 
-func (m *BotManager) InitBots(ctx context.Context) {
+// InitBots logs every bot in, at most concurrency at a time, and returns
+// once all of them are online or failed. Progress is visible through
+// Readiness while it runs.
+func (m *BotManager) InitBots(ctx context.Context, concurrency int) {
+	defer m.initDone.Store(true)
+	m.mu.Lock()
+	m.ctx = ctx
+	m.mu.Unlock()
+
 	botDB, err := m.repo.GetBots(ctx)
 	if err != nil {
 		log.Error().Err(err).Msg("ERR DB")
 		return
 	}
 
-	for _, b := range botDB {
-		bot := bot.NewSteamClient(&b)
-		log.Info().Str("::", bot.SteamID).Msg("db")
+	clients := make([]*bot.SteamBot, len(botDB))
+	gens := make([]uint64, len(botDB))
+	for i := range botDB {
+		clients[i] = bot.NewSteamClient(&botDB[i], m.botOpts...)
+		gens[i] = m.generation(botDB[i].SteamID)
+		m.setStatus(clients[i], StatusLoggingIn, "starting")
+	}
 
-		if err := bot.Login(); err == nil {
-			m.Bots[bot.SteamID] = bot
-			log.Info().Str("username", bot.Username).Msg("Bot logged in")
-		} else {
-			log.Error().Err(err).Str("username", bot.Username).Msg("Failed to login bot")
-		}
+	sem := make(chan struct{}, max(concurrency, 1))
+	var wg sync.WaitGroup
+	for i, b := range clients {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				m.setStatus(b, StatusFailed, ctx.Err().Error())
+				return
+			}
+			defer func() { <-sem }()
+
+			m.initBot(ctx, b, &botDB[i], gens[i])
+		}()
 	}
+	wg.Wait()
 
-	log.Info().Int("total_bots", len(m.Bots)).Msg("All bots initialized")
+	log.Info().Int("total_bots", len(m.List())).Msg("All bots initialized")
 }
 
-// func (m *BotManager) InitBots(ctx context.Context) {
-// 	botDB, err := m.repo.Bot.GetBots(ctx)
-// 	if err != nil {
-// 		return
-// 	}
-// 	log.Info().Interface("BOT", botDB).Msg("bot from db")
-// 	var wg sync.WaitGroup
-// 	var mu sync.Mutex
-// 	for _, b := range botDB {
-// 		wg.Add(1)
-// 		go func(b repository.Bot) {
-// 			defer wg.Done()
-// 			bot := bot.NewSteamClient(&b)
-// 			log.Info().Interface("BOT NEW", bot).Msg("NEW BOT CLIENTR")
-// 			if err := bot.Login(); err == nil {
-// 				mu.Lock()
-// 				m.Bots[bot.SteamID] = bot
-// 				mu.Unlock()
-// 			} else {
-// 				log.Error().Err(err).Str("username", bot.Username).Msg("Failed to login bot")
-// 			}
-// 		}(b)
-// 	}
-
-// 	log.Info().Msg("add bots")
-// 	wg.Wait()
-// }
-
-// func (bm *BotManager) Start(ctx context.Context) {
-// 	ctx, cancel := context.WithCancel(ctx)
-// 	defer cancel()
-
-// 	bm.InitBots(ctx)
-
-// 	for {
-// 		select {
-// 		case <-ctx.Done():
-// 			close(bm.Events)
-// 			log.Error().Msg("ctx bots manager cancel")
-// 			return
-
-// 		case e := <-bm.Events:
-// 			switch ev := e.(type) {
-// 			case ReceiveFromUserTrade:
-// 				bot := bm.GetEmptierBot()
-// 				if bot == nil {
-// 					log.Error().Msg("No available bots")
-// 					continue
-// 				}
-// 				if err := bot.ReceiveFromUser(ev.AssetID, ev.TradeURL); err != nil {
-// 					log.Error().Err(err).Msg("Err receive from user")
-// 				}
-// 			case SendToBuyerEventTrade:
-// 				bot := bm.GetBotByID(ev.BotSteamID)
-// 				if bot == nil {
-// 					log.Error().Msg("Bot not found")
-// 					continue
-// 				}
-// 				if err := bot.SendToBuyer(ev.AssetID, ev.BuyerTradeURL); err != nil {
-// 					log.Error().Err(err).Msg("Err send to buyer")
-// 				}
-// 			}
-// 		}
-// 	}
-// }
-
-func (m *BotManager) GetBotByID(steamID string) *bot.SteamBot {
-	log.Info().Msg("start bot get")
-	for _, b := range m.Bots {
-		if b.SteamID == steamID {
-			return b
+// initBot logs the bot in and registers it unless it was removed after gen
+// was taken.
+func (m *BotManager) initBot(ctx context.Context, b *bot.SteamBot, stored *repository.Bot, gen uint64) {
+	log.Info().Str("::", b.SteamID).Msg("db")
+	m.checkProxy(ctx, b)
+
+	if m.restoreSession(ctx, b) {
+		if !m.register(b, !stored.Enabled, gen) {
+			return
+		}
+		m.markLogin(b.SteamID)
+		m.setStatus(b, StatusOnline, "session restored")
+		log.Info().Str("username", b.Username).Msg("Bot session restored")
+		m.saveSession(ctx, b)
+		m.setupAPIKey(ctx, b, stored)
+		return
+	}
+
+	// A bot that cannot log in now is registered offline anyway, the
+	// watchdog logs it in with backoff and the api key check sets up its key.
+	if err := b.Login(ctx); err != nil {
+		log.Error().Err(err).Str("username", b.Username).Msg("Failed to login bot")
+		if !m.register(b, !stored.Enabled, gen) {
+			return
 		}
+		m.setStatus(b, StatusOffline, err.Error())
+		m.wakeWatchdog(b.SteamID)
+		return
 	}
-	return nil
+
+	if !m.register(b, !stored.Enabled, gen) {
+		return
+	}
+	m.markLogin(b.SteamID)
+	m.setStatus(b, StatusOnline, "logged in")
+	log.Info().Str("username", b.Username).Msg("Bot logged in")
+	m.saveSession(ctx, b)
+	m.setupAPIKey(ctx, b, stored)
 }
 
-func (m *BotManager) GetEmptierBot() (*bot.SteamBot, error) {
-	if m == nil || len(m.Bots) == 0 {
-		return nil, fmt.Errorf("no available bot")
+func (m *BotManager) restoreSession(ctx context.Context, b *bot.SteamBot) bool {
+	if m.cipher == nil {
+		return false
 	}
 
-	var emptier *bot.SteamBot
-	for _, v := range m.Bots {
+	stored, err := m.repo.GetSession(ctx, b.SteamID)
+	if err != nil {
+		log.Info().Err(err).Str("username", b.Username).Msg("No stored bot session")
+		return false
+	}
+	if stored.RefreshTokenExpiresAt != nil && time.Now().After(*stored.RefreshTokenExpiresAt) {
+		return false
+	}
 
-		if emptier == nil {
-			emptier = v
-		} else {
+	plain, err := m.cipher.Decrypt(stored.Session)
+	if err != nil {
+		log.Error().Err(err).Str("username", b.Username).Msg("Failed to decrypt bot session")
+		return false
+	}
 
-			if v.SkinCount < emptier.SkinCount {
-				emptier = v
-			}
-		}
+	var session bot.Session
+	if err := json.Unmarshal(plain, &session); err != nil {
+		log.Error().Err(err).Str("username", b.Username).Msg("Failed to decode bot session")
+		return false
 	}
 
-	if emptier != nil {
-		log.Info().
-			Str("selected_bot_id", emptier.SteamID).
-			Int("skin_count", emptier.SkinCount).
-			Msg("Selected emptier bot")
+	b.RestoreSession(&session)
+	if err := b.EnsureSession(ctx); err != nil {
+		log.Warn().Err(err).Str("username", b.Username).Msg("Stored bot session could not be renewed")
+		return false
 	}
+	if err := b.ValidateSession(ctx); err != nil {
+		log.Warn().Err(err).Str("username", b.Username).Msg("Stored bot session is no longer valid")
+		return false
+	}
+
+	return true
+}
 
-	return emptier, nil
+func (m *BotManager) saveSession(ctx context.Context, b *bot.SteamBot) {
+	if m.cipher == nil {
+		return
+	}
+
+	session := b.ExportSession()
+	plain, err := json.Marshal(session)
+	if err != nil {
+		log.Error().Err(err).Str("username", b.Username).Msg("Failed to encode bot session")
+		return
+	}
+
+	sealed, err := m.cipher.Encrypt(plain)
+	if err != nil {
+		log.Error().Err(err).Str("username", b.Username).Msg("Failed to encrypt bot session")
+		return
+	}
+
+	err = m.repo.SaveSession(ctx, &repository.BotSession{
+		SteamID:               b.SteamID,
+		Session:               sealed,
+		AccessTokenExpiresAt:  &session.AccessTokenExpiresAt,
+		RefreshTokenExpiresAt: &session.RefreshTokenExpiresAt,
+	})
+	if err != nil {
+		log.Error().Err(err).Str("username", b.Username).Msg("Failed to save bot session")
+	}
+}
+
+// StartPollers polls the trade offers of every bot, including bots added
+// later, until the bot is removed.
+func (m *BotManager) StartPollers(interval, history time.Duration) {
+	n := m.addLoop(func(ctx context.Context, b *bot.SteamBot) {
+		NewTradeOfferPoller(m, b, m.Events, interval, history).Run(ctx)
+	})
+
+	log.Info().Int("bots", n).Dur("interval", interval).Msg("Trade offer pollers started")
+}
+
+func (m *BotManager) StartSessionRenewal(interval time.Duration) {
+	m.addLoop(func(ctx context.Context, b *bot.SteamBot) {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				err := m.Do(ctx, b, func() error { return b.EnsureSession(ctx) })
+				if err != nil {
+					log.Error().Err(err).Str("username", b.Username).Msg("Failed to renew bot session")
+					m.recordError(b.SteamID, err)
+					m.checkProxy(ctx, b)
+					m.wakeWatchdog(b.SteamID)
+					continue
+				}
+				m.saveSession(ctx, b)
+			}
+		}
+	})
+}
+
+func (m *BotManager) StartInventoryRefresh(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			m.RefreshInventories(ctx)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// RefreshInventories writes the real CS item count of every bot back to
+// the bots table so ReserveBot works with fresh numbers.
+func (m *BotManager) RefreshInventories(ctx context.Context) {
+	for _, b := range m.List() {
+		inventory, err := b.GetInventory(ctx, bot.CSAppID, bot.CSContextID)
+		if err != nil {
+			log.Error().Err(err).Str("username", b.Username).Msg("Failed to fetch bot inventory")
+			continue
+		}
+
+		count := len(inventory.Assets)
+		if err := m.repo.UpdateSkinCount(ctx, b.SteamID, count); err != nil {
+			log.Error().Err(err).Str("username", b.Username).Msg("Failed to update bot skin count")
+			continue
+		}
+		m.setSkinCount(b.SteamID, count)
+
+		log.Info().Str("username", b.Username).Int("skin_count", count).Msg("Bot inventory refreshed")
+	}
 }
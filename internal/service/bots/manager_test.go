@@ -0,0 +1,95 @@
+package bots_test
+
+import (
+	"context"
+	"csTrade/internal/domain/bot"
+	"csTrade/internal/domain/bot/fakesteam"
+	"csTrade/internal/repository"
+	"csTrade/internal/repository/memrepo"
+	"csTrade/internal/service/bots"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	sharedSecret   = "cnOgv/KdpLoP6Nbh0GMkXkPXALQ="
+	identitySecret = "aBcdEfGhIjKlMnOpQrStUvWxYz0="
+)
+
+func botSteamID(i int) string {
+	return fmt.Sprintf("7656119800000010%d", i)
+}
+
+// addBot creates bot i on the fake Steam server and in the store.
+func addBot(srv *fakesteam.Server, store *memrepo.Bots, i int) repository.Bot {
+	stored := repository.Bot{
+		SteamID:        botSteamID(i),
+		Username:       fmt.Sprintf("bot%d", i),
+		Password:       "hunter2",
+		SharedSecret:   sharedSecret,
+		IdentitySecret: identitySecret,
+		DeviceID:       "android:0a1b2c3d",
+		Enabled:        true,
+	}
+	srv.AddAccount(fakesteam.Account{
+		SteamID:        stored.SteamID,
+		Username:       stored.Username,
+		Password:       stored.Password,
+		SharedSecret:   sharedSecret,
+		IdentitySecret: identitySecret,
+	})
+	store.CreateBots(context.Background(), &stored)
+	return stored
+}
+
+// newFleet starts a fake Steam with n bots and a manager that logged all of
+// them in.
+func newFleet(t *testing.T, n int) (*fakesteam.Server, *memrepo.Bots, *bots.BotManager) {
+	t.Helper()
+
+	srv := fakesteam.New()
+	t.Cleanup(srv.Close)
+
+	store := memrepo.NewBots()
+	for i := range n {
+		addBot(srv, store, i)
+	}
+
+	m := startManager(t, srv, store)
+	require.Len(t, m.List(), n)
+	return srv, store, m
+}
+
+// startManager logs in every bot of the store. Setup on the manager must go
+// into configure, it runs before the logins.
+func startManager(t *testing.T, srv *fakesteam.Server, store *memrepo.Bots, configure ...func(m *bots.BotManager)) *bots.BotManager {
+	t.Helper()
+
+	m := bots.NewBotManager(store, nil,
+		bot.WithEndpoints(srv.Endpoints()),
+		bot.WithRetryPolicy(bot.RetryPolicy{MaxAttempts: 1}),
+	)
+	for _, fn := range configure {
+		fn(m)
+	}
+	m.InitBots(t.Context(), 4)
+	return m
+}
+
+// eventually fails the test unless cond holds within a second.
+func eventually(t *testing.T, cond func() bool, msg string) {
+	t.Helper()
+	require.Eventually(t, cond, time.Second, 5*time.Millisecond, msg)
+}
+
+func statusOf(m *bots.BotManager, steamID string) (bots.BotStatus, bool) {
+	for _, st := range m.Statuses() {
+		if st.SteamID == steamID {
+			return st, true
+		}
+	}
+	return bots.BotStatus{}, false
+}
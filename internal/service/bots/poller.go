@@ -0,0 +1,221 @@
+package bots
+
+import (
+	"context"
+	"csTrade/internal/domain/bot"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// TradeOfferEvent is emitted into BotManager.Events whenever a polled offer
+// shows up for the first time or changes state.
+type TradeOfferEvent struct {
+	BotSteamID string
+	Offer      bot.TradeOffer
+	PrevState  bot.TradeOfferState
+	Sent       bool
+
+	handled func(err error)
+}
+
+// Handled reports the outcome of the event back to the poller that emitted
+// it. The poller remembers the offer's new state only once it was handled
+// without an error, otherwise the next poll emits it again.
+func (ev TradeOfferEvent) Handled(err error) {
+	if ev.handled != nil {
+		ev.handled(err)
+	}
+}
+
+type TradeOfferPoller struct {
+	m        *BotManager
+	bot      *bot.SteamBot
+	events   chan<- interface{}
+	interval time.Duration
+	history  time.Duration
+
+	mu       sync.Mutex
+	states   map[string]polledOffer
+	pending  map[string]pendingOffer
+	lastPoll time.Time
+}
+
+type polledOffer struct {
+	state  bot.TradeOfferState
+	seenAt time.Time
+}
+
+// pendingOffer is a state change that was emitted but not handled yet, or
+// whose handling failed and is emitted again.
+type pendingOffer struct {
+	state     bot.TradeOfferState
+	seenAt    time.Time
+	updatedAt time.Time
+	inflight  bool
+}
+
+func NewTradeOfferPoller(m *BotManager, b *bot.SteamBot, events chan<- interface{}, interval, history time.Duration) *TradeOfferPoller {
+	return &TradeOfferPoller{
+		m:        m,
+		bot:      b,
+		events:   events,
+		interval: interval,
+		history:  history,
+		states:   make(map[string]polledOffer),
+		pending:  make(map[string]pendingOffer),
+	}
+}
+
+func (p *TradeOfferPoller) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := p.poll(ctx); err != nil {
+			log.Error().Err(err).Str("bot", p.bot.SteamID).Msg("err poll trade offers")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (p *TradeOfferPoller) poll(ctx context.Context) error {
+	now := time.Now()
+	cutoff := now.Add(-p.history)
+	if !p.lastPoll.IsZero() {
+		cutoff = p.lastPoll.Add(-p.interval)
+	}
+	cutoff = p.retryCutoff(cutoff)
+
+	var offers *bot.TradeOffers
+	err := p.m.Do(ctx, p.bot, func() error {
+		var err error
+		offers, err = p.bot.GetTradeOffers(ctx, true, true, cutoff)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	p.lastPoll = now
+
+	for _, o := range offers.Sent {
+		if !p.diff(ctx, o, true, now) {
+			return ctx.Err()
+		}
+	}
+	for _, o := range offers.Received {
+		if !p.diff(ctx, o, false, now) {
+			return ctx.Err()
+		}
+	}
+
+	p.prune(now)
+	return nil
+}
+
+func (p *TradeOfferPoller) diff(ctx context.Context, o bot.TradeOffer, sent bool, now time.Time) bool {
+	p.mu.Lock()
+	prev, seen := p.states[o.TradeOfferID]
+	if seen && prev.state == o.State {
+		p.states[o.TradeOfferID] = polledOffer{state: o.State, seenAt: now}
+		p.mu.Unlock()
+		return true
+	}
+	pend, emitted := p.pending[o.TradeOfferID]
+	if emitted && pend.state == o.State && pend.inflight {
+		p.mu.Unlock()
+		return true
+	}
+	if !emitted || pend.state != o.State {
+		pend = pendingOffer{state: o.State, seenAt: now, updatedAt: now}
+		if o.TimeUpdated > 0 {
+			pend.updatedAt = time.Unix(o.TimeUpdated, 0)
+		}
+	}
+	pend.inflight = true
+	p.pending[o.TradeOfferID] = pend
+	p.mu.Unlock()
+
+	ev := TradeOfferEvent{
+		BotSteamID: p.bot.SteamID,
+		Offer:      o,
+		PrevState:  prev.state,
+		Sent:       sent,
+		handled: func(err error) {
+			p.handled(o.TradeOfferID, o.State, err)
+		},
+	}
+
+	select {
+	case p.events <- ev:
+		return true
+	case <-ctx.Done():
+		p.mu.Lock()
+		delete(p.pending, o.TradeOfferID)
+		p.mu.Unlock()
+		return false
+	}
+}
+
+func (p *TradeOfferPoller) handled(tradeOfferID string, state bot.TradeOfferState, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	pend, ok := p.pending[tradeOfferID]
+	if !ok || pend.state != state {
+		return
+	}
+
+	now := time.Now()
+	if err == nil {
+		delete(p.pending, tradeOfferID)
+		p.states[tradeOfferID] = polledOffer{state: state, seenAt: now}
+		return
+	}
+
+	if now.Sub(pend.seenAt) > p.history {
+		log.Error().Err(err).
+			Str("bot", p.bot.SteamID).
+			Str("trade_offer_id", tradeOfferID).
+			Str("state", state.String()).
+			Msg("giving up on trade offer event")
+		delete(p.pending, tradeOfferID)
+		p.states[tradeOfferID] = polledOffer{state: state, seenAt: now}
+		return
+	}
+	pend.inflight = false
+	p.pending[tradeOfferID] = pend
+}
+
+// retryCutoff moves the cutoff back far enough for Steam to report the
+// offers whose handling failed again, even after they turned final.
+func (p *TradeOfferPoller) retryCutoff(cutoff time.Time) time.Time {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, pend := range p.pending {
+		if from := pend.updatedAt.Add(-p.interval); from.Before(cutoff) {
+			cutoff = from
+		}
+	}
+	return cutoff
+}
+
+// prune drops finished offers once they fall out of the overlapping cutoff
+// window, Steam will not report them again.
+func (p *TradeOfferPoller) prune(now time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for id, polled := range p.states {
+		if polled.state.IsFinal() && now.Sub(polled.seenAt) > 2*p.interval {
+			delete(p.states, id)
+		}
+	}
+}
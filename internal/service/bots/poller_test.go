@@ -0,0 +1,88 @@
+package bots_test
+
+import (
+	"csTrade/internal/domain/bot"
+	"csTrade/internal/domain/bot/fakesteam"
+	"csTrade/internal/service/bots"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const partnerSteamID = "76561198000000999"
+
+func nextEvent(t *testing.T, m *bots.BotManager) bots.TradeOfferEvent {
+	t.Helper()
+	select {
+	case e := <-m.Events:
+		ev, ok := e.(bots.TradeOfferEvent)
+		require.True(t, ok)
+		return ev
+	case <-time.After(time.Second):
+		t.Fatal("no trade offer event")
+		return bots.TradeOfferEvent{}
+	}
+}
+
+func assertNoEvent(t *testing.T, m *bots.BotManager, wait time.Duration) {
+	t.Helper()
+	select {
+	case e := <-m.Events:
+		t.Fatalf("unexpected event %+v", e)
+	case <-time.After(wait):
+	}
+}
+
+func TestPollerEmitsUntilHandled(t *testing.T) {
+	srv, _, m := newFleet(t, 1)
+	srv.AddAccount(fakesteam.Account{SteamID: partnerSteamID, Username: "partner", Password: "x"})
+	b := m.List()[0]
+
+	id := srv.SendOffer(partnerSteamID, b.SteamID, []string{"1"}, nil)
+	m.StartPollers(10*time.Millisecond, time.Hour)
+
+	ev := nextEvent(t, m)
+	assert.Equal(t, id, ev.Offer.TradeOfferID)
+	assert.False(t, ev.Sent)
+	assertNoEvent(t, m, 50*time.Millisecond)
+
+	ev.Handled(errors.New("db down"))
+	ev = nextEvent(t, m)
+	assert.Equal(t, id, ev.Offer.TradeOfferID)
+	assert.Equal(t, bot.TradeOfferStateActive, ev.Offer.State)
+
+	ev.Handled(nil)
+	assertNoEvent(t, m, 50*time.Millisecond)
+
+	srv.SetOfferState(id, bot.TradeOfferStateDeclined)
+	ev = nextEvent(t, m)
+	assert.Equal(t, bot.TradeOfferStateDeclined, ev.Offer.State)
+	assert.Equal(t, bot.TradeOfferStateActive, ev.PrevState)
+	ev.Handled(nil)
+	m.RemoveBot(b.SteamID)
+}
+
+func TestPollerRetriesFinalOfferPastTheCutoff(t *testing.T) {
+	srv, _, m := newFleet(t, 1)
+	srv.AddAccount(fakesteam.Account{SteamID: partnerSteamID, Username: "partner", Password: "x"})
+	b := m.List()[0]
+
+	id := srv.SendOffer(partnerSteamID, b.SteamID, []string{"1"}, nil)
+	srv.SetTimeOffset(-time.Minute)
+	srv.SetOfferState(id, bot.TradeOfferStateDeclined)
+	srv.SetTimeOffset(0)
+
+	m.StartPollers(10*time.Millisecond, time.Hour)
+	ev := nextEvent(t, m)
+	require.Equal(t, id, ev.Offer.TradeOfferID)
+	ev.Handled(errors.New("db down"))
+
+	ev = nextEvent(t, m)
+	assert.Equal(t, id, ev.Offer.TradeOfferID, "the failed offer is reported again although it settled before the cutoff")
+	ev.Handled(nil)
+	assertNoEvent(t, m, 50*time.Millisecond)
+	m.RemoveBot(b.SteamID)
+}
@@ -0,0 +1,76 @@
+package bots
+
+import (
+	"context"
+	"csTrade/internal/domain/bot"
+	"fmt"
+
+	"github.com/rs/zerolog/log"
+)
+
+// SetSpareProxies registers proxies a bot can be moved to when its own
+// proxy stops working.
+func (m *BotManager) SetSpareProxies(proxies []string) {
+	m.proxyMu.Lock()
+	defer m.proxyMu.Unlock()
+
+	m.spareProxies = m.spareProxies[:0]
+	for _, p := range proxies {
+		if _, err := bot.ParseProxyURL(p); err != nil {
+			log.Error().Err(err).Msg("Skipping spare proxy")
+			continue
+		}
+		m.spareProxies = append(m.spareProxies, p)
+	}
+}
+
+// checkProxy runs the startup health check and moves the bot to a spare
+// proxy when its own one does not answer.
+func (m *BotManager) checkProxy(ctx context.Context, b *bot.SteamBot) {
+	current := b.ProxyURL()
+	if current == "" {
+		return
+	}
+
+	err := b.CheckProxy(ctx, current)
+	if err == nil {
+		return
+	}
+
+	log.Warn().Err(err).Str("username", b.Username).Msg("Bot proxy failed health check")
+	if err := m.SwitchProxy(ctx, b); err != nil {
+		log.Error().Err(err).Str("username", b.Username).Msg("Failed to switch bot proxy")
+	}
+}
+
+// SwitchProxy moves the bot to the first healthy spare proxy and stores it.
+// The old proxy goes to the back of the spare list.
+func (m *BotManager) SwitchProxy(ctx context.Context, b *bot.SteamBot) error {
+	m.proxyMu.Lock()
+	defer m.proxyMu.Unlock()
+
+	old := b.ProxyURL()
+	for i, candidate := range m.spareProxies {
+		if err := b.CheckProxy(ctx, candidate); err != nil {
+			log.Warn().Err(err).Msg("Spare proxy failed health check")
+			continue
+		}
+
+		if err := b.SetProxy(candidate); err != nil {
+			return err
+		}
+		if err := m.repo.UpdateProxy(ctx, b.SteamID, &candidate); err != nil {
+			return err
+		}
+
+		m.spareProxies = append(m.spareProxies[:i:i], m.spareProxies[i+1:]...)
+		if old != "" && old != candidate {
+			m.spareProxies = append(m.spareProxies, old)
+		}
+
+		log.Info().Str("username", b.Username).Msg("Bot moved to spare proxy")
+		return nil
+	}
+
+	return fmt.Errorf("no healthy spare proxy for bot %s", b.SteamID)
+}
@@ -0,0 +1,37 @@
+package bots
+
+// Readiness counts bots by status. The service is ready once startup logins
+// are over and at least one bot can trade.
+type Readiness struct {
+	Ready        bool `json:"ready"`
+	Starting     bool `json:"starting"`
+	Total        int  `json:"total"`
+	Online       int  `json:"online"`
+	LoggingIn    int  `json:"logging_in"`
+	Reconnecting int  `json:"reconnecting"`
+	Offline      int  `json:"offline"`
+	Failed       int  `json:"failed"`
+}
+
+func (m *BotManager) Readiness() Readiness {
+	m.statusMu.RLock()
+	defer m.statusMu.RUnlock()
+
+	r := Readiness{Starting: !m.initDone.Load(), Total: len(m.states)}
+	for _, st := range m.states {
+		switch st.status {
+		case StatusOnline:
+			r.Online++
+		case StatusLoggingIn:
+			r.LoggingIn++
+		case StatusReconnecting:
+			r.Reconnecting++
+		case StatusOffline:
+			r.Offline++
+		case StatusFailed:
+			r.Failed++
+		}
+	}
+	r.Ready = !r.Starting && r.Online > 0
+	return r
+}
@@ -0,0 +1,334 @@
+package bots
+
+import (
+	"context"
+	"csTrade/internal/domain/bot"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// DefaultBotCapacity is how many items a bot takes in before it is no longer
+// chosen for deposits.
+const DefaultBotCapacity = 1000
+
+// recentFailureWindow is how far back lost sessions count against a bot.
+const recentFailureWindow = time.Hour
+
+type registeredBot struct {
+	bot       *bot.SteamBot
+	skinCount int
+	reserved  int
+	disabled  bool
+	queue     chan struct{}
+	ctx       context.Context
+	cancel    context.CancelFunc
+}
+
+// botLoop is a background job every registered bot runs, like its trade
+// offer poller. It stops when ctx ends, which happens when the bot is
+// removed.
+type botLoop func(ctx context.Context, b *bot.SteamBot)
+
+// Reservation holds inventory space on a bot for the items of a deposit
+// until its trade offer either fails or is settled.
+type Reservation struct {
+	m       *BotManager
+	steamID string
+	items   int
+	once    sync.Once
+}
+
+// register makes the bot available and starts its loops. A bot registered
+// again replaces the old client and its loops. It refuses a bot removed
+// since gen was taken, its login finished too late.
+func (m *BotManager) register(b *bot.SteamBot, disabled bool, gen uint64) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.removals[b.SteamID] != gen {
+		log.Info().Str("bot", b.SteamID).Msg("Bot removed while logging in, not registered")
+		return false
+	}
+
+	if old, ok := m.bots[b.SteamID]; ok {
+		old.cancel()
+	}
+
+	ctx, cancel := context.WithCancel(m.baseCtx())
+	m.bots[b.SteamID] = &registeredBot{
+		bot:       b,
+		skinCount: b.SkinCount,
+		disabled:  disabled,
+		queue:     make(chan struct{}, 1),
+		ctx:       ctx,
+		cancel:    cancel,
+	}
+	for _, loop := range m.loops {
+		go loop(ctx, b)
+	}
+	return true
+}
+
+// unregister stops the bot and counts a removal, so logins started before
+// it cannot register the bot again.
+func (m *BotManager) unregister(steamID string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.removals[steamID]++
+	rb, ok := m.bots[steamID]
+	if !ok {
+		return false
+	}
+	rb.cancel()
+	delete(m.bots, steamID)
+	return true
+}
+
+// generation is the number of removals of the bot, taken before a login
+// and passed to register.
+func (m *BotManager) generation(steamID string) uint64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.removals[steamID]
+}
+
+// current reports whether b is still the registered client of its bot.
+func (m *BotManager) current(b *bot.SteamBot) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	rb, ok := m.bots[b.SteamID]
+	return ok && rb.bot == b
+}
+
+// addLoop runs loop for every registered bot and for bots registered later.
+// It returns how many bots it started on.
+func (m *BotManager) addLoop(loop botLoop) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.loops = append(m.loops, loop)
+	for _, rb := range m.bots {
+		go loop(rb.ctx, rb.bot)
+	}
+	return len(m.bots)
+}
+
+func (m *BotManager) baseCtx() context.Context {
+	if m.ctx == nil {
+		return context.Background()
+	}
+	return m.ctx
+}
+
+// SetCapacity sets how many items a bot inventory may hold.
+func (m *BotManager) SetCapacity(capacity int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.capacity = capacity
+}
+
+// List returns the registered bots.
+func (m *BotManager) List() []*bot.SteamBot {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	out := make([]*bot.SteamBot, 0, len(m.bots))
+	for _, rb := range m.bots {
+		out = append(out, rb.bot)
+	}
+	return out
+}
+
+func (m *BotManager) GetBotByID(steamID string) *bot.SteamBot {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if rb, ok := m.bots[steamID]; ok {
+		return rb.bot
+	}
+	return nil
+}
+
+// GetOnlineBot returns a bot that is online, not frozen and in rotation,
+// for lookups that do not trade, like reading a user's inventory.
+func (m *BotManager) GetOnlineBot() (*bot.SteamBot, error) {
+	if m == nil {
+		return nil, ErrNoEligibleBot
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for steamID, rb := range m.bots {
+		if _, frozen := rb.bot.Frozen(); frozen || rb.disabled || !m.IsOnline(steamID) {
+			continue
+		}
+		return rb.bot, nil
+	}
+	return nil, ErrNoEligibleBot
+}
+
+func (m *BotManager) setSkinCount(steamID string, count int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if rb, ok := m.bots[steamID]; ok {
+		rb.skinCount = count
+	}
+}
+
+// RemoveItems lowers the bot's item count after a delivery left its
+// inventory.
+func (m *BotManager) RemoveItems(steamID string, items int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if rb, ok := m.bots[steamID]; ok {
+		rb.skinCount = max(rb.skinCount-items, 0)
+	}
+}
+
+// SetSelector sets the strategy ReserveBot picks bots with.
+func (m *BotManager) SetSelector(selector BotSelector) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.selector = selector
+}
+
+// ReserveBot lets the selector pick a bot for a deposit of items and
+// reserves room for them on it. Choosing and reserving happen under one
+// lock so parallel deposits see each other's reservations.
+func (m *BotManager) ReserveBot(items int) (*bot.SteamBot, *Reservation, error) {
+	if m == nil {
+		return nil, nil, ErrNoEligibleBot
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	pending := make(map[string]int)
+	for _, r := range m.holds {
+		pending[r.steamID]++
+	}
+
+	candidates := make([]Candidate, 0, len(m.bots))
+	for steamID, rb := range m.bots {
+		_, frozen := rb.bot.Frozen()
+		candidates = append(candidates, Candidate{
+			SteamID:        steamID,
+			Online:         m.IsOnline(steamID),
+			Frozen:         frozen,
+			Disabled:       rb.disabled,
+			SkinCount:      rb.skinCount,
+			Reserved:       rb.reserved,
+			PendingTrades:  pending[steamID],
+			Capacity:       m.capacity,
+			Proxy:          rb.bot.ProxyURL(),
+			RecentFailures: m.recentFailures(steamID, time.Now().Add(-recentFailureWindow)),
+		})
+	}
+
+	sel, err := m.selector.Select(candidates, items)
+	logger := log.Info()
+	if err != nil {
+		logger = log.Warn().Err(err)
+	}
+	logger.
+		Str("selector", m.selector.Name()).
+		Int("items", items).
+		Str("selected_bot_id", sel.SteamID).
+		Str("reason", sel.Reason).
+		Any("skipped", sel.Skipped).
+		Msg("Bot selection")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rb, ok := m.bots[sel.SteamID]
+	if !ok {
+		return nil, nil, fmt.Errorf("selector %s picked unknown bot %s", m.selector.Name(), sel.SteamID)
+	}
+	rb.reserved += items
+
+	return rb.bot, &Reservation{m: m, steamID: sel.SteamID, items: items}, nil
+}
+
+// Release gives the reserved space back. It is safe to call more than once
+// and on a nil reservation.
+func (r *Reservation) Release() {
+	if r == nil {
+		return
+	}
+	r.once.Do(func() {
+		r.m.mu.Lock()
+		defer r.m.mu.Unlock()
+
+		if rb, ok := r.m.bots[r.steamID]; ok {
+			rb.reserved = max(rb.reserved-r.items, 0)
+		}
+	})
+}
+
+// Hold keeps the space reserved until the trade offer is settled.
+func (r *Reservation) Hold(tradeOfferID string) {
+	if r == nil {
+		return
+	}
+	r.m.mu.Lock()
+	defer r.m.mu.Unlock()
+	r.m.holds[tradeOfferID] = r
+}
+
+// MoveReservation keeps the space of a deposit reserved when its items
+// arrive through another trade offer.
+func (m *BotManager) MoveReservation(fromTradeOfferID, toTradeOfferID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if r, ok := m.holds[fromTradeOfferID]; ok {
+		delete(m.holds, fromTradeOfferID)
+		m.holds[toTradeOfferID] = r
+	}
+}
+
+// SettleDeposit releases the space held for a finished deposit trade offer.
+// Accepted items now count toward the bot inventory.
+func (m *BotManager) SettleDeposit(tradeOfferID string, accepted bool) {
+	m.mu.Lock()
+	r, ok := m.holds[tradeOfferID]
+	delete(m.holds, tradeOfferID)
+	if ok && accepted {
+		if rb, ok := m.bots[r.steamID]; ok {
+			rb.skinCount += r.items
+		}
+	}
+	m.mu.Unlock()
+
+	if ok {
+		r.Release()
+	}
+}
+
+// Do runs fn with exclusive use of the bot. Steam sessions do not cope with
+// parallel trade sends, so callers queue up and run one at a time.
+func (m *BotManager) Do(ctx context.Context, b *bot.SteamBot, fn func() error) error {
+	m.mu.RLock()
+	rb, ok := m.bots[b.SteamID]
+	m.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("bot %s is not registered", b.SteamID)
+	}
+
+	select {
+	case rb.queue <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	defer func() { <-rb.queue }()
+
+	return fn()
+}
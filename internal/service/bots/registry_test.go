@@ -0,0 +1,178 @@
+package bots_test
+
+import (
+	"context"
+	"csTrade/internal/domain/bot/fakesteam"
+	"csTrade/internal/repository/memrepo"
+	"csTrade/internal/service/bots"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReserveBotCountsReservations(t *testing.T) {
+	_, _, m := newFleet(t, 2)
+	m.SetCapacity(3)
+
+	first, r1, err := m.ReserveBot(2)
+	require.NoError(t, err)
+	second, r2, err := m.ReserveBot(2)
+	require.NoError(t, err)
+	assert.NotEqual(t, first.SteamID, second.SteamID, "the reserved space makes the first bot the fuller one")
+
+	_, _, err = m.ReserveBot(2)
+	assert.ErrorIs(t, err, bots.ErrNoEligibleBot)
+
+	r1.Release()
+	r1.Release()
+	again, r3, err := m.ReserveBot(2)
+	require.NoError(t, err)
+	assert.Equal(t, first.SteamID, again.SteamID)
+
+	st, _ := statusOf(m, first.SteamID)
+	assert.Equal(t, 2, st.Reserved, "a second Release gives nothing back")
+
+	r2.Release()
+	r3.Release()
+}
+
+func TestGetOnlineBot(t *testing.T) {
+	srv := fakesteam.New()
+	t.Cleanup(srv.Close)
+	store := memrepo.NewBots()
+	stored := addBot(srv, store, 0)
+	srv.FailRequests(beginAuthPath, 1, http.StatusBadGateway, nil)
+
+	m := startManager(t, srv, store)
+	_, err := m.GetOnlineBot()
+	assert.ErrorIs(t, err, bots.ErrNoEligibleBot, "the bot that failed to log in is skipped")
+
+	require.NoError(t, m.Relogin(t.Context(), stored.SteamID))
+	eventually(t, func() bool { return m.IsOnline(stored.SteamID) }, "the bot logs in again")
+	b, err := m.GetOnlineBot()
+	require.NoError(t, err)
+	assert.Equal(t, stored.SteamID, b.SteamID)
+
+	require.NoError(t, m.SetEnabled(stored.SteamID, false))
+	_, err = m.GetOnlineBot()
+	assert.ErrorIs(t, err, bots.ErrNoEligibleBot, "a disabled bot is skipped")
+	m.RemoveBot(stored.SteamID)
+}
+
+func TestSettleDeposit(t *testing.T) {
+	_, _, m := newFleet(t, 1)
+	m.SetCapacity(10)
+
+	b, accepted, err := m.ReserveBot(3)
+	require.NoError(t, err)
+	accepted.Hold("offer-1")
+	m.MoveReservation("offer-1", "offer-2")
+
+	_, declined, err := m.ReserveBot(2)
+	require.NoError(t, err)
+	declined.Hold("offer-3")
+
+	st, _ := statusOf(m, b.SteamID)
+	assert.Equal(t, 5, st.Reserved)
+
+	m.SettleDeposit("offer-1", true)
+	st, _ = statusOf(m, b.SteamID)
+	assert.Equal(t, 5, st.Reserved, "the reservation moved to offer-2")
+
+	m.SettleDeposit("offer-2", true)
+	m.SettleDeposit("offer-3", false)
+	m.SettleDeposit("offer-3", true)
+
+	st, _ = statusOf(m, b.SteamID)
+	assert.Equal(t, 0, st.Reserved)
+	assert.Equal(t, 3, st.SkinCount, "only the accepted deposit fills the inventory")
+}
+
+func TestDoRunsOneAtATime(t *testing.T) {
+	_, _, m := newFleet(t, 1)
+	b := m.List()[0]
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	var running, maxRunning atomic.Int32
+	var order []string
+
+	track := func(name string, wait bool) func() error {
+		return func() error {
+			if n := running.Add(1); n > maxRunning.Load() {
+				maxRunning.Store(n)
+			}
+			defer running.Add(-1)
+			order = append(order, name)
+			if wait {
+				close(started)
+				<-release
+			}
+			return nil
+		}
+	}
+
+	done := make(chan error, 2)
+	go func() { done <- m.Do(t.Context(), b, track("first", true)) }()
+	<-started
+	go func() { done <- m.Do(t.Context(), b, track("second", false)) }()
+
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, []string{"first"}, order, "second waits while first holds the bot")
+
+	close(release)
+	require.NoError(t, <-done)
+	require.NoError(t, <-done)
+	assert.Equal(t, []string{"first", "second"}, order)
+	assert.EqualValues(t, 1, maxRunning.Load())
+}
+
+func TestDoGivesUpWhenCancelled(t *testing.T) {
+	_, _, m := newFleet(t, 1)
+	b := m.List()[0]
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	go m.Do(t.Context(), b, func() error {
+		close(started)
+		<-release
+		return nil
+	})
+	<-started
+
+	ctx, cancel := context.WithTimeout(t.Context(), 20*time.Millisecond)
+	defer cancel()
+	ran := false
+	err := m.Do(ctx, b, func() error {
+		ran = true
+		return nil
+	})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.False(t, ran)
+
+	close(release)
+	require.NoError(t, m.Do(t.Context(), b, func() error { return nil }), "the bot is free again")
+
+	m.RemoveBot(b.SteamID)
+	assert.Error(t, m.Do(t.Context(), b, func() error { return nil }))
+}
+
+func TestLoopsAndRenewalShareTheBot(t *testing.T) {
+	_, _, m := newFleet(t, 1)
+	b := m.List()[0]
+
+	m.StartPollers(time.Millisecond, time.Minute)
+	m.StartWatchdog(time.Millisecond, bots.WatchdogBackoff{Base: time.Millisecond, Max: time.Millisecond})
+	for range 10 {
+		require.NoError(t, m.Do(t.Context(), b, func() error { return b.RenewAccessToken(t.Context()) }))
+		m.CheckAPIKey(t.Context(), b)
+	}
+
+	st, _ := statusOf(m, b.SteamID)
+	assert.Equal(t, bots.StatusOnline, st.Status)
+	m.RemoveBot(b.SteamID)
+}
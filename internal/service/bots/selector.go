@@ -0,0 +1,273 @@
+package bots
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"sync"
+)
+
+var ErrNoEligibleBot = errors.New("no available bot")
+
+// Candidate is what a selector knows about a bot when a deposit needs one.
+type Candidate struct {
+	SteamID   string
+	Online    bool
+	Frozen    bool
+	Disabled  bool
+	SkinCount int
+	// Reserved is the space held for deposits that have not arrived yet.
+	Reserved int
+	// PendingTrades counts deposit trade offers still waiting on the user.
+	PendingTrades int
+	Capacity      int
+	Proxy         string
+	// RecentFailures counts how often the bot went offline lately.
+	RecentFailures int
+}
+
+func (c Candidate) Load() int {
+	return c.SkinCount + c.Reserved
+}
+
+func (c Candidate) Free() int {
+	return c.Capacity - c.Load()
+}
+
+// Selection is the chosen bot and why, with the reason every other bot was
+// passed over.
+type Selection struct {
+	SteamID string
+	Reason  string
+	Skipped map[string]string
+}
+
+// BotSelector picks the bot that takes a deposit of items.
+type BotSelector interface {
+	Name() string
+	Select(candidates []Candidate, items int) (Selection, error)
+}
+
+const (
+	SelectorLeastLoaded      = "least_loaded"
+	SelectorRoundRobin       = "round_robin"
+	SelectorWeightedCapacity = "weighted_capacity"
+	SelectorHealthAware      = "health_aware"
+)
+
+// NewSelector returns the selector configured by name, "" is least loaded.
+func NewSelector(name string) (BotSelector, error) {
+	switch name {
+	case "", SelectorLeastLoaded:
+		return LeastLoadedSelector{}, nil
+	case SelectorRoundRobin:
+		return &RoundRobinSelector{}, nil
+	case SelectorWeightedCapacity:
+		return NewWeightedCapacitySelector(rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64()))), nil
+	case SelectorHealthAware:
+		return HealthAwareSelector{}, nil
+	}
+	return nil, fmt.Errorf("unknown bot selector %q", name)
+}
+
+// eligible drops bots that cannot take the deposit at all: disabled,
+// offline, frozen or without room for the items below the inventory cap.
+func eligible(candidates []Candidate, items int) ([]Candidate, map[string]string) {
+	ok := make([]Candidate, 0, len(candidates))
+	skipped := make(map[string]string)
+	for _, c := range candidates {
+		switch {
+		case c.Disabled:
+			skipped[c.SteamID] = "disabled"
+		case c.Frozen:
+			skipped[c.SteamID] = "frozen"
+		case !c.Online:
+			skipped[c.SteamID] = "offline"
+		case c.Free() < items:
+			skipped[c.SteamID] = fmt.Sprintf("only %d of %d slots free", max(c.Free(), 0), items)
+		default:
+			ok = append(ok, c)
+		}
+	}
+	slices.SortFunc(ok, func(a, b Candidate) int { return strings.Compare(a.SteamID, b.SteamID) })
+	return ok, skipped
+}
+
+func noEligible(skipped map[string]string) (Selection, error) {
+	return Selection{Skipped: skipped}, ErrNoEligibleBot
+}
+
+// LeastLoadedSelector picks the bot with the fewest items, counting reserved
+// space, then the one with fewer pending trades.
+type LeastLoadedSelector struct{}
+
+func (LeastLoadedSelector) Name() string { return SelectorLeastLoaded }
+
+func (LeastLoadedSelector) Select(candidates []Candidate, items int) (Selection, error) {
+	ok, skipped := eligible(candidates, items)
+	if len(ok) == 0 {
+		return noEligible(skipped)
+	}
+
+	best := ok[0]
+	for _, c := range ok[1:] {
+		if c.Load() < best.Load() || c.Load() == best.Load() && c.PendingTrades < best.PendingTrades {
+			best = c
+		}
+	}
+	for _, c := range ok {
+		if c.SteamID != best.SteamID {
+			skipped[c.SteamID] = fmt.Sprintf("load %d, pending %d", c.Load(), c.PendingTrades)
+		}
+	}
+
+	return Selection{
+		SteamID: best.SteamID,
+		Reason:  fmt.Sprintf("least loaded: load %d, pending %d", best.Load(), best.PendingTrades),
+		Skipped: skipped,
+	}, nil
+}
+
+// RoundRobinSelector hands deposits to eligible bots in turn.
+type RoundRobinSelector struct {
+	mu   sync.Mutex
+	last string
+}
+
+func (*RoundRobinSelector) Name() string { return SelectorRoundRobin }
+
+func (s *RoundRobinSelector) Select(candidates []Candidate, items int) (Selection, error) {
+	ok, skipped := eligible(candidates, items)
+	if len(ok) == 0 {
+		return noEligible(skipped)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Continue after the last pick by steam ID, so bots joining or leaving
+	// the eligible set do not reset the rotation.
+	next := ok[0]
+	for _, c := range ok {
+		if c.SteamID > s.last {
+			next = c
+			break
+		}
+	}
+	s.last = next.SteamID
+
+	for _, c := range ok {
+		if c.SteamID != next.SteamID {
+			skipped[c.SteamID] = "not its turn"
+		}
+	}
+
+	return Selection{SteamID: next.SteamID, Reason: "round robin turn", Skipped: skipped}, nil
+}
+
+// WeightedCapacitySelector picks a bot at random, weighted by its free
+// inventory space, so emptier bots fill faster without all deposits going
+// to the same one.
+type WeightedCapacitySelector struct {
+	mu  sync.Mutex
+	rnd *rand.Rand
+}
+
+func NewWeightedCapacitySelector(rnd *rand.Rand) *WeightedCapacitySelector {
+	return &WeightedCapacitySelector{rnd: rnd}
+}
+
+func (*WeightedCapacitySelector) Name() string { return SelectorWeightedCapacity }
+
+func (s *WeightedCapacitySelector) Select(candidates []Candidate, items int) (Selection, error) {
+	ok, skipped := eligible(candidates, items)
+	if len(ok) == 0 {
+		return noEligible(skipped)
+	}
+
+	total := 0
+	for _, c := range ok {
+		total += c.Free()
+	}
+
+	s.mu.Lock()
+	pick := s.rnd.IntN(total)
+	s.mu.Unlock()
+
+	var chosen Candidate
+	for _, c := range ok {
+		if pick < c.Free() {
+			chosen = c
+			break
+		}
+		pick -= c.Free()
+	}
+	for _, c := range ok {
+		if c.SteamID != chosen.SteamID {
+			skipped[c.SteamID] = fmt.Sprintf("not drawn, weight %d/%d", c.Free(), total)
+		}
+	}
+
+	return Selection{
+		SteamID: chosen.SteamID,
+		Reason:  fmt.Sprintf("drawn with weight %d/%d", chosen.Free(), total),
+		Skipped: skipped,
+	}, nil
+}
+
+// HealthAwareSelector scores bots by how full they are, how many trades
+// they have pending, how often they lost their session lately and how many
+// other bots share their proxy. The lowest score wins.
+type HealthAwareSelector struct{}
+
+func (HealthAwareSelector) Name() string { return SelectorHealthAware }
+
+const (
+	pendingTradePenalty  = 0.05
+	recentFailurePenalty = 0.25
+	sharedProxyPenalty   = 0.1
+)
+
+func (HealthAwareSelector) Select(candidates []Candidate, items int) (Selection, error) {
+	ok, skipped := eligible(candidates, items)
+	if len(ok) == 0 {
+		return noEligible(skipped)
+	}
+
+	proxies := make(map[string]int)
+	for _, c := range ok {
+		if c.Proxy != "" {
+			proxies[c.Proxy]++
+		}
+	}
+
+	score := func(c Candidate) (float64, string) {
+		fill := float64(c.Load()) / float64(max(c.Capacity, 1))
+		shared := 0
+		if c.Proxy != "" {
+			shared = proxies[c.Proxy] - 1
+		}
+		s := fill +
+			pendingTradePenalty*float64(c.PendingTrades) +
+			recentFailurePenalty*float64(c.RecentFailures) +
+			sharedProxyPenalty*float64(shared)
+		return s, fmt.Sprintf("score %.2f: %.0f%% full, %d pending, %d recent failures, proxy shared with %d",
+			s, fill*100, c.PendingTrades, c.RecentFailures, shared)
+	}
+
+	best := ok[0]
+	bestScore, bestReason := score(best)
+	for _, c := range ok[1:] {
+		s, reason := score(c)
+		if s < bestScore {
+			skipped[best.SteamID] = bestReason
+			best, bestScore, bestReason = c, s, reason
+		} else {
+			skipped[c.SteamID] = reason
+		}
+	}
+
+	return Selection{SteamID: best.SteamID, Reason: bestReason, Skipped: skipped}, nil
+}
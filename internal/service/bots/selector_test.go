@@ -0,0 +1,152 @@
+package bots_test
+
+import (
+	"csTrade/internal/service/bots"
+	"fmt"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fleet builds n online bots with room for 100 items each and lets the test
+// adjust them.
+func fleet(n int, adjust func(i int, c *bots.Candidate)) []bots.Candidate {
+	out := make([]bots.Candidate, n)
+	for i := range out {
+		out[i] = bots.Candidate{
+			SteamID:  fmt.Sprintf("7656119800000000%d", i),
+			Online:   true,
+			Capacity: 100,
+		}
+		if adjust != nil {
+			adjust(i, &out[i])
+		}
+	}
+	return out
+}
+
+func TestSelectorsSkipIneligibleBots(t *testing.T) {
+	candidates := fleet(5, func(i int, c *bots.Candidate) {
+		switch i {
+		case 0:
+			c.Online = false
+		case 1:
+			c.Frozen = true
+		case 2:
+			c.SkinCount = 99
+		case 3:
+			c.SkinCount = 50
+		case 4:
+			c.Disabled = true
+		}
+	})
+
+	for _, name := range []string{bots.SelectorLeastLoaded, bots.SelectorRoundRobin, bots.SelectorWeightedCapacity, bots.SelectorHealthAware} {
+		t.Run(name, func(t *testing.T) {
+			selector, err := bots.NewSelector(name)
+			require.NoError(t, err)
+
+			sel, err := selector.Select(candidates, 2)
+			require.NoError(t, err)
+			assert.Equal(t, candidates[3].SteamID, sel.SteamID)
+			assert.NotEmpty(t, sel.Reason)
+			assert.Equal(t, "offline", sel.Skipped[candidates[0].SteamID])
+			assert.Equal(t, "frozen", sel.Skipped[candidates[1].SteamID])
+			assert.Equal(t, "only 1 of 2 slots free", sel.Skipped[candidates[2].SteamID])
+			assert.Equal(t, "disabled", sel.Skipped[candidates[4].SteamID])
+
+			_, err = selector.Select(candidates, 60)
+			assert.ErrorIs(t, err, bots.ErrNoEligibleBot)
+		})
+	}
+}
+
+func TestLeastLoadedSelector(t *testing.T) {
+	candidates := fleet(3, func(i int, c *bots.Candidate) {
+		c.SkinCount = 10
+		c.PendingTrades = 3 - i
+	})
+	candidates[0].Reserved = 5
+
+	sel, err := bots.LeastLoadedSelector{}.Select(candidates, 1)
+	require.NoError(t, err)
+	assert.Equal(t, candidates[2].SteamID, sel.SteamID, "ties on load go to fewer pending trades")
+	assert.Equal(t, "load 15, pending 3", sel.Skipped[candidates[0].SteamID])
+}
+
+func TestRoundRobinSelector(t *testing.T) {
+	candidates := fleet(3, nil)
+	selector := &bots.RoundRobinSelector{}
+
+	var picked []string
+	for range 4 {
+		sel, err := selector.Select(candidates, 1)
+		require.NoError(t, err)
+		picked = append(picked, sel.SteamID)
+	}
+	assert.Equal(t, []string{
+		candidates[0].SteamID, candidates[1].SteamID, candidates[2].SteamID, candidates[0].SteamID,
+	}, picked)
+
+	candidates[1].Online = false
+	sel, err := selector.Select(candidates, 1)
+	require.NoError(t, err)
+	assert.Equal(t, candidates[2].SteamID, sel.SteamID, "rotation skips the offline bot")
+}
+
+func TestWeightedCapacitySelector(t *testing.T) {
+	candidates := fleet(2, func(i int, c *bots.Candidate) {
+		if i == 0 {
+			c.SkinCount = 90
+		}
+	})
+	selector := bots.NewWeightedCapacitySelector(rand.New(rand.NewPCG(1, 2)))
+
+	counts := make(map[string]int)
+	for range 1000 {
+		sel, err := selector.Select(candidates, 1)
+		require.NoError(t, err)
+		counts[sel.SteamID]++
+	}
+
+	// Free space is 10 against 100, so roughly one deposit in eleven goes
+	// to the fuller bot.
+	assert.InDelta(t, 91, counts[candidates[0].SteamID], 40)
+	assert.Greater(t, counts[candidates[1].SteamID], 800)
+}
+
+func TestHealthAwareSelector(t *testing.T) {
+	candidates := fleet(4, func(i int, c *bots.Candidate) {
+		c.SkinCount = 20
+		c.Proxy = fmt.Sprintf("http://proxy-%d:8080", i)
+	})
+	// Emptiest bot but it keeps losing its session.
+	candidates[0].SkinCount = 0
+	candidates[0].RecentFailures = 2
+	// Shares a proxy with the next bot.
+	candidates[1].Proxy = candidates[2].Proxy
+	// One deposit still waiting on its user.
+	candidates[3].PendingTrades = 1
+
+	sel, err := bots.HealthAwareSelector{}.Select(candidates, 1)
+	require.NoError(t, err)
+	assert.Equal(t, candidates[3].SteamID, sel.SteamID)
+	assert.Contains(t, sel.Skipped[candidates[0].SteamID], "2 recent failures")
+	assert.Contains(t, sel.Skipped[candidates[1].SteamID], "proxy shared with 1")
+
+	candidates[3].PendingTrades = 10
+	sel, err = bots.HealthAwareSelector{}.Select(candidates, 1)
+	require.NoError(t, err)
+	assert.Equal(t, candidates[1].SteamID, sel.SteamID)
+}
+
+func TestNewSelectorRejectsUnknownName(t *testing.T) {
+	_, err := bots.NewSelector("fastest")
+	assert.Error(t, err)
+
+	selector, err := bots.NewSelector("")
+	require.NoError(t, err)
+	assert.Equal(t, bots.SelectorLeastLoaded, selector.Name())
+}
@@ -0,0 +1,279 @@
+package bots
+
+import (
+	"context"
+	"csTrade/internal/domain/bot"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+type Status string
+
+const (
+	StatusOnline       Status = "online"
+	StatusOffline      Status = "offline"
+	StatusReconnecting Status = "reconnecting"
+	StatusLoggingIn    Status = "logging_in"
+	StatusFailed       Status = "failed"
+)
+
+// statusHistory is how many transitions are kept per bot.
+const statusHistory = 20
+
+type StatusChange struct {
+	From   Status    `json:"from"`
+	To     Status    `json:"to"`
+	Reason string    `json:"reason"`
+	At     time.Time `json:"at"`
+}
+
+type BotStatus struct {
+	SteamID       string         `json:"steam_id"`
+	Username      string         `json:"username"`
+	Status        Status         `json:"status"`
+	Enabled       bool           `json:"enabled"`
+	Frozen        bool           `json:"frozen"`
+	FrozenReason  string         `json:"frozen_reason,omitempty"`
+	LastLoginAt   *time.Time     `json:"last_login_at,omitempty"`
+	LastError     string         `json:"last_error,omitempty"`
+	SkinCount     int            `json:"skin_count"`
+	Reserved      int            `json:"reserved"`
+	PendingOffers int            `json:"pending_offers"`
+	Changes       []StatusChange `json:"changes"`
+}
+
+type botState struct {
+	username    string
+	status      Status
+	changes     []StatusChange
+	wake        chan struct{}
+	lastLoginAt time.Time
+	lastError   string
+}
+
+// WatchdogBackoff bounds the wait between re-login attempts of an offline bot.
+type WatchdogBackoff struct {
+	Base time.Duration
+	Max  time.Duration
+}
+
+func (m *BotManager) state(steamID string) *botState {
+	m.statusMu.Lock()
+	defer m.statusMu.Unlock()
+
+	st, ok := m.states[steamID]
+	if !ok {
+		st = &botState{status: StatusOffline, wake: make(chan struct{}, 1)}
+		m.states[steamID] = st
+	}
+	return st
+}
+
+func (m *BotManager) setStatus(b *bot.SteamBot, to Status, reason string) {
+	st := m.state(b.SteamID)
+
+	m.statusMu.Lock()
+	from := st.status
+	if from == to && len(st.changes) > 0 {
+		m.statusMu.Unlock()
+		return
+	}
+	st.username = b.Username
+	st.status = to
+	if to == StatusOffline || to == StatusFailed {
+		st.lastError = reason
+	}
+	st.changes = append(st.changes, StatusChange{From: from, To: to, Reason: reason, At: time.Now()})
+	if len(st.changes) > statusHistory {
+		st.changes = st.changes[len(st.changes)-statusHistory:]
+	}
+	m.statusMu.Unlock()
+
+	log.Info().
+		Str("username", b.Username).
+		Str("from", string(from)).
+		Str("to", string(to)).
+		Str("reason", reason).
+		Msg("Bot status changed")
+}
+
+func (m *BotManager) markLogin(steamID string) {
+	st := m.state(steamID)
+
+	m.statusMu.Lock()
+	defer m.statusMu.Unlock()
+	st.lastLoginAt = time.Now()
+}
+
+func (m *BotManager) recordError(steamID string, err error) {
+	st := m.state(steamID)
+
+	m.statusMu.Lock()
+	defer m.statusMu.Unlock()
+	st.lastError = err.Error()
+}
+
+// IsOnline reports whether the bot has a working session and can be given
+// trades.
+func (m *BotManager) IsOnline(steamID string) bool {
+	m.statusMu.RLock()
+	defer m.statusMu.RUnlock()
+
+	st, ok := m.states[steamID]
+	return ok && st.status == StatusOnline
+}
+
+// recentFailures counts how often the bot went offline since the given time.
+func (m *BotManager) recentFailures(steamID string, since time.Time) int {
+	m.statusMu.RLock()
+	defer m.statusMu.RUnlock()
+
+	st, ok := m.states[steamID]
+	if !ok {
+		return 0
+	}
+	n := 0
+	for _, change := range st.changes {
+		if change.To == StatusOffline && change.At.After(since) {
+			n++
+		}
+	}
+	return n
+}
+
+// Statuses returns the current status and recent transitions of every bot,
+// including bots that failed to start. PendingOffers is left to the caller,
+// it lives in the database.
+func (m *BotManager) Statuses() []BotStatus {
+	m.statusMu.RLock()
+	out := make([]BotStatus, 0, len(m.states))
+	for steamID, st := range m.states {
+		bs := BotStatus{
+			SteamID:   steamID,
+			Username:  st.username,
+			Status:    st.status,
+			Enabled:   true,
+			LastError: st.lastError,
+			Changes:   slices.Clone(st.changes),
+		}
+		if !st.lastLoginAt.IsZero() {
+			lastLoginAt := st.lastLoginAt
+			bs.LastLoginAt = &lastLoginAt
+		}
+		out = append(out, bs)
+	}
+	m.statusMu.RUnlock()
+
+	m.mu.RLock()
+	for i := range out {
+		if rb, ok := m.bots[out[i].SteamID]; ok {
+			out[i].FrozenReason, out[i].Frozen = rb.bot.Frozen()
+			out[i].Enabled = !rb.disabled
+			out[i].SkinCount = rb.skinCount
+			out[i].Reserved = rb.reserved
+		}
+	}
+	m.mu.RUnlock()
+	slices.SortFunc(out, func(a, b BotStatus) int {
+		if a.SteamID < b.SteamID {
+			return -1
+		}
+		if a.SteamID > b.SteamID {
+			return 1
+		}
+		return 0
+	})
+	return out
+}
+
+// reportAuthFailure wakes the bot's watchdog when a request came back
+// looking like a lost session. It runs on the request path and never blocks.
+func (m *BotManager) reportAuthFailure(b *bot.SteamBot, resp *http.Response) {
+	log.Warn().
+		Str("username", b.Username).
+		Int("status", resp.StatusCode).
+		Str("url", resp.Request.URL.Path).
+		Msg("Steam request looks unauthenticated")
+
+	m.wakeWatchdog(b.SteamID)
+}
+
+func (m *BotManager) wakeWatchdog(steamID string) {
+	select {
+	case m.state(steamID).wake <- struct{}{}:
+	default:
+	}
+}
+
+// StartWatchdog checks every bot's session each interval and right after
+// an auth failure was reported. Bots that lost their session are taken out
+// of rotation and logged in again with backoff.
+func (m *BotManager) StartWatchdog(interval time.Duration, backoff WatchdogBackoff) {
+	n := m.addLoop(func(ctx context.Context, b *bot.SteamBot) {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		wake := m.state(b.SteamID).wake
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			case <-wake:
+			}
+
+			m.checkSession(ctx, b, backoff)
+		}
+	})
+
+	log.Info().Int("bots", n).Dur("interval", interval).Msg("Session watchdog started")
+}
+
+func (m *BotManager) checkSession(ctx context.Context, b *bot.SteamBot, backoff WatchdogBackoff) {
+	err := m.Do(ctx, b, func() error { return b.ValidateSession(ctx) })
+	if err == nil {
+		m.setStatus(b, StatusOnline, "session valid")
+		return
+	}
+	if !errors.Is(err, bot.ErrSessionInvalid) {
+		log.Warn().Err(err).Str("username", b.Username).Msg("Could not check bot session")
+		return
+	}
+
+	m.setStatus(b, StatusOffline, err.Error())
+	m.reconnect(ctx, b, backoff)
+}
+
+func (m *BotManager) reconnect(ctx context.Context, b *bot.SteamBot, backoff WatchdogBackoff) {
+	m.setStatus(b, StatusReconnecting, "re-authenticating")
+
+	delay := backoff.Base
+	for attempt := 1; ; attempt++ {
+		err := m.Do(ctx, b, func() error { return b.Reauthenticate(ctx) })
+		if err == nil {
+			m.saveSession(ctx, b)
+			m.markLogin(b.SteamID)
+			m.setStatus(b, StatusOnline, fmt.Sprintf("re-authenticated after %d attempts", attempt))
+			return
+		}
+		m.recordError(b.SteamID, err)
+
+		log.Error().Err(err).Str("username", b.Username).Int("attempt", attempt).Dur("wait", delay).Msg("Bot re-login failed")
+		m.checkProxy(ctx, b)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		delay = min(delay*2, backoff.Max)
+	}
+}
@@ -0,0 +1,38 @@
+package bots_test
+
+import (
+	"csTrade/internal/domain/bot/fakesteam"
+	"csTrade/internal/repository/memrepo"
+	"csTrade/internal/service/bots"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatchdogLogsInBotThatFailedAtStartup(t *testing.T) {
+	srv := fakesteam.New()
+	t.Cleanup(srv.Close)
+	store := memrepo.NewBots()
+	stored := addBot(srv, store, 0)
+	srv.FailRequests("/IAuthenticationService/BeginAuthSessionViaCredentials/v1/", 1, http.StatusBadGateway, nil)
+
+	m := startManager(t, srv, store)
+
+	b := m.GetBotByID(stored.SteamID)
+	require.NotNil(t, b, "the bot is registered although its login failed")
+	st, _ := statusOf(m, stored.SteamID)
+	assert.Equal(t, bots.StatusOffline, st.Status)
+	_, _, err := m.ReserveBot(1)
+	assert.ErrorIs(t, err, bots.ErrNoEligibleBot)
+
+	m.StartWatchdog(time.Hour, bots.WatchdogBackoff{Base: time.Millisecond, Max: time.Millisecond})
+	eventually(t, func() bool { return m.IsOnline(stored.SteamID) }, "the watchdog logs the bot in")
+
+	_, r, err := m.ReserveBot(1)
+	require.NoError(t, err)
+	r.Release()
+	m.RemoveBot(stored.SteamID)
+}
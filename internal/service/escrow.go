@@ -0,0 +1,55 @@
+package service
+
+import (
+	"context"
+	"csTrade/internal/domain/bot"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// EscrowPolicy decides what happens to a listing or purchase whose trade
+// Steam would hold because the partner has no mobile authenticator.
+type EscrowPolicy string
+
+const (
+	EscrowReject EscrowPolicy = "reject"
+	EscrowAllow  EscrowPolicy = "allow"
+)
+
+func ParseEscrowPolicy(s string) (EscrowPolicy, error) {
+	switch p := EscrowPolicy(s); p {
+	case EscrowReject, EscrowAllow:
+		return p, nil
+	case "":
+		return EscrowReject, nil
+	default:
+		return "", fmt.Errorf("unknown escrow policy %q", s)
+	}
+}
+
+// checkTradeHold asks Steam how long a trade with the partner would be held
+// before it is created. It returns the expected escrow end date when the
+// policy lets held trades through, and nil when there is no hold.
+func checkTradeHold(ctx context.Context, b *bot.SteamBot, policy EscrowPolicy, partnerSteamID, tradeURL string) (*time.Time, error) {
+	holds, err := b.GetTradeHoldDurations(ctx, partnerSteamID, tradeURL)
+	if err != nil {
+		return nil, err
+	}
+	if !holds.Held() {
+		return nil, nil
+	}
+
+	if policy != EscrowAllow {
+		return nil, fmt.Errorf("%w: partner %s trades are held for %s", bot.ErrEscrowRequired, partnerSteamID, holds.Both)
+	}
+
+	end := time.Now().Add(holds.Both)
+	log.Info().
+		Str("bot", b.SteamID).
+		Str("partner", partnerSteamID).
+		Time("escrow_end_date", end).
+		Msg("trade will be held in escrow")
+	return &end, nil
+}
@@ -0,0 +1,45 @@
+package service
+
+import (
+	"csTrade/internal/domain/bot"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseEscrowPolicy(t *testing.T) {
+	for in, want := range map[string]EscrowPolicy{
+		"":       EscrowReject,
+		"reject": EscrowReject,
+		"allow":  EscrowAllow,
+	} {
+		got, err := ParseEscrowPolicy(in)
+		require.NoError(t, err, in)
+		assert.Equal(t, want, got, in)
+	}
+
+	_, err := ParseEscrowPolicy("sometimes")
+	assert.Error(t, err)
+}
+
+func TestCheckTradeHold(t *testing.T) {
+	srv, m := newSteam(t)
+	b := m.GetBotByID(botSteamID)
+
+	end, err := checkTradeHold(t.Context(), b, EscrowReject, buyerSteamID, srv.TradeURL(buyerSteamID))
+	require.NoError(t, err)
+	assert.Nil(t, end, "no hold without escrow")
+
+	_, err = checkTradeHold(t.Context(), b, EscrowReject, heldSteamID, srv.TradeURL(heldSteamID))
+	assert.ErrorIs(t, err, bot.ErrEscrowRequired)
+
+	end, err = checkTradeHold(t.Context(), b, EscrowAllow, heldSteamID, srv.TradeURL(heldSteamID))
+	require.NoError(t, err)
+	require.NotNil(t, end)
+	assert.WithinDuration(t, time.Now().Add(15*24*time.Hour), *end, time.Minute)
+
+	_, err = checkTradeHold(t.Context(), b, EscrowAllow, heldSteamID, srv.URL+"/tradeoffer/new/?partner=4&token=wrong")
+	assert.ErrorIs(t, err, bot.ErrInvalidTradeToken)
+}
@@ -0,0 +1,66 @@
+package service
+
+import (
+	"context"
+	"csTrade/internal/domain/bot"
+	"csTrade/internal/domain/offer"
+	"csTrade/internal/service/bots"
+	"fmt"
+)
+
+// fetchTradableItems reads the user's CS inventory through one of our bots
+// and keeps only the items that can be traded right now.
+func fetchTradableItems(ctx context.Context, botsManager *bots.BotManager, steamID string) ([]offer.InventoryItem, error) {
+	b, err := botsManager.GetOnlineBot()
+	if err != nil {
+		return nil, err
+	}
+
+	inventory, err := b.GetUserInventory(ctx, steamID, bot.CSAppID, bot.CSContextID)
+	if err != nil {
+		return nil, fmt.Errorf("err fetch user inventory: %w", err)
+	}
+
+	descriptions := make(map[string]int, len(inventory.Descriptions))
+	for i, d := range inventory.Descriptions {
+		descriptions[d.Classid+"_"+d.Instanceid] = i
+	}
+
+	items := make([]offer.InventoryItem, 0, len(inventory.Assets))
+	for _, asset := range inventory.Assets {
+		idx, ok := descriptions[asset.Classid+"_"+asset.Instanceid]
+		if !ok {
+			continue
+		}
+
+		item := offer.NewInventoryItem(steamID, asset, inventory.Descriptions[idx])
+		if item.Tradable {
+			items = append(items, item)
+		}
+	}
+
+	return items, nil
+}
+
+// botAssetIDs maps the deposited assets to the IDs they got in the bot
+// inventory once the trade went through. Assets missing from the trade
+// receipt are left out.
+func botAssetIDs(ctx context.Context, b *bot.SteamBot, tradeID string, assetIDs ...string) (map[string]string, error) {
+	if tradeID == "" {
+		return nil, fmt.Errorf("deposit has no trade id")
+	}
+
+	receipt, err := b.GetTradeReceipt(ctx, tradeID)
+	if err != nil {
+		return nil, err
+	}
+
+	newAssetIDs := make(map[string]string, len(assetIDs))
+	for _, assetID := range assetIDs {
+		if newAssetID, ok := receipt.NewAssetID(assetID); ok {
+			newAssetIDs[assetID] = newAssetID
+		}
+	}
+
+	return newAssetIDs, nil
+}
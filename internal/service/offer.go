@@ -2,8 +2,9 @@ package service
 
 import (
 	"context"
+	"csTrade/internal/domain/bot"
 	"csTrade/internal/domain/offer"
-	"csTrade/internal/domain/transaction"
+	"csTrade/internal/domain/operation"
 	"csTrade/internal/repository"
 	"csTrade/internal/service/bots"
 	"fmt"
@@ -13,128 +14,393 @@ import (
 )
 
 type OfferService struct {
-	repo        *repository.Repository
-	botsManager *bots.BotManager
+	repo         *repository.Repository
+	botsManager  *bots.BotManager
+	dispatcher   *bots.Dispatcher
+	escrowPolicy EscrowPolicy
 	// rdb  *redis.Client
 }
 
-func NewOfferService(repo *repository.Repository, botsManager *bots.BotManager) *OfferService {
-	return &OfferService{repo: repo, botsManager: botsManager}
+func NewOfferService(repo *repository.Repository, botsManager *bots.BotManager, dispatcher *bots.Dispatcher, escrowPolicy EscrowPolicy) *OfferService {
+	return &OfferService{repo: repo, botsManager: botsManager, dispatcher: dispatcher, escrowPolicy: escrowPolicy}
 }
 
-func (of *OfferService) ReceiveFromUserOffer(ctx context.Context, offerData *offer.OfferCreateReq) error {
-	log.Info().Msg("createOffer")
-	bot, errBot := of.botsManager.GetEmptierBot()
+// ReceiveFromUserOffer lists every requested item of the seller and queues
+// a single trade offer asking for all of them. The listings share its trade
+// ID once the dispatcher sent it, the returned operation tracks that.
+func (of *OfferService) ReceiveFromUserOffer(ctx context.Context, req *offer.ListSkinReq) (*operation.OperationResp, error) {
+	log.Info().Int("items", len(req.Items)).Msg("createOffer")
+	if len(req.Items) == 0 {
+		return nil, fmt.Errorf("no items to list")
+	}
+
+	items, err := fetchTradableItems(ctx, of.botsManager, req.SellerID)
+	if err != nil {
+		return nil, err
+	}
+
+	inventory := make(map[string]offer.InventoryItem, len(items))
+	for _, item := range items {
+		inventory[item.AssetID] = item
+	}
+
+	offersData := make([]*offer.OfferCreateReq, 0, len(req.Items))
+	assetIDs := make([]string, 0, len(req.Items))
+	listed := make(map[string]bool, len(req.Items))
+	for _, reqItem := range req.Items {
+		if reqItem.Price <= 0 {
+			return nil, fmt.Errorf("price of asset %s must be positive", reqItem.AssetID)
+		}
+		if listed[reqItem.AssetID] {
+			return nil, fmt.Errorf("asset %s is listed twice", reqItem.AssetID)
+		}
+		listed[reqItem.AssetID] = true
+
+		item, ok := inventory[reqItem.AssetID]
+		if !ok {
+			return nil, fmt.Errorf("asset %s is not a tradable item of the seller", reqItem.AssetID)
+		}
+		offersData = append(offersData, item.ToCreateReq(req.SellerID, reqItem.Price))
+		assetIDs = append(assetIDs, reqItem.AssetID)
+	}
+
+	if err := rejectListedAssets(ctx, of.repo, assetIDs); err != nil {
+		return nil, err
+	}
+
+	b, reservation, errBot := of.botsManager.ReserveBot(len(offersData))
 	if errBot != nil {
-		return errBot
+		return nil, errBot
 	}
 
-	err := of.repo.WithTxOptions(ctx, pgx.TxOptions{},
-		func(r *repository.Repository) error {
-			user, err := r.User.GetUserBySteamId(ctx, offerData.SellerID)
-			if err != nil {
-				return err
-			}
+	user, err := of.repo.User.GetUserBySteamId(ctx, req.SellerID)
+	if err != nil {
+		reservation.Release()
+		return nil, err
+	}
+
+	deposit := &pendingDeposit{reservation: reservation}
+	deposit.escrowEndDate, err = checkTradeHold(ctx, b, of.escrowPolicy, req.SellerID, user.TradeUrl)
+	if err != nil {
+		reservation.Release()
+		return nil, err
+	}
 
-			offerData.BotSteamID = bot.SteamID
-			offerId, err := r.Offer.CreateOffer(ctx, offerData)
-			if offerId == "" {
+	cmd := bots.ReceiveFromUserTrade{
+		BotSteamID: b.SteamID,
+		SellerID:   req.SellerID,
+		TradeURL:   user.TradeUrl,
+		Done:       deposit.finish(of),
+	}
+	res := &operation.OperationResp{SecurityCode: newSecurityCode()}
+	err = of.repo.WithTxOptions(ctx, pgx.TxOptions{},
+		func(r *repository.Repository) error {
+			// Deposits of one seller run one at a time, so no other one
+			// lists the same assets before this commits.
+			if _, err := r.User.GetUserBySteamIdForUpdate(ctx, req.SellerID); err != nil {
 				return err
 			}
-
-			steamTradeId, err := bot.ReceiveFromUser(offerData.AssetID, user.TradeUrl, offerData.SellerID)
-			if offerId == "" {
+			if err := rejectListedAssets(ctx, r, assetIDs); err != nil {
 				return err
 			}
 
-			err = r.Offer.UpdateOfferAfterReceive(ctx, bot.SteamID, steamTradeId, offerId)
-			if err != nil {
-				return err
+			for _, offerData := range offersData {
+				offerData.BotSteamID = b.SteamID
+				offerId, err := r.Offer.CreateOffer(ctx, offerData)
+				if offerId == "" {
+					return err
+				}
+				if err := r.Offer.SetSecurityCode(ctx, offerId, res.SecurityCode); err != nil {
+					return err
+				}
+				deposit.offerIDs = append(deposit.offerIDs, offerId)
+				cmd.Assets = append(cmd.Assets, bot.CSAsset(offerData.AssetID))
 			}
 
-			return nil
+			var err error
+			res.OperationID, err = r.Operation.CreateOperation(ctx, &operation.OperationDB{
+				Kind:         operation.OperationDeposit,
+				BotSteamID:   b.SteamID,
+				UserSteamID:  req.SellerID,
+				SecurityCode: res.SecurityCode,
+				OfferIDs:     deposit.offerIDs,
+			})
+			return err
 		})
+	if err != nil {
+		reservation.Release()
+		return nil, err
+	}
 
-	return err
+	deposit.operationID = res.OperationID
+	cmd.Message = securityMessage(res.SecurityCode)
+	if err := of.dispatcher.Submit(cmd); err != nil {
+		cmd.Done(ctx, bots.TradeResult{BotSteamID: b.SteamID, Err: err})
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// rejectListedAssets fails when any of the assets already has an offer that
+// is pending, on sale or reserved.
+func rejectListedAssets(ctx context.Context, r *repository.Repository, assetIDs []string) error {
+	listed, err := r.Offer.GetListedAssetIDs(ctx, assetIDs,
+		offer.OfferPending.String(), offer.OfferOnSale.String(), offer.OfferReserved.String())
+	if err != nil {
+		return err
+	}
+	if len(listed) > 0 {
+		return fmt.Errorf("asset %s is already listed", listed[0])
+	}
+
+	return nil
 }
 
-func (of *OfferService) GetTradeStatus(ctx context.Context, steamTradeOfferId string) (string, error) {
-	offerData, err := of.repo.Offer.GetOfferBySteamOfferID(ctx, steamTradeOfferId)
+// GetTradeStatus reports the state of a deposit or delivery trade offer
+// through the bot that sent it.
+func (of *OfferService) GetTradeStatus(ctx context.Context, steamTradeOfferId string) (*bot.TradeOfferStatus, error) {
+	botSteamID, err := of.tradeBotID(ctx, steamTradeOfferId)
 	if err != nil {
 		log.Error().Err(err).Msg("err get offerBotId by steamOfferId")
-		return "", fmt.Errorf("err get offerBotId by steamOfferId: %w", err)
+		return nil, fmt.Errorf("err get offerBotId by steamOfferId: %w", err)
 	}
 
-	bot := of.botsManager.GetBotByID(offerData.BotSteamID)
+	bot := of.botsManager.GetBotByID(botSteamID)
 	if bot == nil {
 		log.Error().Err(err).Msg("err get bot by id")
-		return "", fmt.Errorf("err get bot by id")
+		return nil, fmt.Errorf("err get bot by id")
 	}
 
-	err = bot.GetStatus(steamTradeOfferId)
+	status, err := bot.GetStatus(ctx, steamTradeOfferId)
 	if err != nil {
 		log.Error().Err(err).Msg("err get status by steamOfferId")
-		return "", fmt.Errorf("err get status by steamOfferId: %w", err)
+		return nil, fmt.Errorf("err get status by steamOfferId: %w", err)
 	}
-	return "ok", err
+	return status, nil
 }
 
+// tradeBotID finds the bot behind a trade offer, looking at the deposited
+// listings first and the deliveries after.
+func (of *OfferService) tradeBotID(ctx context.Context, tradeOfferID string) (string, error) {
+	offersData, err := of.repo.Offer.GetOffersBySteamTradeID(ctx, tradeOfferID)
+	if err != nil {
+		return "", err
+	}
+	for _, offerData := range offersData {
+		if offerData.BotSteamID != "" {
+			return offerData.BotSteamID, nil
+		}
+	}
+
+	transactions, err := of.repo.Transaction.GetTransactionsBySteamTradeID(ctx, tradeOfferID)
+	if err != nil {
+		return "", err
+	}
+	for _, tr := range transactions {
+		if tr.BotID != "" {
+			return tr.BotID, nil
+		}
+	}
+
+	return "", fmt.Errorf("no deposit or delivery with trade offer %s", tradeOfferID)
+}
+
+// CancelTrade cancels the Steam trade offer and every listing it covers.
+// The offer is declined before the listings are locked.
 func (of *OfferService) CancelTrade(ctx context.Context, steamTradeOfferId string) error {
-	err := of.repo.WithTx(ctx, func(r *repository.Repository) error {
-		offerData, err := r.Offer.GetOfferBySteamOfferIDForUpdate(ctx, steamTradeOfferId)
+	offersData, err := of.repo.Offer.GetOffersBySteamTradeID(ctx, steamTradeOfferId)
+	if err != nil {
+		log.Error().Err(err).Msg("err get offerBotId by steamOfferId")
+		return fmt.Errorf("err get offerBotId by steamOfferId: %w", err)
+	}
+	if len(offersData) == 0 {
+		return fmt.Errorf("err get offerBotId by steamOfferId: %w", pgx.ErrNoRows)
+	}
+
+	bot := of.botsManager.GetBotByID(offersData[0].BotSteamID)
+	if bot == nil {
+		log.Error().Err(err).Msg("err get bot by id")
+		return fmt.Errorf("err get bot by id")
+	}
+
+	_, err = of.botsManager.SendWithSessionRetry(ctx, bot, func() (string, error) {
+		return "", bot.DeclineTrade(ctx, steamTradeOfferId)
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("err cancel trade")
+		return fmt.Errorf("err cancel trade %w", err)
+	}
+
+	return of.repo.WithTx(ctx, func(r *repository.Repository) error {
+		offersData, err := r.Offer.GetOffersBySteamTradeIDForUpdate(ctx, steamTradeOfferId)
 		if err != nil {
-			log.Error().Err(err).Msg("err get offerBotId by steamOfferId")
 			return fmt.Errorf("err get offerBotId by steamOfferId: %w", err)
 		}
 
-		bot := of.botsManager.GetBotByID(offerData.BotSteamID)
-		if bot == nil {
-			log.Error().Err(err).Msg("err get bot by id")
-			return fmt.Errorf("err get bot by id")
+		for _, offerData := range offersData {
+			err = r.Offer.ChangeStatusByID(ctx, offer.OfferCanceled.String(), offerData.ID.String())
+			if err != nil {
+				log.Error().Err(err).Msg("err change trade statu")
+				return fmt.Errorf("err change trade status %w", err)
+			}
+		}
+
+		return nil
+	})
+}
+
+// SendToBuyerOffer reserves the listings for the buyer and queues a single
+// trade offer for all of them. They have to sit in the same bot inventory.
+// Steam is asked about the trade hold and asset IDs before the listings are
+// locked, the locked rows are checked again.
+func (of *OfferService) SendToBuyerOffer(ctx context.Context, offerIDs []string, buyerID string) (*operation.OperationResp, error) {
+	log.Info().Strs("offers", offerIDs).Msg("sendToBuyerOffer")
+	if len(offerIDs) == 0 {
+		return nil, fmt.Errorf("no offers to purchase")
+	}
+
+	offersData := make([]*offer.OfferDB, 0, len(offerIDs))
+	seen := make(map[string]bool, len(offerIDs))
+	for _, offerID := range offerIDs {
+		if seen[offerID] {
+			return nil, fmt.Errorf("offer %s is purchased twice", offerID)
 		}
+		seen[offerID] = true
 
-		err = bot.DeclineTrade(steamTradeOfferId)
+		offerData, err := of.repo.Offer.GetByID(ctx, offerID)
 		if err != nil {
-			log.Error().Err(err).Msg("err cancel trade")
-			return fmt.Errorf("err cancel trade %w", err)
+			return nil, err
 		}
+		if err := checkPurchasable(offerData, offersData); err != nil {
+			return nil, err
+		}
+		offersData = append(offersData, offerData)
+	}
+
+	buyer, err := of.repo.User.GetUserBySteamId(ctx, buyerID)
+	if err != nil {
+		return nil, err
+	}
+
+	b := of.botsManager.GetBotByID(offersData[0].BotSteamID)
+	if b == nil {
+		return nil, fmt.Errorf("err get bot by id")
+	}
 
-		err = r.Offer.ChangeStatusByID(ctx, offer.OfferCanceled.String(), offerData.ID.String())
+	purchase := &pendingPurchase{buyerID: buyer.SteamID}
+	purchase.escrowEndDate, err = checkTradeHold(ctx, b, of.escrowPolicy, buyer.SteamID, buyer.TradeUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	assetIDs := make(map[string]string, len(offersData))
+	for i, offerData := range offersData {
+		assetIDs[offerIDs[i]], err = depositedAssetID(ctx, b, offerData)
 		if err != nil {
-			log.Error().Err(err).Msg("err change trade statu")
-			return fmt.Errorf("err change trade status %w", err)
+			return nil, err
 		}
+	}
 
-		return nil
+	cmd := bots.SendToBuyerEventTrade{
+		BotSteamID:    b.SteamID,
+		BuyerID:       buyerID,
+		BuyerTradeURL: buyer.TradeUrl,
+		Done:          purchase.finish(of),
+	}
+	res := &operation.OperationResp{SecurityCode: newSecurityCode()}
+	err = of.repo.WithTx(ctx, func(r *repository.Repository) error {
+		locked := make([]*offer.OfferDB, 0, len(offerIDs))
+		for _, offerID := range offerIDs {
+			offerData, err := r.Offer.GetByIDForUpdate(ctx, offerID)
+			if err != nil {
+				return err
+			}
+			if err := checkPurchasable(offerData, locked); err != nil {
+				return err
+			}
+			if offerData.BotSteamID != b.SteamID {
+				return fmt.Errorf("offer %s moved to another bot", offerID)
+			}
+
+			assetID := assetIDs[offerID]
+			if offerData.BotAssetID == nil {
+				if err := r.Offer.SetBotAssetID(ctx, offerID, assetID); err != nil {
+					return err
+				}
+			}
+			cmd.Assets = append(cmd.Assets, bot.CSAsset(assetID))
+
+			err = r.Offer.ChangeStatusByID(ctx, offer.OfferReserved.String(), offerID)
+			if err != nil {
+				return err
+			}
+			locked = append(locked, offerData)
+		}
+		purchase.offers = locked
+
+		var err error
+		res.OperationID, err = r.Operation.CreateOperation(ctx, &operation.OperationDB{
+			Kind:         operation.OperationPurchase,
+			BotSteamID:   b.SteamID,
+			UserSteamID:  buyer.SteamID,
+			SecurityCode: res.SecurityCode,
+			OfferIDs:     offerIDs,
+		})
+		return err
 	})
+	if err != nil {
+		return nil, err
+	}
 
-	return err
+	purchase.operationID = res.OperationID
+	purchase.securityCode = res.SecurityCode
+	cmd.Message = securityMessage(res.SecurityCode)
+	if err := of.dispatcher.Submit(cmd); err != nil {
+		cmd.Done(ctx, bots.TradeResult{BotSteamID: cmd.BotSteamID, Err: err})
+		return nil, err
+	}
+
+	return res, nil
 }
 
-func (of *OfferService) SendToBuyerOffer(ctx context.Context, offer *offer.OfferCreateReq) error {
-	log.Info().Msg("sendToBuyerOffer")
-	user, err := of.repo.User.GetUserBySteamId(ctx, offer.SellerID)
-	if err != nil {
-		return err
+// checkPurchasable reports why offerData cannot be bought together with the
+// offers already picked.
+func checkPurchasable(offerData *offer.OfferDB, picked []*offer.OfferDB) error {
+	if offerData.Status != offer.OfferOnSale {
+		return fmt.Errorf("offer %s is not on sale", offerData.ID)
+	}
+	if len(picked) > 0 && offerData.BotSteamID != picked[0].BotSteamID {
+		return fmt.Errorf("offers are held by different bots")
 	}
+	return nil
+}
 
-	bot := of.botsManager.GetBotByID(offer.BotSteamID)
-	if bot == nil {
-		return fmt.Errorf("err get bot by id")
+// depositedAssetID returns the asset ID of the item in the bot inventory,
+// looking it up from the deposit trade if the tracker could not store it.
+func depositedAssetID(ctx context.Context, b *bot.SteamBot, offerData *offer.OfferDB) (string, error) {
+	if offerData.BotAssetID != nil {
+		return *offerData.BotAssetID, nil
+	}
+	if offerData.SteamTradeId == nil {
+		return "", fmt.Errorf("offer has no deposit trade")
 	}
 
-	offer.BotSteamID = bot.SteamID
-	err = of.repo.Transaction.CreateTransaction(ctx, transaction.TransactionDB{})
+	deposit, err := b.GetTradeOffer(ctx, *offerData.SteamTradeId)
 	if err != nil {
-		return err
+		return "", err
 	}
 
-	err = bot.SendToBuyer(offer.AssetID, user.TradeUrl, offer.SellerID)
+	newAssetIDs, err := botAssetIDs(ctx, b, deposit.TradeID, offerData.AssetID)
 	if err != nil {
-		return err
+		return "", err
 	}
 
-	return nil
+	assetID, ok := newAssetIDs[offerData.AssetID]
+	if !ok {
+		return "", fmt.Errorf("asset %s not found in trade %s", offerData.AssetID, deposit.TradeID)
+	}
+	return assetID, nil
 }
 
 func (of *OfferService) GetAllOffers(ctx context.Context) ([]offer.OfferDB, error) {
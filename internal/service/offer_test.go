@@ -0,0 +1,48 @@
+//go:build integration
+// +build integration
+
+package service
+
+import (
+	"csTrade/internal/domain/bot"
+	"csTrade/internal/domain/offer"
+	"csTrade/internal/domain/transaction"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetTradeStatus(t *testing.T) {
+	e := newTestEnv(t)
+	depositID, _ := e.deposit(t, "ABC234", "1001")
+
+	offerData := e.onSale(t, "2001")
+	require.NoError(t, e.repo.Offer.ChangeStatusByID(t.Context(), offer.OfferReserved.String(), offerData.ID.String()))
+	code := "XYZ789"
+	deliveryID := e.sendPurchase(t, code, "2001")
+	require.NoError(t, e.repo.Transaction.CreateTransaction(t.Context(), transaction.TransactionDB{
+		OfferID:      offerData.ID,
+		SellerID:     sellerSteamID,
+		BuyerID:      buyerSteamID,
+		BotID:        botSteamID,
+		Status:       transaction.TransactionPending,
+		Price:        offerData.Price,
+		SteamTradeID: &deliveryID,
+		SecurityCode: &code,
+	}))
+	require.True(t, e.srv.SetOfferState(deliveryID, bot.TradeOfferStateAccepted))
+
+	status, err := e.offers.GetTradeStatus(t.Context(), depositID)
+	require.NoError(t, err)
+	assert.Equal(t, depositID, status.TradeOfferID)
+	assert.Equal(t, bot.TradeOfferStateActive, status.State)
+
+	status, err = e.offers.GetTradeStatus(t.Context(), deliveryID)
+	require.NoError(t, err, "a delivery is found through its transaction")
+	assert.Equal(t, deliveryID, status.TradeOfferID)
+	assert.Equal(t, bot.TradeOfferStateAccepted, status.State)
+
+	_, err = e.offers.GetTradeStatus(t.Context(), "999999")
+	assert.Error(t, err)
+}
@@ -0,0 +1,321 @@
+package service
+
+import (
+	"context"
+	"csTrade/internal/domain/bot"
+	"csTrade/internal/domain/offer"
+	"csTrade/internal/domain/operation"
+	"csTrade/internal/domain/transaction"
+	"csTrade/internal/repository"
+	"csTrade/internal/service/bots"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// pendingDeposit is what a queued deposit needs once the dispatcher sent
+// or gave up on its trade offer.
+type pendingDeposit struct {
+	operationID   string
+	offerIDs      []string
+	escrowEndDate *time.Time
+	reservation   *bots.Reservation
+}
+
+func (d *pendingDeposit) finish(of *OfferService) func(context.Context, bots.TradeResult) {
+	return func(ctx context.Context, res bots.TradeResult) {
+		if res.Err != nil && res.TradeOfferID != "" && !of.withdrawOffer(ctx, res) {
+			d.reservation.Hold(res.TradeOfferID)
+			err := of.repo.WithTx(ctx, func(r *repository.Repository) error {
+				if err := d.recordTrade(ctx, r, res); err != nil {
+					return err
+				}
+				return r.Operation.FailOperation(ctx, d.operationID, res.TradeOfferID, res.Err.Error())
+			})
+			logOperation(d.operationID, res, err)
+			return
+		}
+
+		if res.Err != nil {
+			d.reservation.Release()
+			err := of.repo.WithTx(ctx, func(r *repository.Repository) error {
+				for _, offerID := range d.offerIDs {
+					_, err := r.Offer.ChangeStatusByIDFrom(ctx, offerID, offer.OfferPending.String(), offer.OfferCanceled.String())
+					if err != nil {
+						return err
+					}
+				}
+				return r.Operation.FailOperation(ctx, d.operationID, "", res.Err.Error())
+			})
+			logOperation(d.operationID, res, err)
+			return
+		}
+
+		d.reservation.Hold(res.TradeOfferID)
+		err := of.repo.WithTx(ctx, func(r *repository.Repository) error {
+			if err := d.recordTrade(ctx, r, res); err != nil {
+				return err
+			}
+			return r.Operation.CompleteOperation(ctx, d.operationID, res.TradeOfferID)
+		})
+		logOperation(d.operationID, res, err)
+	}
+}
+
+// recordTrade ties the listings of the deposit to the trade offer the bot
+// sent.
+func (d *pendingDeposit) recordTrade(ctx context.Context, r *repository.Repository, res bots.TradeResult) error {
+	for _, offerID := range d.offerIDs {
+		if err := r.Offer.UpdateOfferAfterReceive(ctx, res.BotSteamID, res.TradeOfferID, offerID); err != nil {
+			return err
+		}
+
+		if d.escrowEndDate != nil {
+			if err := r.Offer.SetEscrowEndDate(ctx, offerID, *d.escrowEndDate); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// pendingPurchase is what a queued purchase needs once the dispatcher sent
+// or gave up on its trade offer.
+type pendingPurchase struct {
+	operationID   string
+	securityCode  string
+	buyerID       string
+	offers        []*offer.OfferDB
+	escrowEndDate *time.Time
+}
+
+func (p *pendingPurchase) finish(of *OfferService) func(context.Context, bots.TradeResult) {
+	return func(ctx context.Context, res bots.TradeResult) {
+		if res.Err != nil && res.TradeOfferID != "" && !of.withdrawOffer(ctx, res) {
+			// The offer exists on Steam and could not be taken back, so the
+			// listings stay reserved under its trade ID until the tracker
+			// sees it end.
+			err := of.repo.WithTx(ctx, func(r *repository.Repository) error {
+				if err := p.recordTrade(ctx, r, res); err != nil {
+					return err
+				}
+				return r.Operation.FailOperation(ctx, p.operationID, res.TradeOfferID, res.Err.Error())
+			})
+			logOperation(p.operationID, res, err)
+			return
+		}
+
+		if res.Err != nil {
+			err := of.repo.WithTx(ctx, func(r *repository.Repository) error {
+				for _, offerData := range p.offers {
+					_, err := r.Offer.ChangeStatusByIDFrom(ctx, offerData.ID.String(), offer.OfferReserved.String(), offer.OfferOnSale.String())
+					if err != nil {
+						return err
+					}
+				}
+				return r.Operation.FailOperation(ctx, p.operationID, "", res.Err.Error())
+			})
+			logOperation(p.operationID, res, err)
+			return
+		}
+
+		err := of.repo.WithTx(ctx, func(r *repository.Repository) error {
+			if err := p.recordTrade(ctx, r, res); err != nil {
+				return err
+			}
+			return r.Operation.CompleteOperation(ctx, p.operationID, res.TradeOfferID)
+		})
+		logOperation(p.operationID, res, err)
+	}
+}
+
+// recordTrade creates the pending transactions of the purchase under the
+// trade offer the bot sent.
+func (p *pendingPurchase) recordTrade(ctx context.Context, r *repository.Repository, res bots.TradeResult) error {
+	for _, offerData := range p.offers {
+		err := r.Transaction.CreateTransaction(ctx, transaction.TransactionDB{
+			OfferID:      offerData.ID,
+			SellerID:     offerData.SellerID,
+			BuyerID:      p.buyerID,
+			BotID:        res.BotSteamID,
+			Status:       transaction.TransactionPending,
+			Price:        offerData.Price,
+			SteamTradeID: &res.TradeOfferID,
+			SecurityCode: &p.securityCode,
+		})
+		if err != nil {
+			return err
+		}
+
+		if p.escrowEndDate != nil {
+			if err := r.Offer.SetEscrowEndDate(ctx, offerData.ID.String(), *p.escrowEndDate); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// withdrawOffer cancels an offer Steam created even though sending it
+// failed afterwards, at the mobile confirmation or because the process
+// stopped. It reports whether the offer is gone.
+func (of *OfferService) withdrawOffer(ctx context.Context, res bots.TradeResult) bool {
+	b := of.botsManager.GetBotByID(res.BotSteamID)
+	if b == nil {
+		return false
+	}
+
+	err := of.botsManager.Do(ctx, b, func() error { return b.DeclineTrade(ctx, res.TradeOfferID) })
+	if err != nil {
+		log.Error().Err(err).
+			Str("bot", res.BotSteamID).
+			Str("trade_offer_id", res.TradeOfferID).
+			Msg("err withdraw trade offer after failed send")
+		return false
+	}
+
+	log.Warn().AnErr("trade_err", res.Err).
+		Str("bot", res.BotSteamID).
+		Str("trade_offer_id", res.TradeOfferID).
+		Msg("trade offer withdrawn after failed send")
+	return true
+}
+
+var errOperationInterrupted = errors.New("trade operation interrupted by restart")
+
+// StartReconciliation reconciles the operations of earlier runs now and
+// again every interval until none is left. Operations whose bot is offline
+// or whose offers could not be looked up are settled on a later round.
+func (of *OfferService) StartReconciliation(ctx context.Context, startedAt time.Time, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for !of.ReconcileOperations(ctx, startedAt) {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+		log.Info().Msg("trade operations of earlier runs reconciled")
+	}()
+}
+
+// ReconcileOperations settles operations an earlier run queued but never
+// finished. An offer Steam already created for one is found by the security
+// code in its message. Open offers are withdrawn, accepted ones and offers
+// that could not be withdrawn are recorded like a normal send and handed
+// to the tracker. Everything else gets its listings back and fails. It
+// reports whether no operation is left pending.
+func (of *OfferService) ReconcileOperations(ctx context.Context, startedAt time.Time) bool {
+	ops, err := of.repo.Operation.GetPendingOperations(ctx, startedAt)
+	if err != nil {
+		log.Error().Err(err).Msg("err get pending trade operations")
+		return false
+	}
+
+	left := 0
+	for _, op := range ops {
+		if err := of.reconcileOperation(ctx, &op); err != nil {
+			log.Error().Err(err).Str("operation", op.ID.String()).Msg("err reconcile trade operation")
+			left++
+		}
+	}
+	if len(ops) > 0 {
+		log.Info().Int("operations", len(ops)).Int("left", left).Msg("pending trade operations reconciled")
+	}
+	return left == 0
+}
+
+func (of *OfferService) reconcileOperation(ctx context.Context, op *operation.OperationDB) error {
+	b := of.botsManager.GetBotByID(op.BotSteamID)
+	if b == nil {
+		return fmt.Errorf("bot %s is not running, operation stays pending", op.BotSteamID)
+	}
+
+	var sent *bot.TradeOffer
+	err := of.botsManager.Do(ctx, b, func() error {
+		offers, err := b.GetTradeOffers(ctx, true, false, op.CreatedAt.Add(-time.Minute))
+		if err != nil {
+			return err
+		}
+		for _, o := range offers.Sent {
+			if strings.Contains(o.Message, op.SecurityCode) {
+				sent = &o
+				return nil
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("err look up trade offer of operation: %w", err)
+	}
+
+	res := bots.TradeResult{BotSteamID: op.BotSteamID, Err: errOperationInterrupted}
+	if sent != nil {
+		switch sent.State {
+		case bot.TradeOfferStateActive, bot.TradeOfferStateNeedsConfirmation:
+			res.TradeOfferID = sent.TradeOfferID
+		case bot.TradeOfferStateAccepted, bot.TradeOfferStateInEscrow:
+			res.TradeOfferID, res.Err = sent.TradeOfferID, nil
+		}
+	}
+
+	switch op.Kind {
+	case operation.OperationDeposit:
+		deposit := &pendingDeposit{operationID: op.ID.String(), offerIDs: op.OfferIDs}
+		deposit.finish(of)(ctx, res)
+	case operation.OperationPurchase:
+		purchase := &pendingPurchase{
+			operationID:  op.ID.String(),
+			securityCode: op.SecurityCode,
+			buyerID:      op.UserSteamID,
+		}
+		for _, offerID := range op.OfferIDs {
+			offerData, err := of.repo.Offer.GetByID(ctx, offerID)
+			if err != nil {
+				return err
+			}
+			purchase.offers = append(purchase.offers, offerData)
+		}
+		purchase.finish(of)(ctx, res)
+	default:
+		return fmt.Errorf("unknown operation kind %q", op.Kind)
+	}
+
+	// The poller only reports offers that changed lately, hand this one to
+	// the tracker directly in case it settled long ago.
+	if sent != nil {
+		select {
+		case of.botsManager.Events <- bots.TradeOfferEvent{BotSteamID: op.BotSteamID, Offer: *sent, Sent: true}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+func logOperation(operationID string, res bots.TradeResult, err error) {
+	if err != nil {
+		log.Error().Err(err).
+			Str("operation", operationID).
+			Str("trade_offer_id", res.TradeOfferID).
+			Msg("err record trade operation result")
+		return
+	}
+
+	log.Info().
+		AnErr("trade_err", res.Err).
+		Str("operation", operationID).
+		Str("bot", res.BotSteamID).
+		Str("trade_offer_id", res.TradeOfferID).
+		Msg("trade operation finished")
+}
+
+func (of *OfferService) GetOperation(ctx context.Context, operationID string) (*operation.OperationDB, error) {
+	return of.repo.Operation.GetOperation(ctx, operationID)
+}
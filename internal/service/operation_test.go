@@ -0,0 +1,270 @@
+//go:build integration
+// +build integration
+
+package service
+
+import (
+	"csTrade/internal/domain/bot"
+	"csTrade/internal/domain/offer"
+	"csTrade/internal/domain/operation"
+	"csTrade/internal/domain/transaction"
+	"csTrade/internal/service/bots"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errSend = errors.New("confirmation failed")
+
+func (e *testEnv) createOperation(t *testing.T, kind operation.OperationKind, userSteamID, code string, offerIDs ...string) string {
+	t.Helper()
+
+	id, err := e.repo.Operation.CreateOperation(t.Context(), &operation.OperationDB{
+		Kind:         kind,
+		BotSteamID:   botSteamID,
+		UserSteamID:  userSteamID,
+		SecurityCode: code,
+		OfferIDs:     offerIDs,
+	})
+	require.NoError(t, err)
+	return id
+}
+
+func (e *testEnv) operation(t *testing.T, id string) *operation.OperationDB {
+	t.Helper()
+
+	op, err := e.repo.Operation.GetOperation(t.Context(), id)
+	require.NoError(t, err)
+	return op
+}
+
+// sendDeposit has the bot ask the seller for the item like the dispatcher
+// would, leaving the bookkeeping to the test.
+func (e *testEnv) sendDeposit(t *testing.T, code, assetID string) string {
+	t.Helper()
+
+	e.addItem(sellerSteamID, assetID)
+	tradeOfferID, err := e.bot.ReceiveFromUser(t.Context(), []bot.Asset{bot.CSAsset(assetID)}, e.srv.TradeURL(sellerSteamID), sellerSteamID, securityMessage(code))
+	require.NoError(t, err)
+	return tradeOfferID
+}
+
+func (e *testEnv) sendPurchase(t *testing.T, code, assetID string) string {
+	t.Helper()
+
+	tradeOfferID, err := e.bot.SendToBuyer(t.Context(), []bot.Asset{bot.CSAsset(assetID)}, e.srv.TradeURL(buyerSteamID), buyerSteamID, securityMessage(code))
+	require.NoError(t, err)
+	return tradeOfferID
+}
+
+func TestDepositFinish(t *testing.T) {
+	t.Run("sent", func(t *testing.T) {
+		e := newTestEnv(t)
+		offerID := e.listItem(t, "1001")
+		opID := e.createOperation(t, operation.OperationDeposit, sellerSteamID, "ABC234", offerID)
+		tradeOfferID := e.sendDeposit(t, "ABC234", "1001")
+
+		d := &pendingDeposit{operationID: opID, offerIDs: []string{offerID}}
+		d.finish(e.offers)(t.Context(), bots.TradeResult{BotSteamID: botSteamID, TradeOfferID: tradeOfferID})
+
+		offerData := e.offer(t, offerID)
+		assert.Equal(t, offer.OfferPending, offerData.Status)
+		assert.Equal(t, botSteamID, offerData.BotSteamID)
+		assert.Equal(t, tradeOfferID, *offerData.SteamTradeId)
+		op := e.operation(t, opID)
+		assert.Equal(t, operation.OperationSucceeded, op.Status)
+		assert.Equal(t, tradeOfferID, *op.TradeOfferID)
+	})
+
+	t.Run("not sent", func(t *testing.T) {
+		e := newTestEnv(t)
+		offerID := e.listItem(t, "1001")
+		opID := e.createOperation(t, operation.OperationDeposit, sellerSteamID, "ABC234", offerID)
+
+		d := &pendingDeposit{operationID: opID, offerIDs: []string{offerID}}
+		d.finish(e.offers)(t.Context(), bots.TradeResult{BotSteamID: botSteamID, Err: errSend})
+
+		assert.Equal(t, offer.OfferCanceled, e.offer(t, offerID).Status)
+		op := e.operation(t, opID)
+		assert.Equal(t, operation.OperationFailed, op.Status)
+		assert.Nil(t, op.TradeOfferID)
+		assert.Equal(t, errSend.Error(), *op.Error)
+	})
+
+	t.Run("withdrawn after a failed send", func(t *testing.T) {
+		e := newTestEnv(t)
+		offerID := e.listItem(t, "1001")
+		opID := e.createOperation(t, operation.OperationDeposit, sellerSteamID, "ABC234", offerID)
+		tradeOfferID := e.sendDeposit(t, "ABC234", "1001")
+
+		d := &pendingDeposit{operationID: opID, offerIDs: []string{offerID}}
+		d.finish(e.offers)(t.Context(), bots.TradeResult{BotSteamID: botSteamID, TradeOfferID: tradeOfferID, Err: errSend})
+
+		assert.Equal(t, bot.TradeOfferStateCanceled, e.steamState(tradeOfferID))
+		assert.Equal(t, offer.OfferCanceled, e.offer(t, offerID).Status)
+		op := e.operation(t, opID)
+		assert.Equal(t, operation.OperationFailed, op.Status)
+		assert.Nil(t, op.TradeOfferID)
+	})
+
+	t.Run("kept when it cannot be withdrawn", func(t *testing.T) {
+		e := newTestEnv(t)
+		offerID := e.listItem(t, "1001")
+		opID := e.createOperation(t, operation.OperationDeposit, sellerSteamID, "ABC234", offerID)
+		tradeOfferID := e.sendDeposit(t, "ABC234", "1001")
+		e.srv.FailRequests("/tradeoffer/"+tradeOfferID+"/cancel", 1, http.StatusBadGateway, nil)
+
+		d := &pendingDeposit{operationID: opID, offerIDs: []string{offerID}}
+		d.finish(e.offers)(t.Context(), bots.TradeResult{BotSteamID: botSteamID, TradeOfferID: tradeOfferID, Err: errSend})
+
+		assert.Equal(t, bot.TradeOfferStateActive, e.steamState(tradeOfferID))
+		offerData := e.offer(t, offerID)
+		assert.Equal(t, offer.OfferPending, offerData.Status, "the tracker settles the listing with the offer")
+		assert.Equal(t, tradeOfferID, *offerData.SteamTradeId)
+		op := e.operation(t, opID)
+		assert.Equal(t, operation.OperationFailed, op.Status)
+		assert.Equal(t, tradeOfferID, *op.TradeOfferID)
+	})
+}
+
+func TestDepositRejectsListedAssets(t *testing.T) {
+	e := newTestEnv(t)
+	_, offerIDs := e.deposit(t, "ABC234", "1001")
+	e.addItem(sellerSteamID, "1002")
+
+	_, err := e.offers.ReceiveFromUserOffer(t.Context(), &offer.ListSkinReq{
+		SellerID: sellerSteamID,
+		Items:    []offer.ListSkinItem{{AssetID: "1002", Price: 10}, {AssetID: "1001", Price: 12}},
+	})
+	require.ErrorContains(t, err, "asset 1001 is already listed")
+
+	listings, err := e.repo.Offer.GetOfferBySellerID(t.Context(), sellerSteamID)
+	require.NoError(t, err)
+	require.Len(t, listings, 1, "no listing is created for any of the items")
+	assert.Equal(t, offerIDs[0], listings[0].ID.String())
+	assert.Zero(t, e.m.Statuses()[0].Reserved, "no bot is reserved")
+}
+
+func TestPurchaseFinish(t *testing.T) {
+	reserved := func(t *testing.T, e *testEnv) *offer.OfferDB {
+		offerData := e.onSale(t, "2001")
+		require.NoError(t, e.repo.Offer.ChangeStatusByID(t.Context(), offer.OfferReserved.String(), offerData.ID.String()))
+		return e.offer(t, offerData.ID.String())
+	}
+	transactions := func(t *testing.T, e *testEnv, tradeOfferID string) []transaction.TransactionDB {
+		transactions, err := e.repo.Transaction.GetTransactionsBySteamTradeID(t.Context(), tradeOfferID)
+		require.NoError(t, err)
+		return transactions
+	}
+
+	t.Run("sent", func(t *testing.T) {
+		e := newTestEnv(t)
+		offerData := reserved(t, e)
+		opID := e.createOperation(t, operation.OperationPurchase, buyerSteamID, "XYZ789", offerData.ID.String())
+		tradeOfferID := e.sendPurchase(t, "XYZ789", "2001")
+
+		p := &pendingPurchase{operationID: opID, securityCode: "XYZ789", buyerID: buyerSteamID, offers: []*offer.OfferDB{offerData}}
+		p.finish(e.offers)(t.Context(), bots.TradeResult{BotSteamID: botSteamID, TradeOfferID: tradeOfferID})
+
+		trs := transactions(t, e, tradeOfferID)
+		require.Len(t, trs, 1)
+		assert.Equal(t, transaction.TransactionPending, trs[0].Status)
+		assert.Equal(t, buyerSteamID, trs[0].BuyerID)
+		assert.Equal(t, "XYZ789", *trs[0].SecurityCode)
+		assert.Equal(t, offer.OfferReserved, e.offer(t, offerData.ID.String()).Status)
+		assert.Equal(t, operation.OperationSucceeded, e.operation(t, opID).Status)
+	})
+
+	t.Run("not sent", func(t *testing.T) {
+		e := newTestEnv(t)
+		offerData := reserved(t, e)
+		opID := e.createOperation(t, operation.OperationPurchase, buyerSteamID, "XYZ789", offerData.ID.String())
+
+		p := &pendingPurchase{operationID: opID, securityCode: "XYZ789", buyerID: buyerSteamID, offers: []*offer.OfferDB{offerData}}
+		p.finish(e.offers)(t.Context(), bots.TradeResult{BotSteamID: botSteamID, Err: errSend})
+
+		assert.Equal(t, offer.OfferOnSale, e.offer(t, offerData.ID.String()).Status, "the listing is back on sale")
+		assert.Equal(t, operation.OperationFailed, e.operation(t, opID).Status)
+	})
+
+	t.Run("kept when it cannot be withdrawn", func(t *testing.T) {
+		e := newTestEnv(t)
+		offerData := reserved(t, e)
+		opID := e.createOperation(t, operation.OperationPurchase, buyerSteamID, "XYZ789", offerData.ID.String())
+		tradeOfferID := e.sendPurchase(t, "XYZ789", "2001")
+		e.srv.FailRequests("/tradeoffer/"+tradeOfferID+"/cancel", 1, http.StatusBadGateway, nil)
+
+		p := &pendingPurchase{operationID: opID, securityCode: "XYZ789", buyerID: buyerSteamID, offers: []*offer.OfferDB{offerData}}
+		p.finish(e.offers)(t.Context(), bots.TradeResult{BotSteamID: botSteamID, TradeOfferID: tradeOfferID, Err: errSend})
+
+		require.Len(t, transactions(t, e, tradeOfferID), 1, "the tracker settles the purchase with the offer")
+		assert.Equal(t, offer.OfferReserved, e.offer(t, offerData.ID.String()).Status)
+		op := e.operation(t, opID)
+		assert.Equal(t, operation.OperationFailed, op.Status)
+		assert.Equal(t, tradeOfferID, *op.TradeOfferID)
+	})
+}
+
+func TestReconcileOperations(t *testing.T) {
+	e := newTestEnv(t)
+
+	openOffer := e.listItem(t, "1001")
+	openOp := e.createOperation(t, operation.OperationDeposit, sellerSteamID, "OPEN23", openOffer)
+	openTrade := e.sendDeposit(t, "OPEN23", "1001")
+
+	doneOffer := e.listItem(t, "1002")
+	doneOp := e.createOperation(t, operation.OperationDeposit, sellerSteamID, "DONE45", doneOffer)
+	doneTrade := e.sendDeposit(t, "DONE45", "1002")
+	require.True(t, e.srv.SetOfferState(doneTrade, bot.TradeOfferStateAccepted))
+
+	purchase := e.onSale(t, "2001")
+	require.NoError(t, e.repo.Offer.ChangeStatusByID(t.Context(), offer.OfferReserved.String(), purchase.ID.String()))
+	lostOp := e.createOperation(t, operation.OperationPurchase, buyerSteamID, "LOST67", purchase.ID.String())
+
+	assert.True(t, e.offers.ReconcileOperations(t.Context(), time.Now().Add(time.Second)))
+
+	assert.Equal(t, bot.TradeOfferStateCanceled, e.steamState(openTrade), "an open offer is withdrawn")
+	assert.Equal(t, offer.OfferCanceled, e.offer(t, openOffer).Status)
+	assert.Equal(t, operation.OperationFailed, e.operation(t, openOp).Status)
+
+	assert.Equal(t, operation.OperationSucceeded, e.operation(t, doneOp).Status, "an accepted offer counts as sent")
+	assert.Equal(t, doneTrade, *e.offer(t, doneOffer).SteamTradeId)
+
+	assert.Equal(t, offer.OfferOnSale, e.offer(t, purchase.ID.String()).Status, "a purchase never sent is undone")
+	assert.Equal(t, operation.OperationFailed, e.operation(t, lostOp).Status)
+
+	tradeOfferIDs := map[string]bool{}
+	for len(e.m.Events) > 0 {
+		ev := (<-e.m.Events).(bots.TradeOfferEvent)
+		tradeOfferIDs[ev.Offer.TradeOfferID] = true
+		require.NoError(t, e.tracker.handleTradeOffer(t.Context(), ev))
+	}
+	assert.Equal(t, map[string]bool{openTrade: true, doneTrade: true}, tradeOfferIDs, "found offers go to the tracker")
+	assert.Equal(t, offer.OfferOnSale, e.offer(t, doneOffer).Status)
+}
+
+func TestReconcileOperationsOnceBotComesUp(t *testing.T) {
+	e := newTestEnv(t)
+	offerID := e.listItem(t, "1001")
+	opID := e.createOperation(t, operation.OperationDeposit, sellerSteamID, "ABC234", offerID)
+	startedAt := time.Now().Add(time.Second)
+	e.m.RemoveBot(botSteamID)
+
+	assert.False(t, e.offers.ReconcileOperations(t.Context(), startedAt))
+	assert.Equal(t, operation.OperationPending, e.operation(t, opID).Status, "it waits for the bot")
+	assert.Equal(t, offer.OfferPending, e.offer(t, offerID).Status)
+
+	e.offers.StartReconciliation(t.Context(), startedAt, 10*time.Millisecond)
+	require.NoError(t, e.m.Relogin(t.Context(), botSteamID))
+
+	require.Eventually(t, func() bool {
+		op, err := e.repo.Operation.GetOperation(t.Context(), opID)
+		return err == nil && op.Status == operation.OperationFailed
+	}, 2*time.Second, 10*time.Millisecond, "the operation is reconciled once the bot is online")
+	assert.Equal(t, offer.OfferCanceled, e.offer(t, offerID).Status)
+	e.m.RemoveBot(botSteamID)
+}
@@ -0,0 +1,217 @@
+package service
+
+import (
+	"context"
+	"csTrade/internal/domain/bot"
+	"csTrade/internal/domain/offer"
+	"csTrade/internal/repository"
+	"csTrade/internal/service/bots"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	decisionAccepted = "accepted"
+	decisionDeclined = "declined"
+)
+
+// handleReceived deals with offers other people send to a bot. An offer
+// that gives the bot exactly the items of a pending deposit is accepted in
+// place of the bot's own offer, anything else is declined. Once accepted it
+// completes like any other deposit. A failed accept or decline is returned
+// so the poller reports the offer again.
+func (t *TradeTracker) handleReceived(ctx context.Context, ev bots.TradeOfferEvent) error {
+	o := ev.Offer
+	switch {
+	case o.State == bot.TradeOfferStateAccepted:
+		if err := t.settleAccepted(ctx, ev.BotSteamID, o); err != nil {
+			return err
+		}
+		return t.handleDeposit(ctx, ev.BotSteamID, o)
+	case o.State.IsFinal():
+		return t.handleDeposit(ctx, ev.BotSteamID, o)
+	case o.State != bot.TradeOfferStateActive:
+		return nil
+	}
+
+	b := t.botsManager.GetBotByID(ev.BotSteamID)
+	if b == nil {
+		return fmt.Errorf("err get bot by id %s", ev.BotSteamID)
+	}
+
+	audit := &repository.ReceivedOfferAudit{
+		BotSteamID:     ev.BotSteamID,
+		TradeOfferID:   o.TradeOfferID,
+		PartnerSteamID: bot.SteamID64(o.AccountIDOther),
+		ItemsToGive:    len(o.ItemsToGive),
+		ItemsToReceive: len(o.ItemsToReceive),
+	}
+
+	depositID, reason, err := t.matchDeposit(ctx, ev.BotSteamID, audit.PartnerSteamID, o)
+	if err != nil {
+		return err
+	}
+	audit.Reason = reason
+
+	var actionErr error
+	if depositID != "" {
+		audit.Decision = decisionAccepted
+		actionErr = t.acceptDeposit(ctx, b, o, audit.PartnerSteamID, depositID)
+	} else {
+		audit.Decision = decisionDeclined
+		actionErr = t.botsManager.Do(ctx, b, func() error {
+			return b.DeclineTradeOffer(ctx, o.TradeOfferID)
+		})
+	}
+	if actionErr != nil {
+		msg := actionErr.Error()
+		audit.Error = &msg
+	}
+
+	log.Info().
+		Err(actionErr).
+		Str("bot", ev.BotSteamID).
+		Str("trade_offer_id", o.TradeOfferID).
+		Str("partner", audit.PartnerSteamID).
+		Str("decision", audit.Decision).
+		Str("reason", reason).
+		Msg("received trade offer handled")
+
+	if err := t.repo.ReceivedOffer.CreateAudit(ctx, audit); err != nil {
+		return err
+	}
+	return actionErr
+}
+
+// matchDeposit looks for a pending deposit of the partner on this bot whose
+// items are exactly the ones offered. It returns the bot's own trade offer
+// for that deposit, or a reason to decline.
+func (t *TradeTracker) matchDeposit(ctx context.Context, botSteamID, partnerSteamID string, o bot.TradeOffer) (string, string, error) {
+	if len(o.ItemsToGive) > 0 {
+		return "", "offer requests our items", nil
+	}
+	if len(o.ItemsToReceive) == 0 {
+		return "", "offer is empty", nil
+	}
+
+	for _, item := range o.ItemsToReceive {
+		if item.AppID != bot.CSAppID || item.ContextID != bot.CSContextID {
+			return "", "offer contains non CS items", nil
+		}
+	}
+	offered := sortedAssetIDs(o.ItemsToReceive)
+
+	offersData, err := t.repo.Offer.GetOfferBySellerID(ctx, partnerSteamID)
+	if err != nil {
+		return "", "", err
+	}
+
+	deposits := make(map[string][]string)
+	for _, offerData := range offersData {
+		if offerData.Status != offer.OfferPending || offerData.BotSteamID != botSteamID || offerData.SteamTradeId == nil {
+			continue
+		}
+		deposits[*offerData.SteamTradeId] = append(deposits[*offerData.SteamTradeId], offerData.AssetID)
+	}
+
+	for depositID, assetIDs := range deposits {
+		slices.Sort(assetIDs)
+		if slices.Equal(assetIDs, offered) {
+			return depositID, "matches pending deposit " + depositID, nil
+		}
+	}
+
+	return "", "no matching pending deposit", nil
+}
+
+// acceptDeposit accepts the received offer, then moves the pending listings
+// onto it and withdraws the bot's own offer for the same items. Listings
+// already on the offer mean an earlier attempt accepted it.
+func (t *TradeTracker) acceptDeposit(ctx context.Context, b *bot.SteamBot, o bot.TradeOffer, partnerSteamID, depositID string) error {
+	if depositID != o.TradeOfferID {
+		err := t.botsManager.Do(ctx, b, func() error {
+			_, err := b.AcceptTradeOffer(ctx, o.TradeOfferID, partnerSteamID)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return t.replaceDeposit(ctx, b, o, depositID)
+}
+
+// settleAccepted finishes the bookkeeping of a received offer that was
+// accepted in place of a deposit when it failed right after the accept.
+func (t *TradeTracker) settleAccepted(ctx context.Context, botSteamID string, o bot.TradeOffer) error {
+	b := t.botsManager.GetBotByID(botSteamID)
+	if b == nil {
+		return fmt.Errorf("err get bot by id %s", botSteamID)
+	}
+
+	depositID, _, err := t.matchDeposit(ctx, botSteamID, bot.SteamID64(o.AccountIDOther), o)
+	if err != nil || depositID == "" {
+		return err
+	}
+	return t.replaceDeposit(ctx, b, o, depositID)
+}
+
+func (t *TradeTracker) replaceDeposit(ctx context.Context, b *bot.SteamBot, o bot.TradeOffer, depositID string) error {
+	if depositID != o.TradeOfferID {
+		n, err := t.repo.Offer.MovePendingToSteamTradeID(ctx, depositID, o.TradeOfferID)
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return fmt.Errorf("deposit %s is no longer pending", depositID)
+		}
+		t.botsManager.MoveReservation(depositID, o.TradeOfferID)
+	}
+	return t.withdrawReplaced(ctx, b, o)
+}
+
+// withdrawReplaced cancels the bot's active offers to the same partner for
+// the same items as o. Found by their items, it also works when retried
+// after the listings already moved.
+func (t *TradeTracker) withdrawReplaced(ctx context.Context, b *bot.SteamBot, o bot.TradeOffer) error {
+	var offers *bot.TradeOffers
+	err := t.botsManager.Do(ctx, b, func() error {
+		var err error
+		offers, err = b.GetTradeOffers(ctx, true, false, time.Now())
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("err get replaced deposit offers: %w", err)
+	}
+
+	received := sortedAssetIDs(o.ItemsToReceive)
+	for _, sent := range offers.Sent {
+		if sent.State != bot.TradeOfferStateActive && sent.State != bot.TradeOfferStateNeedsConfirmation {
+			continue
+		}
+		if sent.AccountIDOther != o.AccountIDOther || len(sent.ItemsToGive) > 0 ||
+			!slices.Equal(sortedAssetIDs(sent.ItemsToReceive), received) {
+			continue
+		}
+
+		err := t.botsManager.Do(ctx, b, func() error {
+			return b.DeclineTrade(ctx, sent.TradeOfferID)
+		})
+		if err != nil {
+			return fmt.Errorf("err cancel replaced deposit offer %s: %w", sent.TradeOfferID, err)
+		}
+		log.Info().Str("trade_offer_id", sent.TradeOfferID).Str("replaced_by", o.TradeOfferID).Msg("replaced deposit offer canceled")
+	}
+	return nil
+}
+
+func sortedAssetIDs(items []bot.TradeItem) []string {
+	assetIDs := make([]string, 0, len(items))
+	for _, item := range items {
+		assetIDs = append(assetIDs, item.AssetID)
+	}
+	slices.Sort(assetIDs)
+	return assetIDs
+}
@@ -0,0 +1,124 @@
+//go:build integration
+// +build integration
+
+package service
+
+import (
+	"csTrade/internal/domain/bot"
+	"csTrade/internal/domain/offer"
+	"csTrade/internal/repository"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func (e *testEnv) audits(t *testing.T) []repository.ReceivedOfferAudit {
+	t.Helper()
+
+	audits, err := e.repo.ReceivedOffer.GetAuditByBot(t.Context(), botSteamID, 10)
+	require.NoError(t, err)
+	return audits
+}
+
+func TestReceivedOfferReplacesDeposit(t *testing.T) {
+	e := newTestEnv(t)
+	depositID, offerIDs := e.deposit(t, "ABC234", "1001", "1002")
+	received := e.srv.SendOffer(sellerSteamID, botSteamID, []string{"1002", "1001"}, nil)
+
+	require.NoError(t, e.tracker.handleTradeOffer(t.Context(), e.event(t, received, false)))
+
+	assert.Equal(t, bot.TradeOfferStateAccepted, e.steamState(received))
+	assert.Equal(t, bot.TradeOfferStateCanceled, e.steamState(depositID), "the bot's own offer is withdrawn")
+	for _, offerID := range offerIDs {
+		offerData := e.offer(t, offerID)
+		require.NotNil(t, offerData.SteamTradeId)
+		assert.Equal(t, received, *offerData.SteamTradeId)
+	}
+
+	audits := e.audits(t)
+	require.Len(t, audits, 1)
+	assert.Equal(t, decisionAccepted, audits[0].Decision)
+	assert.Equal(t, "matches pending deposit "+depositID, audits[0].Reason)
+	assert.Equal(t, sellerSteamID, audits[0].PartnerSteamID)
+	assert.Nil(t, audits[0].Error)
+
+	require.NoError(t, e.tracker.handleTradeOffer(t.Context(), e.event(t, received, false)))
+	for _, offerID := range offerIDs {
+		assert.Equal(t, offer.OfferOnSale, e.offer(t, offerID).Status, "the accepted offer completes the deposit")
+	}
+}
+
+func TestReceivedOfferDeclined(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		from    string
+		give    []string
+		request []string
+		reason  string
+	}{
+		{"requests our items", sellerSteamID, []string{"1001"}, []string{"4001"}, "offer requests our items"},
+		{"empty", sellerSteamID, nil, nil, "offer is empty"},
+		{"part of the deposit", sellerSteamID, []string{"1001"}, nil, "no matching pending deposit"},
+		{"other partner", buyerSteamID, []string{"1001", "1002"}, nil, "no matching pending deposit"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			e := newTestEnv(t)
+			e.addItem(botSteamID, "4001")
+			depositID, offerIDs := e.deposit(t, "ABC234", "1001", "1002")
+			received := e.srv.SendOffer(tc.from, botSteamID, tc.give, tc.request)
+
+			require.NoError(t, e.tracker.handleTradeOffer(t.Context(), e.event(t, received, false)))
+
+			assert.Equal(t, bot.TradeOfferStateDeclined, e.steamState(received))
+			assert.Equal(t, bot.TradeOfferStateActive, e.steamState(depositID))
+			audits := e.audits(t)
+			require.Len(t, audits, 1)
+			assert.Equal(t, decisionDeclined, audits[0].Decision)
+			assert.Equal(t, tc.reason, audits[0].Reason)
+
+			offerData := e.offer(t, offerIDs[0])
+			assert.Equal(t, offer.OfferPending, offerData.Status)
+			assert.Equal(t, depositID, *offerData.SteamTradeId)
+		})
+	}
+}
+
+func TestReceivedOfferAcceptRetried(t *testing.T) {
+	e := newTestEnv(t)
+	depositID, offerIDs := e.deposit(t, "ABC234", "1001")
+	received := e.srv.SendOffer(sellerSteamID, botSteamID, []string{"1001"}, nil)
+	e.srv.FailRequests("/tradeoffer/"+received+"/accept", 1, http.StatusBadGateway, nil)
+
+	err := e.tracker.handleTradeOffer(t.Context(), e.event(t, received, false))
+	require.Error(t, err, "the poller reports the offer again")
+	assert.Equal(t, bot.TradeOfferStateActive, e.steamState(received))
+	assert.Equal(t, bot.TradeOfferStateActive, e.steamState(depositID))
+	assert.Equal(t, depositID, *e.offer(t, offerIDs[0]).SteamTradeId, "the listing stays on the deposit")
+	audits := e.audits(t)
+	require.Len(t, audits, 1)
+	assert.NotNil(t, audits[0].Error)
+
+	require.NoError(t, e.tracker.handleTradeOffer(t.Context(), e.event(t, received, false)))
+	assert.Equal(t, bot.TradeOfferStateAccepted, e.steamState(received))
+	assert.Equal(t, bot.TradeOfferStateCanceled, e.steamState(depositID))
+	assert.Equal(t, received, *e.offer(t, offerIDs[0]).SteamTradeId)
+}
+
+// An offer accepted before its listings moved, because the process
+// stopped in between, is settled once the poller reports it accepted.
+func TestReceivedOfferAcceptedBeforeBookkeeping(t *testing.T) {
+	e := newTestEnv(t)
+	depositID, offerIDs := e.deposit(t, "ABC234", "1001")
+	received := e.srv.SendOffer(sellerSteamID, botSteamID, []string{"1001"}, nil)
+	require.True(t, e.srv.SetOfferState(received, bot.TradeOfferStateAccepted))
+
+	require.NoError(t, e.tracker.handleTradeOffer(t.Context(), e.event(t, received, false)))
+
+	assert.Equal(t, bot.TradeOfferStateCanceled, e.steamState(depositID))
+	offerData := e.offer(t, offerIDs[0])
+	assert.Equal(t, received, *offerData.SteamTradeId)
+	assert.Equal(t, offer.OfferOnSale, offerData.Status)
+	assert.NotNil(t, offerData.BotAssetID)
+}
@@ -0,0 +1,88 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"csTrade/internal/domain/offer"
+	"csTrade/internal/domain/transaction"
+	"fmt"
+	"strings"
+)
+
+// securityCodeAlphabet leaves out characters that are easy to misread.
+const (
+	securityCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+	securityCodeLength   = 6
+)
+
+// newSecurityCode returns the code put into a trade offer message so users
+// can tell our offers from impersonators.
+func newSecurityCode() string {
+	b := make([]byte, securityCodeLength)
+	rand.Read(b)
+	for i := range b {
+		b[i] = securityCodeAlphabet[int(b[i])%len(securityCodeAlphabet)]
+	}
+	return string(b)
+}
+
+func securityMessage(code string) string {
+	return fmt.Sprintf("csTrade security code: %s. Accept only if it matches the code shown on the site.", code)
+}
+
+// VerifyTradeOffer checks that the trade offer was sent by one of our bots
+// for a pending deposit or delivery and still carries its security code.
+// The answer never includes the code, so it cannot be read off this check.
+func (of *OfferService) VerifyTradeOffer(ctx context.Context, tradeOfferID string) (*offer.TradeVerification, error) {
+	res := &offer.TradeVerification{TradeOfferID: tradeOfferID}
+
+	botSteamID, code, operation, err := of.pendingOperation(ctx, tradeOfferID)
+	if err != nil {
+		return nil, err
+	}
+	if operation == "" {
+		return res, nil
+	}
+
+	b := of.botsManager.GetBotByID(botSteamID)
+	if b == nil {
+		return nil, fmt.Errorf("err get bot by id")
+	}
+
+	sent, err := b.GetTradeOffer(ctx, tradeOfferID)
+	if err != nil {
+		return nil, err
+	}
+
+	if sent.IsOurOffer && !sent.State.IsFinal() && code != "" && strings.Contains(sent.Message, code) {
+		res.Verified = true
+		res.Operation = operation
+	}
+	return res, nil
+}
+
+// pendingOperation finds the deposit or delivery waiting on the trade offer.
+// operation is empty when there is none.
+func (of *OfferService) pendingOperation(ctx context.Context, tradeOfferID string) (botSteamID, code, operation string, err error) {
+	offersData, err := of.repo.Offer.GetOffersBySteamTradeID(ctx, tradeOfferID)
+	if err != nil {
+		return "", "", "", err
+	}
+	for _, offerData := range offersData {
+		if offerData.Status == offer.OfferPending && offerData.SecurityCode != nil {
+			return offerData.BotSteamID, *offerData.SecurityCode, "deposit", nil
+		}
+	}
+
+	transactions, err := of.repo.Transaction.GetTransactionsBySteamTradeID(ctx, tradeOfferID)
+	if err != nil {
+		return "", "", "", err
+	}
+	for _, tr := range transactions {
+		if tr.Status == transaction.TransactionPending && tr.SecurityCode != nil {
+			return tr.BotID, *tr.SecurityCode, "delivery", nil
+		}
+	}
+
+	return "", "", "", nil
+}
@@ -0,0 +1,70 @@
+//go:build integration
+// +build integration
+
+package service
+
+import (
+	"csTrade/internal/domain/bot"
+	"csTrade/internal/domain/offer"
+	"csTrade/internal/domain/transaction"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyTradeOfferDeposit(t *testing.T) {
+	e := newTestEnv(t)
+	tradeOfferID, offerIDs := e.deposit(t, "ABC234", "1001")
+
+	res, err := e.offers.VerifyTradeOffer(t.Context(), tradeOfferID)
+	require.NoError(t, err)
+	assert.Equal(t, &offer.TradeVerification{TradeOfferID: tradeOfferID, Verified: true, Operation: "deposit"}, res)
+
+	require.NoError(t, e.repo.Offer.SetSecurityCode(t.Context(), offerIDs[0], "ZZZ999"))
+	res, err = e.offers.VerifyTradeOffer(t.Context(), tradeOfferID)
+	require.NoError(t, err)
+	assert.Equal(t, &offer.TradeVerification{TradeOfferID: tradeOfferID}, res, "the message lacks the code")
+
+	require.NoError(t, e.repo.Offer.SetSecurityCode(t.Context(), offerIDs[0], "ABC234"))
+	require.True(t, e.srv.SetOfferState(tradeOfferID, bot.TradeOfferStateDeclined))
+	res, err = e.offers.VerifyTradeOffer(t.Context(), tradeOfferID)
+	require.NoError(t, err)
+	assert.False(t, res.Verified, "a finished offer is no longer vouched for")
+	assert.Empty(t, res.Operation)
+}
+
+func TestVerifyTradeOfferDelivery(t *testing.T) {
+	e := newTestEnv(t)
+	offerData := e.onSale(t, "2001")
+	require.NoError(t, e.repo.Offer.ChangeStatusByID(t.Context(), offer.OfferReserved.String(), offerData.ID.String()))
+	code := "XYZ789"
+	tradeOfferID := e.sendPurchase(t, code, "2001")
+	require.NoError(t, e.repo.Transaction.CreateTransaction(t.Context(), transaction.TransactionDB{
+		OfferID:      offerData.ID,
+		SellerID:     sellerSteamID,
+		BuyerID:      buyerSteamID,
+		BotID:        botSteamID,
+		Status:       transaction.TransactionPending,
+		Price:        offerData.Price,
+		SteamTradeID: &tradeOfferID,
+		SecurityCode: &code,
+	}))
+
+	res, err := e.offers.VerifyTradeOffer(t.Context(), tradeOfferID)
+	require.NoError(t, err)
+	assert.Equal(t, &offer.TradeVerification{TradeOfferID: tradeOfferID, Verified: true, Operation: "delivery"}, res)
+}
+
+func TestVerifyTradeOfferUnknown(t *testing.T) {
+	e := newTestEnv(t)
+	// Offers between users never went through a bot.
+	e.addItem(sellerSteamID, "1001")
+	foreign := e.srv.SendOffer(sellerSteamID, buyerSteamID, []string{"1001"}, nil)
+
+	for _, tradeOfferID := range []string{foreign, "999999"} {
+		res, err := e.offers.VerifyTradeOffer(t.Context(), tradeOfferID)
+		require.NoError(t, err)
+		assert.Equal(t, &offer.TradeVerification{TradeOfferID: tradeOfferID}, res)
+	}
+}
@@ -0,0 +1,183 @@
+//go:build integration
+// +build integration
+
+package service
+
+import (
+	"context"
+	"csTrade/internal"
+	"csTrade/internal/domain/bot"
+	"csTrade/internal/domain/bot/fakesteam"
+	"csTrade/internal/domain/offer"
+	"csTrade/internal/domain/user"
+	"csTrade/internal/repository"
+	"csTrade/internal/service/bots"
+	"database/sql"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/pressly/goose/v3"
+	"github.com/stretchr/testify/require"
+	tcpostgres "github.com/testcontainers/testcontainers-go/modules/postgres"
+)
+
+func setupTestDB(t *testing.T) *pgxpool.Pool {
+	ctx := context.Background()
+
+	pgContainer, err := tcpostgres.Run(ctx,
+		"postgres:latest",
+		tcpostgres.WithDatabase("test"),
+		tcpostgres.WithUsername("user"),
+		tcpostgres.WithPassword("password"),
+		tcpostgres.BasicWaitStrategies(),
+	)
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		_ = pgContainer.Terminate(ctx)
+	})
+
+	connStr, err := pgContainer.ConnectionString(ctx, "sslmode=disable")
+	require.NoError(t, err)
+
+	sqlDB, err := sql.Open("pgx", connStr)
+	require.NoError(t, err)
+
+	err = goose.Up(sqlDB, "../../sql/migration")
+	require.NoError(t, err)
+
+	pool, err := pgxpool.New(ctx, connStr)
+	require.NoError(t, err)
+	t.Cleanup(pool.Close)
+
+	return pool
+}
+
+// testEnv is a database with the seller and buyer stored, a fake Steam and
+// a manager with one bot logged in.
+type testEnv struct {
+	srv     *fakesteam.Server
+	m       *bots.BotManager
+	bot     *bot.SteamBot
+	repo    *repository.Repository
+	tracker *TradeTracker
+	offers  *OfferService
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+
+	srv, m := newSteam(t)
+	repo := repository.NewRepository(setupTestDB(t))
+	e := &testEnv{
+		srv:     srv,
+		m:       m,
+		bot:     m.GetBotByID(botSteamID),
+		repo:    repo,
+		tracker: NewTradeTracker(repo, m),
+		offers:  NewOfferService(repo, m, bots.NewDispatcher(m, 1, 1), EscrowReject),
+	}
+
+	for _, steamID := range []string{sellerSteamID, buyerSteamID} {
+		require.NoError(t, repo.User.CreateUser(t.Context(), &user.UserCreateReq{
+			SteamID:   steamID,
+			Username:  "user" + steamID,
+			Name:      "user" + steamID,
+			Email:     steamID + "@example.com",
+			TradeUrl:  srv.TradeURL(steamID),
+			AvatarURL: "https://example.com/avatar.png",
+		}))
+	}
+	return e
+}
+
+// addItem puts a CS item into the inventory of steamID.
+func (e *testEnv) addItem(steamID, assetID string) {
+	e.srv.AddInventoryItem(steamID, internal.Asset{
+		Appid:      bot.CSAppID,
+		Contextid:  bot.CSContextID,
+		Assetid:    assetID,
+		Classid:    "310776",
+		Instanceid: "0",
+		Amount:     "1",
+	}, internal.Description{Appid: bot.CSAppID, Classid: "310776", Instanceid: "0", Tradable: 1})
+}
+
+// listItem stores a pending listing of the seller's item.
+func (e *testEnv) listItem(t *testing.T, assetID string) string {
+	t.Helper()
+
+	offerID, err := e.repo.Offer.CreateOffer(t.Context(), &offer.OfferCreateReq{
+		SellerID:   sellerSteamID,
+		Price:      10,
+		AssetID:    assetID,
+		ClassID:    "310776",
+		InstanceID: "0",
+		Name:       "AK-47 | Redline",
+		FullName:   "AK-47 | Redline (Field-Tested)",
+		IconURL:    "icon",
+		NameColor:  "D2D2D2",
+		TagType:    "Rifle",
+	})
+	require.NoError(t, err)
+	return offerID
+}
+
+// deposit lists the seller's items and has the bot ask for them with the
+// security code in the message, the way the dispatcher records a send.
+func (e *testEnv) deposit(t *testing.T, code string, assetIDs ...string) (string, []string) {
+	t.Helper()
+
+	assets := make([]bot.Asset, 0, len(assetIDs))
+	for _, assetID := range assetIDs {
+		e.addItem(sellerSteamID, assetID)
+		assets = append(assets, bot.CSAsset(assetID))
+	}
+	tradeOfferID, err := e.bot.ReceiveFromUser(t.Context(), assets, e.srv.TradeURL(sellerSteamID), sellerSteamID, securityMessage(code))
+	require.NoError(t, err)
+
+	offerIDs := make([]string, 0, len(assetIDs))
+	for _, assetID := range assetIDs {
+		offerID := e.listItem(t, assetID)
+		require.NoError(t, e.repo.Offer.UpdateOfferAfterReceive(t.Context(), botSteamID, tradeOfferID, offerID))
+		require.NoError(t, e.repo.Offer.SetSecurityCode(t.Context(), offerID, code))
+		offerIDs = append(offerIDs, offerID)
+	}
+	return tradeOfferID, offerIDs
+}
+
+// onSale stores a listing whose item already sits in the bot inventory.
+func (e *testEnv) onSale(t *testing.T, assetID string) *offer.OfferDB {
+	t.Helper()
+
+	e.addItem(botSteamID, assetID)
+	offerID := e.listItem(t, assetID)
+	require.NoError(t, e.repo.Offer.AddBotSteamID(t.Context(), botSteamID, offerID))
+	require.NoError(t, e.repo.Offer.SetBotAssetID(t.Context(), offerID, assetID))
+	require.NoError(t, e.repo.Offer.ChangeStatusByID(t.Context(), offer.OfferOnSale.String(), offerID))
+	return e.offer(t, offerID)
+}
+
+// event is the offer as the bot sees it on its next poll.
+func (e *testEnv) event(t *testing.T, tradeOfferID string, sent bool) bots.TradeOfferEvent {
+	t.Helper()
+
+	o, err := e.bot.GetTradeOffer(t.Context(), tradeOfferID)
+	require.NoError(t, err)
+	return bots.TradeOfferEvent{BotSteamID: botSteamID, Offer: *o, Sent: sent}
+}
+
+func (e *testEnv) offer(t *testing.T, offerID string) *offer.OfferDB {
+	t.Helper()
+
+	offerData, err := e.repo.Offer.GetByID(t.Context(), offerID)
+	require.NoError(t, err)
+	return offerData
+}
+
+// steamState is the state of the offer on Steam.
+func (e *testEnv) steamState(tradeOfferID string) bot.TradeOfferState {
+	o, _ := e.srv.Offer(tradeOfferID)
+	return o.State
+}
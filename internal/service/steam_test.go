@@ -0,0 +1,61 @@
+package service
+
+import (
+	"context"
+	"csTrade/internal/domain/bot"
+	"csTrade/internal/domain/bot/fakesteam"
+	"csTrade/internal/repository"
+	"csTrade/internal/repository/memrepo"
+	"csTrade/internal/service/bots"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	botSteamID    = "76561198000000100"
+	sellerSteamID = "76561198000000200"
+	buyerSteamID  = "76561198000000300"
+	heldSteamID   = "76561198000000400"
+	sharedSecret  = "cnOgv/KdpLoP6Nbh0GMkXkPXALQ="
+)
+
+// newSteam starts a fake Steam with a seller, a buyer, an account without
+// mobile authenticator and a manager that logged one bot in.
+func newSteam(t *testing.T) (*fakesteam.Server, *bots.BotManager) {
+	t.Helper()
+
+	srv := fakesteam.New()
+	t.Cleanup(srv.Close)
+
+	srv.AddAccount(fakesteam.Account{
+		SteamID:        botSteamID,
+		Username:       "bot0",
+		Password:       "hunter2",
+		SharedSecret:   sharedSecret,
+		IdentitySecret: sharedSecret,
+	})
+	srv.AddAccount(fakesteam.Account{SteamID: sellerSteamID, Username: "seller", TradeToken: "sellertk"})
+	srv.AddAccount(fakesteam.Account{SteamID: buyerSteamID, Username: "buyer", TradeToken: "buyertkn"})
+	srv.AddAccount(fakesteam.Account{SteamID: heldSteamID, Username: "nomobile", TradeToken: "heldtokn", TradeHold: 15 * 24 * time.Hour})
+
+	store := memrepo.NewBots()
+	store.CreateBots(context.Background(), &repository.Bot{
+		SteamID:        botSteamID,
+		Username:       "bot0",
+		Password:       "hunter2",
+		SharedSecret:   sharedSecret,
+		IdentitySecret: sharedSecret,
+		DeviceID:       "android:0a1b2c3d",
+		Enabled:        true,
+	})
+
+	m := bots.NewBotManager(store, nil,
+		bot.WithEndpoints(srv.Endpoints()),
+		bot.WithRetryPolicy(bot.RetryPolicy{MaxAttempts: 1}),
+	)
+	m.InitBots(t.Context(), 1)
+	require.True(t, m.IsOnline(botSteamID), "the bot logs in")
+	return srv, m
+}
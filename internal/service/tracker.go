@@ -0,0 +1,243 @@
+package service
+
+import (
+	"context"
+	"csTrade/internal/domain/bot"
+	"csTrade/internal/domain/offer"
+	"csTrade/internal/domain/transaction"
+	"csTrade/internal/repository"
+	"csTrade/internal/service/bots"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// TradeTracker consumes bot events and moves offers and transactions along
+// as their Steam trade offers change state.
+type TradeTracker struct {
+	repo        *repository.Repository
+	botsManager *bots.BotManager
+}
+
+func NewTradeTracker(repo *repository.Repository, botsManager *bots.BotManager) *TradeTracker {
+	return &TradeTracker{repo: repo, botsManager: botsManager}
+}
+
+func (t *TradeTracker) Run(ctx context.Context, events <-chan interface{}) {
+	for {
+		select {
+		case <-ctx.Done():
+			log.Info().Msg("trade tracker stopped")
+			return
+		case e := <-events:
+			switch ev := e.(type) {
+			case bots.TradeOfferEvent:
+				err := t.handleTradeOffer(ctx, ev)
+				if err != nil {
+					log.Error().Err(err).
+						Str("bot", ev.BotSteamID).
+						Str("trade_offer_id", ev.Offer.TradeOfferID).
+						Msg("err handle trade offer event")
+				}
+				ev.Handled(err)
+			default:
+				log.Warn().Any("event", e).Msg("unknown bot event")
+			}
+		}
+	}
+}
+
+func (t *TradeTracker) handleTradeOffer(ctx context.Context, ev bots.TradeOfferEvent) error {
+	log.Info().
+		Str("bot", ev.BotSteamID).
+		Str("trade_offer_id", ev.Offer.TradeOfferID).
+		Str("prev_state", ev.PrevState.String()).
+		Str("state", ev.Offer.State.String()).
+		Msg("trade offer state changed")
+
+	if !ev.Sent {
+		return t.handleReceived(ctx, ev)
+	}
+
+	if ev.Offer.State == bot.TradeOfferStateInEscrow && ev.Offer.EscrowEndDate > 0 {
+		if err := t.recordEscrow(ctx, ev.Offer); err != nil {
+			log.Warn().Err(err).Str("trade_offer_id", ev.Offer.TradeOfferID).Msg("err record escrow end date")
+		}
+	}
+
+	if len(ev.Offer.ItemsToGive) > 0 {
+		return t.handleDelivery(ctx, ev.BotSteamID, ev.Offer)
+	}
+	return t.handleDeposit(ctx, ev.BotSteamID, ev.Offer)
+}
+
+func (t *TradeTracker) handleDeposit(ctx context.Context, botSteamID string, o bot.TradeOffer) error {
+	if o.State.IsFinal() {
+		t.botsManager.SettleDeposit(o.TradeOfferID, o.State == bot.TradeOfferStateAccepted)
+	}
+
+	switch {
+	case o.State == bot.TradeOfferStateAccepted:
+		return t.completeDeposit(ctx, botSteamID, o)
+	case !o.State.IsFinal():
+		return nil
+	}
+
+	n, err := t.repo.Offer.ChangeStatusBySteamTradeID(ctx, o.TradeOfferID, offer.OfferPending.String(), offer.OfferCanceled.String())
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		log.Info().Str("trade_offer_id", o.TradeOfferID).Str("status", offer.OfferCanceled.String()).Msg("deposit offer updated")
+	}
+
+	return nil
+}
+
+// completeDeposit puts the offers of the trade on sale and remembers the
+// asset IDs their items got in the bot inventory. IDs missing from the
+// trade receipt are resolved again before delivery.
+func (t *TradeTracker) completeDeposit(ctx context.Context, botSteamID string, o bot.TradeOffer) error {
+	offersData, err := t.repo.Offer.GetOffersBySteamTradeID(ctx, o.TradeOfferID)
+	if err != nil {
+		return err
+	}
+
+	pending := make([]offer.OfferDB, 0, len(offersData))
+	assetIDs := make([]string, 0, len(offersData))
+	for _, offerData := range offersData {
+		if offerData.Status == offer.OfferPending {
+			pending = append(pending, offerData)
+			assetIDs = append(assetIDs, offerData.AssetID)
+		}
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+
+	var newAssetIDs map[string]string
+	if b := t.botsManager.GetBotByID(botSteamID); b != nil {
+		newAssetIDs, err = botAssetIDs(ctx, b, o.TradeID, assetIDs...)
+		if err != nil {
+			log.Warn().Err(err).Str("trade_offer_id", o.TradeOfferID).Msg("err resolve bot asset id")
+		}
+	}
+
+	return t.repo.WithTx(ctx, func(r *repository.Repository) error {
+		for _, offerData := range pending {
+			n, err := r.Offer.ChangeStatusByIDFrom(ctx, offerData.ID.String(), offer.OfferPending.String(), offer.OfferOnSale.String())
+			if err != nil {
+				return err
+			}
+			if n == 0 {
+				continue
+			}
+
+			newAssetID := newAssetIDs[offerData.AssetID]
+			if newAssetID != "" {
+				if err := r.Offer.SetBotAssetID(ctx, offerData.ID.String(), newAssetID); err != nil {
+					return err
+				}
+			}
+
+			log.Info().
+				Str("trade_offer_id", o.TradeOfferID).
+				Str("offer", offerData.ID.String()).
+				Str("bot_asset_id", newAssetID).
+				Str("status", offer.OfferOnSale.String()).
+				Msg("deposit offer updated")
+		}
+		return nil
+	})
+}
+
+func (t *TradeTracker) handleDelivery(ctx context.Context, botSteamID string, o bot.TradeOffer) error {
+	if !o.State.IsFinal() {
+		return nil
+	}
+
+	transactions, err := t.repo.Transaction.GetTransactionsBySteamTradeID(ctx, o.TradeOfferID)
+	if err != nil {
+		return err
+	}
+
+	trStatus, offerStatus := transaction.TransactionFailed, offer.OfferOnSale
+	if o.State == bot.TradeOfferStateAccepted {
+		trStatus, offerStatus = transaction.TransactionCompleted, offer.OfferSold
+	}
+
+	// Only the run that moved transactions off pending lowers the bot's
+	// item count, a repeated event must not count the items twice.
+	delivered := 0
+	err = t.repo.WithTx(ctx, func(r *repository.Repository) error {
+		delivered = 0
+		for _, tr := range transactions {
+			if tr.Status != transaction.TransactionPending {
+				continue
+			}
+
+			n, err := r.Transaction.UpdateTransactionStatusFrom(ctx, tr.ID.String(),
+				transaction.TransactionPending.GetString(), trStatus.GetString())
+			if err != nil {
+				return err
+			}
+			if n == 0 {
+				continue
+			}
+			delivered++
+
+			_, err = r.Offer.ChangeStatusByIDFrom(ctx, tr.OfferID.String(), offer.OfferReserved.String(), offerStatus.String())
+			if err != nil {
+				return fmt.Errorf("err change offer status after delivery: %w", err)
+			}
+
+			log.Info().
+				Str("trade_offer_id", o.TradeOfferID).
+				Str("transaction", tr.ID.String()).
+				Str("status", trStatus.GetString()).
+				Msg("delivery transaction updated")
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if o.State == bot.TradeOfferStateAccepted && delivered > 0 {
+		t.botsManager.RemoveItems(botSteamID, len(o.ItemsToGive))
+	}
+	return nil
+}
+
+// recordEscrow replaces the expected escrow end date stored when the trade
+// was created with the one Steam reports for the held trade.
+func (t *TradeTracker) recordEscrow(ctx context.Context, o bot.TradeOffer) error {
+	escrowEndDate := time.Unix(o.EscrowEndDate, 0)
+
+	var offerIDs []string
+	if len(o.ItemsToGive) > 0 {
+		transactions, err := t.repo.Transaction.GetTransactionsBySteamTradeID(ctx, o.TradeOfferID)
+		if err != nil {
+			return err
+		}
+		for _, tr := range transactions {
+			offerIDs = append(offerIDs, tr.OfferID.String())
+		}
+	} else {
+		offersData, err := t.repo.Offer.GetOffersBySteamTradeID(ctx, o.TradeOfferID)
+		if err != nil {
+			return err
+		}
+		for _, offerData := range offersData {
+			offerIDs = append(offerIDs, offerData.ID.String())
+		}
+	}
+
+	for _, offerID := range offerIDs {
+		if err := t.repo.Offer.SetEscrowEndDate(ctx, offerID, escrowEndDate); err != nil {
+			return err
+		}
+	}
+	return nil
+}
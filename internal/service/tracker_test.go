@@ -0,0 +1,158 @@
+//go:build integration
+// +build integration
+
+package service
+
+import (
+	"context"
+	"csTrade/internal/domain/bot"
+	"csTrade/internal/domain/offer"
+	"csTrade/internal/domain/transaction"
+	"csTrade/internal/repository"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrackerCompletesDeposit(t *testing.T) {
+	e := newTestEnv(t)
+	tradeOfferID, offerIDs := e.deposit(t, "ABC234", "1001", "1002")
+
+	require.True(t, e.srv.SetOfferState(tradeOfferID, bot.TradeOfferStateAccepted))
+	require.NoError(t, e.tracker.handleTradeOffer(t.Context(), e.event(t, tradeOfferID, true)))
+
+	for _, offerID := range offerIDs {
+		offerData := e.offer(t, offerID)
+		assert.Equal(t, offer.OfferOnSale, offerData.Status)
+		require.NotNil(t, offerData.BotAssetID, "the new asset id is stored")
+		assert.NotEqual(t, offerData.AssetID, *offerData.BotAssetID)
+	}
+
+	require.NoError(t, e.tracker.handleTradeOffer(t.Context(), e.event(t, tradeOfferID, true)), "a repeated event changes nothing")
+	assert.Equal(t, offer.OfferOnSale, e.offer(t, offerIDs[0]).Status)
+}
+
+func TestTrackerCancelsDeclinedDeposit(t *testing.T) {
+	e := newTestEnv(t)
+	tradeOfferID, offerIDs := e.deposit(t, "ABC234", "1001")
+
+	require.NoError(t, e.tracker.handleTradeOffer(t.Context(), e.event(t, tradeOfferID, true)))
+	assert.Equal(t, offer.OfferPending, e.offer(t, offerIDs[0]).Status, "an active offer keeps the listing pending")
+
+	require.True(t, e.srv.SetOfferState(tradeOfferID, bot.TradeOfferStateDeclined))
+	require.NoError(t, e.tracker.handleTradeOffer(t.Context(), e.event(t, tradeOfferID, true)))
+	assert.Equal(t, offer.OfferCanceled, e.offer(t, offerIDs[0]).Status)
+}
+
+func TestTrackerSettlesDelivery(t *testing.T) {
+	for _, tc := range []struct {
+		state       bot.TradeOfferState
+		trStatus    transaction.TransactionStatus
+		offerStatus offer.OfferStatus
+	}{
+		{bot.TradeOfferStateAccepted, transaction.TransactionCompleted, offer.OfferSold},
+		{bot.TradeOfferStateDeclined, transaction.TransactionFailed, offer.OfferOnSale},
+	} {
+		t.Run(tc.state.String(), func(t *testing.T) {
+			e := newTestEnv(t)
+			offerData := e.onSale(t, "2001")
+			require.NoError(t, e.repo.Offer.ChangeStatusByID(t.Context(), offer.OfferReserved.String(), offerData.ID.String()))
+
+			code := "XYZ789"
+			tradeOfferID, err := e.bot.SendToBuyer(t.Context(), []bot.Asset{bot.CSAsset("2001")}, e.srv.TradeURL(buyerSteamID), buyerSteamID, securityMessage(code))
+			require.NoError(t, err)
+			require.NoError(t, e.repo.Transaction.CreateTransaction(t.Context(), transaction.TransactionDB{
+				OfferID:      offerData.ID,
+				SellerID:     sellerSteamID,
+				BuyerID:      buyerSteamID,
+				BotID:        botSteamID,
+				Status:       transaction.TransactionPending,
+				Price:        offerData.Price,
+				SteamTradeID: &tradeOfferID,
+				SecurityCode: &code,
+			}))
+
+			require.True(t, e.srv.SetOfferState(tradeOfferID, tc.state))
+			require.NoError(t, e.tracker.handleTradeOffer(t.Context(), e.event(t, tradeOfferID, true)))
+
+			transactions, err := e.repo.Transaction.GetTransactionsBySteamTradeID(t.Context(), tradeOfferID)
+			require.NoError(t, err)
+			require.Len(t, transactions, 1)
+			assert.Equal(t, tc.trStatus, transactions[0].Status)
+			assert.Equal(t, tc.offerStatus, e.offer(t, offerData.ID.String()).Status)
+		})
+	}
+}
+
+func (e *testEnv) skinCount() int {
+	for _, st := range e.m.Statuses() {
+		if st.SteamID == botSteamID {
+			return st.SkinCount
+		}
+	}
+	return -1
+}
+
+func TestTrackerCountsDeliveryOnce(t *testing.T) {
+	e := newTestEnv(t)
+	offerData := e.onSale(t, "2001")
+	e.addItem(botSteamID, "2002")
+	e.addItem(botSteamID, "2003")
+	e.m.RefreshInventories(t.Context())
+	require.Equal(t, 3, e.skinCount())
+
+	require.NoError(t, e.repo.Offer.ChangeStatusByID(t.Context(), offer.OfferReserved.String(), offerData.ID.String()))
+	code := "XYZ789"
+	tradeOfferID, err := e.bot.SendToBuyer(t.Context(), []bot.Asset{bot.CSAsset("2001")}, e.srv.TradeURL(buyerSteamID), buyerSteamID, securityMessage(code))
+	require.NoError(t, err)
+	require.NoError(t, e.repo.Transaction.CreateTransaction(t.Context(), transaction.TransactionDB{
+		OfferID:      offerData.ID,
+		SellerID:     sellerSteamID,
+		BuyerID:      buyerSteamID,
+		BotID:        botSteamID,
+		Status:       transaction.TransactionPending,
+		Price:        offerData.Price,
+		SteamTradeID: &tradeOfferID,
+		SecurityCode: &code,
+	}))
+	require.True(t, e.srv.SetOfferState(tradeOfferID, bot.TradeOfferStateAccepted))
+
+	for range 2 {
+		require.NoError(t, e.tracker.handleTradeOffer(t.Context(), e.event(t, tradeOfferID, true)))
+	}
+	assert.Equal(t, 2, e.skinCount(), "a repeated event does not count the delivered item again")
+}
+
+// A received offer whose decline fails stays with the poller until the
+// tracker handled it.
+func TestTrackerRetriesFailedEvents(t *testing.T) {
+	e := newTestEnv(t)
+	e.addItem(botSteamID, "3002")
+	tradeOfferID := e.srv.SendOffer(sellerSteamID, botSteamID, nil, []string{"3002"})
+	e.srv.FailRequests("/tradeoffer/"+tradeOfferID+"/decline", 1, http.StatusBadGateway, nil)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	t.Cleanup(cancel)
+	go e.tracker.Run(ctx, e.m.Events)
+	e.m.StartPollers(10*time.Millisecond, time.Hour)
+
+	var audits []repository.ReceivedOfferAudit
+	require.Eventually(t, func() bool {
+		var err error
+		audits, err = e.repo.ReceivedOffer.GetAuditByBot(t.Context(), botSteamID, 10)
+		return err == nil && len(audits) == 2
+	}, 2*time.Second, 10*time.Millisecond, "the decline is retried")
+
+	assert.Equal(t, bot.TradeOfferStateDeclined, e.steamState(tradeOfferID))
+	assert.NotNil(t, audits[1].Error, "the failed decline is audited")
+	assert.Nil(t, audits[0].Error)
+	for _, audit := range audits {
+		assert.Equal(t, decisionDeclined, audit.Decision)
+		assert.Equal(t, "offer requests our items", audit.Reason)
+	}
+	assert.Equal(t, 2, e.srv.Requests("/tradeoffer/"+tradeOfferID+"/decline"))
+	e.m.RemoveBot(botSteamID)
+}
@@ -2,18 +2,21 @@ package service
 
 import (
 	"context"
+	"csTrade/internal/domain/offer"
 	"csTrade/internal/domain/user"
 	"csTrade/internal/repository"
+	"csTrade/internal/service/bots"
 
 	"github.com/rs/zerolog/log"
 )
 
 type UserService struct {
-	repo *repository.Repository
+	repo        *repository.Repository
+	botsManager *bots.BotManager
 }
 
-func NewUserService(repo *repository.Repository) *UserService {
-	return &UserService{repo: repo}
+func NewUserService(repo *repository.Repository, botsManager *bots.BotManager) *UserService {
+	return &UserService{repo: repo, botsManager: botsManager}
 }
 
 func (of *UserService) CreateUser(ctx context.Context, req *user.UserCreateReq) error {
@@ -25,3 +28,12 @@ func (of *UserService) CreateUser(ctx context.Context, req *user.UserCreateReq)
 
 	return nil
 }
+
+func (of *UserService) GetInventory(ctx context.Context, steamID string) ([]offer.InventoryItem, error) {
+	user, err := of.repo.User.GetUserBySteamId(ctx, steamID)
+	if err != nil {
+		return nil, err
+	}
+
+	return fetchTradableItems(ctx, of.botsManager, user.SteamID)
+}
@@ -55,6 +55,8 @@ type InventoryResponse struct {
 	TotalInventoryCount int           `json:"total_inventory_count"`
 	Success             int           `json:"success"`
 	Descriptions        []Description `json:"descriptions"`
+	MoreItems           int           `json:"more_items"`
+	LastAssetid         string        `json:"last_assetid"`
 }
 
 type Asset struct {
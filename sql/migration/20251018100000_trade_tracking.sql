@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TYPE offer_status ADD VALUE IF NOT EXISTS 'pending' BEFORE 'onsale';
+ALTER TYPE transaction_status ADD VALUE IF NOT EXISTS 'pending';
+
+ALTER TABLE transactions ADD COLUMN steam_trade_id TEXT;
+CREATE INDEX idx_transactions_steam_trade_id ON transactions (steam_trade_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_transactions_steam_trade_id;
+ALTER TABLE transactions DROP COLUMN IF EXISTS steam_trade_id;
+-- enum values cannot be dropped without recreating the types
+-- +goose StatementEnd
@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE bots ADD PRIMARY KEY (steam_id);
+
+CREATE TABLE bot_sessions (
+    steam_id TEXT PRIMARY KEY REFERENCES bots (steam_id) ON DELETE CASCADE,
+    session BYTEA NOT NULL,
+    access_token_expires_at TIMESTAMPTZ,
+    refresh_token_expires_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS bot_sessions;
+ALTER TABLE bots DROP CONSTRAINT IF EXISTS bots_pkey;
+-- +goose StatementEnd
@@ -0,0 +1,9 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE offers ADD COLUMN bot_asset_id TEXT;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE offers DROP COLUMN IF EXISTS bot_asset_id;
+-- +goose StatementEnd
@@ -0,0 +1,9 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE offers ADD COLUMN escrow_end_date TIMESTAMPTZ;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE offers DROP COLUMN IF EXISTS escrow_end_date;
+-- +goose StatementEnd
@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE offers ADD COLUMN security_code TEXT;
+ALTER TABLE transactions ADD COLUMN security_code TEXT;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE transactions DROP COLUMN IF EXISTS security_code;
+ALTER TABLE offers DROP COLUMN IF EXISTS security_code;
+-- +goose StatementEnd
@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE received_offer_audit (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    bot_steam_id TEXT NOT NULL,
+    trade_offer_id TEXT NOT NULL,
+    partner_steam_id TEXT NOT NULL,
+    decision TEXT NOT NULL,
+    reason TEXT NOT NULL,
+    items_to_give INTEGER NOT NULL,
+    items_to_receive INTEGER NOT NULL,
+    error TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX idx_received_offer_audit_bot ON received_offer_audit (bot_steam_id, created_at);
+CREATE INDEX idx_received_offer_audit_trade_offer_id ON received_offer_audit (trade_offer_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS received_offer_audit;
+-- +goose StatementEnd
@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE bots ADD COLUMN api_key BYTEA;
+ALTER TABLE bots ADD COLUMN api_key_domain TEXT;
+ALTER TABLE bots ADD COLUMN frozen_at TIMESTAMPTZ;
+ALTER TABLE bots ADD COLUMN frozen_reason TEXT;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE bots DROP COLUMN IF EXISTS frozen_reason;
+ALTER TABLE bots DROP COLUMN IF EXISTS frozen_at;
+ALTER TABLE bots DROP COLUMN IF EXISTS api_key_domain;
+ALTER TABLE bots DROP COLUMN IF EXISTS api_key;
+-- +goose StatementEnd
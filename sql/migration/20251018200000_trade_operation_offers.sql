@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE trade_operations ADD COLUMN offer_ids UUID[] NOT NULL DEFAULT '{}';
+CREATE INDEX idx_trade_operations_pending ON trade_operations (created_at) WHERE status = 'pending';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_trade_operations_pending;
+ALTER TABLE trade_operations DROP COLUMN IF EXISTS offer_ids;
+-- +goose StatementEnd